@@ -4,13 +4,34 @@ package handlers
 import (
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/qcbit/service/app/services/sales-api/handlers/v1/apikeygrp"
+	"github.com/qcbit/service/app/services/sales-api/handlers/v1/docgrp"
+	"github.com/qcbit/service/app/services/sales-api/handlers/v1/mfagrp"
+	"github.com/qcbit/service/app/services/sales-api/handlers/v1/pwresetgrp"
+	"github.com/qcbit/service/app/services/sales-api/handlers/v1/rolegrp"
+	"github.com/qcbit/service/app/services/sales-api/handlers/v1/streamgrp"
 	"github.com/qcbit/service/app/services/sales-api/handlers/v1/usergrp"
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/apikey/stores/apikeydb"
+	"github.com/qcbit/service/business/core/idempotency"
+	"github.com/qcbit/service/business/core/mfa"
+	"github.com/qcbit/service/business/core/mfa/stores/mfadb"
+	"github.com/qcbit/service/business/core/pwreset"
+	"github.com/qcbit/service/business/core/pwreset/stores/pwresetdb"
+	"github.com/qcbit/service/business/core/role"
 	"github.com/qcbit/service/business/core/user"
 	"github.com/qcbit/service/business/core/user/stores/userdb"
+	"github.com/qcbit/service/business/sys/notify"
 	"github.com/qcbit/service/business/web/auth"
+	v1 "github.com/qcbit/service/business/web/v1"
 	"github.com/qcbit/service/business/web/v1/mid"
+	"github.com/qcbit/service/business/web/v1/paging"
+	"github.com/qcbit/service/business/web/v1/stream"
+	"github.com/qcbit/service/foundation/openapi"
+	"github.com/qcbit/service/foundation/tracer"
 	"github.com/qcbit/service/foundation/web"
 	"go.uber.org/zap"
 
@@ -19,26 +40,297 @@ import (
 
 // APIMuxConfig contains all the mandatory systems required by handlers.
 type APIMuxConfig struct {
+	Build    string
 	Shutdown chan os.Signal
 	Log      *zap.SugaredLogger
 	Auth     *auth.Auth
 	DB       *sqlx.DB
+	Role     *role.Core
+	User     user.Config
+	Notifier notify.Notifier
+	ResetTTL time.Duration
+
+	// MaxBodyBytes limits the size of request bodies, zero means no limit.
+	MaxBodyBytes int64
+
+	// Tracer records a span for every request when set.
+	Tracer *tracer.Tracer
+
+	RateLimits RateLimits
+
+	// Errors selects the format of error responses.
+	Errors mid.ErrorsConfig
+
+	// Load bounds the time and concurrency of requests.
+	Load Load
+
+	// Idempotency records the responses of POST requests carrying an
+	// Idempotency-Key header so retries are replayed.
+	Idempotency *idempotency.Core
+
+	// Broker streams the changes to users and products to clients, the
+	// stream is disabled when it isn't set. The changes are published to it
+	// by the follower of the domain events in every instance, see
+	// streamgrp.Forward.
+	Broker *stream.Broker
+	Stream streamgrp.Config
+
+	// Compress compresses responses when set.
+	Compress *mid.CompressConfig
+
+	// CORS allows browsers to call the API from the allowed origins, no
+	// origins disables cross-origin requests.
+	CORS mid.CORSConfig
+}
+
+// RateLimits contains the rate limit rules applied to the routes. Client
+// applies to every request by address, User to authenticated requests by
+// subject and Auth to the token and password reset routes.
+type RateLimits struct {
+	Client mid.RateLimitConfig
+	User   mid.RateLimitConfig
+	Auth   mid.RateLimitConfig
+}
+
+// Load contains the limits protecting the service from overload. Timeout is
+// the deadline of every request and AuthTimeout the tighter deadline of the
+// routes accepting credentials. Shed caps the concurrent requests of the API.
+type Load struct {
+	Timeout     time.Duration
+	AuthTimeout time.Duration
+	Shed        mid.LoadShedConfig
 }
 
 // APIMux constructs a http.Handler with all application routes defined.
 func APIMux(cfg APIMuxConfig) *web.App {
-	app := web.NewApp(cfg.Shutdown, mid.Logger(cfg.Log), mid.Errors(cfg.Log), mid.Metrics(), mid.Panics())
+	mw := []web.Middleware{mid.Logger(cfg.Log)}
+	if cfg.Compress != nil {
+		mw = append(mw, mid.Compress(*cfg.Compress))
+	}
+	mw = append(mw, mid.Errors(cfg.Log, cfg.Errors), mid.Metrics(), mid.Panics(), mid.Language(), mid.Conditional())
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		mw = append(mw, mid.CORS(cfg.CORS))
+	}
+	if cfg.MaxBodyBytes > 0 {
+		mw = append(mw, mid.BodyLimit(cfg.MaxBodyBytes))
+	}
 
-	app.Handle(http.MethodGet, "/test", testgrp.Test)
-	app.Handle(http.MethodGet, "/test/auth", testgrp.Test, mid.Authenticate(cfg.Auth), mid.Authorize(cfg.Auth, auth.RuleAdminOnly))
+	app := web.NewApp(cfg.Shutdown, mw...)
+	if cfg.Tracer != nil {
+		app.SetTracer(cfg.Tracer)
+	}
+
+	// The routes keep the paths existing clients call, groups share the
+	// middleware and the prefix of each subtree.
+	api := app.Group("",
+		mid.RateLimit(cfg.RateLimits.Client),
+		mid.LoadShed(cfg.Load.Shed),
+		mid.Deadline("v1", cfg.Load.Timeout),
+	)
+
+	// Streams are long-lived, so they don't count against the requests in
+	// flight and have no deadline.
+	streams := app.Group("",
+		mid.RateLimit(cfg.RateLimits.Client),
+	)
+
+	v1Routes(api, streams, cfg)
+
+	return app
+}
+
+// v1Routes binds all the version 1 routes.
+func v1Routes(api *web.Group, streams *web.Group, cfg APIMuxConfig) {
+
+	// Authenticated requests are limited by subject once the claims are known.
+	authenticate := mid.Authenticate(cfg.Auth)
+	userLimit := mid.RateLimit(cfg.RateLimits.User)
+	authen := func(handler web.Handler) web.Handler {
+		if userLimit != nil {
+			handler = userLimit(handler)
+		}
+		return authenticate(handler)
+	}
+
+	// Routes accepting credentials share a stricter limit and deadline.
+	authLimit := mid.RateLimit(cfg.RateLimits.Auth)
+	authDeadline := mid.Deadline("auth", cfg.Load.AuthTimeout)
+
+	// Creating resources is safe to retry with an Idempotency-Key header. The
+	// responses are stored, so routes returning credentials don't use it.
+	var idem web.Middleware
+	if cfg.Idempotency != nil {
+		idem = mid.Idempotency(cfg.Log, cfg.Idempotency, cfg.RateLimits.Client.TrustedProxies, cfg.MaxBodyBytes)
+	}
+
+	api.Handle(http.MethodGet, "/test", testgrp.Test).
+		Doc("Test the service is responding", "test")
+	api.Handle(http.MethodGet, "/test/auth", testgrp.Test, authen, mid.Authorize(cfg.Auth, auth.RuleAdminOnly)).
+		Doc("Test authentication and authorization", "test")
 
 	// -----------------------------------------------------------------
 
-	usrcore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
+	usrcore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB), cfg.User)
 
-	ugh := usergrp.New(usrcore)
+	mfacore := mfa.NewCore(mfadb.NewStore(cfg.Log, cfg.DB), cfg.Auth.Issuer())
 
-	app.Handle(http.MethodGet, "/users", ugh.Query)
+	ugh := usergrp.New(usrcore, mfacore, cfg.Auth)
 
-	return app
+	users := api.Group("/users")
+	users.Handle(http.MethodGet, "/token/:kid", ugh.Token, authLimit, authDeadline).
+		Doc("Issue a token using basic authentication and an optional X-MFA-Code header", "users").
+		Response(http.StatusOK, usergrp.AppToken{}).
+		Response(http.StatusUnauthorized, v1.ErrorResponse{}).
+		Response(http.StatusLocked, v1.ErrorResponse{}).
+		Response(http.StatusTooManyRequests, v1.ErrorResponse{})
+	users.Handle(http.MethodGet, "", ugh.Query).
+		Doc("List users", "users").
+		Query("page", "Page number, starting at 1").
+		Query("rows", "Rows per page").
+		Query("orderBy", "Field and direction to order by, e.g. name,ASC").
+		Query("user_id", "Filter by user id").
+		Query("email", "Filter by email").
+		Query("name", "Filter by name").
+		Query("start_created_date", "Filter by creation date, RFC3339").
+		Query("end_created_date", "Filter by creation date, RFC3339").
+		Response(http.StatusOK, paging.Response[usergrp.AppUser]{}).
+		Response(http.StatusNotModified, nil).
+		Response(http.StatusBadRequest, v1.ErrorResponse{})
+	users.Handle(http.MethodPost, "", ugh.Create, authen, mid.AuthorizePermission(cfg.Auth, role.PermissionUsersWrite), idem).
+		Doc("Create a user, retries with the same Idempotency-Key header replay the response", "users").
+		Request(usergrp.AppNewUser{}).
+		Response(http.StatusCreated, usergrp.AppUser{}).
+		Response(http.StatusBadRequest, v1.ErrorResponse{}).
+		Response(http.StatusConflict, v1.ErrorResponse{}).
+		Response(http.StatusUnprocessableEntity, v1.ErrorResponse{})
+	users.Handle(http.MethodPut, "/:user_id/roles", ugh.AssignRoles, authen, mid.AuthorizePermission(cfg.Auth, role.PermissionRolesAssign)).
+		Doc("Replace the roles assigned to a user", "users").
+		Request(usergrp.AppUserRoles{}).
+		Response(http.StatusOK, usergrp.AppUser{}).
+		Response(http.StatusBadRequest, v1.ErrorResponse{}).
+		Response(http.StatusNotFound, v1.ErrorResponse{})
+
+	// -----------------------------------------------------------------
+
+	mgh := mfagrp.New(mfacore, usrcore)
+
+	userMFA := users.Group("/mfa", authen)
+	userMFA.Handle(http.MethodPost, "/enroll", mgh.Enroll).
+		Doc("Start an mfa enrollment for the authenticated user", "mfa").
+		Response(http.StatusCreated, mfagrp.AppEnrollment{}).
+		Response(http.StatusConflict, v1.ErrorResponse{})
+	userMFA.Handle(http.MethodPost, "/verify", mgh.Verify).
+		Doc("Confirm the mfa enrollment and issue recovery codes", "mfa").
+		Request(mfagrp.AppCode{}).
+		Response(http.StatusOK, mfagrp.AppRecoveryCodes{}).
+		Response(http.StatusBadRequest, v1.ErrorResponse{})
+	userMFA.Handle(http.MethodPost, "/disable", mgh.Disable).
+		Doc("Disable mfa for the authenticated user", "mfa").
+		Request(mfagrp.AppCode{}).
+		Response(http.StatusNoContent, nil).
+		Response(http.StatusBadRequest, v1.ErrorResponse{})
+
+	// -----------------------------------------------------------------
+
+	rstcore := pwreset.NewCore(usrcore, pwresetdb.NewStore(cfg.Log, cfg.DB), cfg.Notifier, cfg.ResetTTL)
+
+	pgh := pwresetgrp.New(rstcore)
+
+	users.Handle(http.MethodPost, "/password/reset", pgh.Request, authLimit, authDeadline).
+		Doc("Request a password reset token", "users").
+		Request(pwresetgrp.AppRequestReset{}).
+		Response(http.StatusAccepted, nil).
+		Response(http.StatusBadRequest, v1.ErrorResponse{})
+	users.Handle(http.MethodPost, "/password/reset/confirm", pgh.Confirm, authLimit, authDeadline).
+		Doc("Reset a password using a reset token", "users").
+		Request(pwresetgrp.AppConfirmReset{}).
+		Response(http.StatusNoContent, nil).
+		Response(http.StatusBadRequest, v1.ErrorResponse{})
+
+	// -----------------------------------------------------------------
+
+	rgh := rolegrp.New(cfg.Role)
+
+	roles := api.Group("/roles", authen)
+	roles.Handle(http.MethodGet, "", rgh.Query, mid.AuthorizePermission(cfg.Auth, role.PermissionRolesRead)).
+		Doc("List roles", "roles").
+		Response(http.StatusOK, []rolegrp.AppRole{})
+	roles.Handle(http.MethodGet, "/:name", rgh.QueryByName, mid.AuthorizePermission(cfg.Auth, role.PermissionRolesRead)).
+		Doc("Get a role", "roles").
+		Response(http.StatusOK, rolegrp.AppRole{}).
+		Response(http.StatusNotFound, v1.ErrorResponse{})
+	roles.Handle(http.MethodPost, "", rgh.Create, mid.AuthorizePermission(cfg.Auth, role.PermissionRolesWrite), idem).
+		Doc("Create a role", "roles").
+		Request(rolegrp.AppNewRole{}).
+		Response(http.StatusCreated, rolegrp.AppRole{}).
+		Response(http.StatusBadRequest, v1.ErrorResponse{}).
+		Response(http.StatusConflict, v1.ErrorResponse{})
+	roles.Handle(http.MethodPut, "/:name", rgh.Update, mid.AuthorizePermission(cfg.Auth, role.PermissionRolesWrite)).
+		Doc("Update a role", "roles").
+		Request(rolegrp.AppUpdateRole{}).
+		Response(http.StatusOK, rolegrp.AppRole{}).
+		Response(http.StatusBadRequest, v1.ErrorResponse{}).
+		Response(http.StatusNotFound, v1.ErrorResponse{})
+	roles.Handle(http.MethodDelete, "/:name", rgh.Delete, mid.AuthorizePermission(cfg.Auth, role.PermissionRolesWrite)).
+		Doc("Delete a role", "roles").
+		Response(http.StatusNoContent, nil).
+		Response(http.StatusConflict, v1.ErrorResponse{})
+
+	// -----------------------------------------------------------------
+
+	keycore := apikey.NewCore(usrcore, apikeydb.NewStore(cfg.Log, cfg.DB))
+
+	kgh := apikeygrp.New(keycore)
+
+	apikeys := api.Group("/apikeys", authen)
+	apikeys.Handle(http.MethodGet, "", kgh.Query, mid.AuthorizePermission(cfg.Auth, role.PermissionAPIKeysRead)).
+		Doc("List api keys", "apikeys").
+		Query("page", "Page number, starting at 1").
+		Query("rows", "Rows per page").
+		Query("orderBy", "Field and direction to order by, e.g. datecreated,DESC").
+		Query("api_key_id", "Filter by api key id").
+		Query("user_id", "Filter by user id").
+		Query("name", "Filter by name").
+		Response(http.StatusOK, paging.Response[apikeygrp.AppKey]{}).
+		Response(http.StatusBadRequest, v1.ErrorResponse{})
+	// Issuing a key isn't idempotent, recording the response would store the
+	// plaintext key.
+	apikeys.Handle(http.MethodPost, "", kgh.Create, mid.AuthorizePermission(cfg.Auth, role.PermissionAPIKeysWrite)).
+		Doc("Issue an api key, the key is only returned once", "apikeys").
+		Request(apikeygrp.AppNewKey{}).
+		Response(http.StatusCreated, apikeygrp.AppCreatedKey{}).
+		Response(http.StatusBadRequest, v1.ErrorResponse{})
+	apikeys.Handle(http.MethodDelete, "/:api_key_id", kgh.Revoke, mid.AuthorizePermission(cfg.Auth, role.PermissionAPIKeysWrite)).
+		Doc("Revoke an api key", "apikeys").
+		Response(http.StatusNoContent, nil).
+		Response(http.StatusBadRequest, v1.ErrorResponse{}).
+		Response(http.StatusNotFound, v1.ErrorResponse{})
+
+	// -----------------------------------------------------------------
+
+	if cfg.Broker != nil {
+		sgh := streamgrp.New(cfg.Broker, cfg.Auth, cfg.Stream)
+
+		streams.Handle(http.MethodGet, "/events", sgh.Events, authen, mid.Authorize(cfg.Auth, auth.RuleAny)).
+			Doc("Stream changes to users and products as Server-Sent Events, admins receive every change and other users the changes to what they own", "events").
+			Response(http.StatusOK, streamgrp.AppEvent{}).
+			Response(http.StatusBadRequest, v1.ErrorResponse{})
+	}
+
+	// -----------------------------------------------------------------
+
+	dgh := docgrp.New(api, openapi.Config{
+		Info: openapi.Info{
+			Title:   "Sales API",
+			Version: cfg.Build,
+		},
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			"apiKeyAuth": {Type: "apiKey", Name: auth.APIKeyHeader, In: "header"},
+		},
+	})
+
+	api.Handle(http.MethodGet, "/openapi.json", dgh.OpenAPI).
+		Doc("The OpenAPI document for this version of the API", "docs").
+		Response(http.StatusOK, openapi.Document{})
 }
@@ -0,0 +1,140 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/app/services/sales-api/handlers"
+	"github.com/qcbit/service/business/core/role"
+	"github.com/qcbit/service/business/web/auth"
+	"github.com/qcbit/service/business/web/v1/errtest"
+	"github.com/qcbit/service/foundation/openapi"
+)
+
+func Test_OpenAPI(t *testing.T) {
+	log := zap.NewNop().Sugar()
+
+	a, err := auth.New(auth.Config{Log: log})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	app := handlers.APIMux(handlers.APIMuxConfig{
+		Build: "test",
+		Log:   log,
+		Auth:  a,
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Should receive a 200 for the document: got %d", w.Code)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Should be able to unmarshal the document: %s", err)
+	}
+
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("Should declare the openapi version: got %q, exp %q", doc.OpenAPI, openapi.Version)
+	}
+
+	// Every registered route must be documented.
+
+	routes := app.Routes()
+	for _, route := range routes {
+
+		item, exists := doc.Paths[openapi.Path(route.Path)]
+		if !exists {
+			t.Errorf("Should document path %s", route.Path)
+			continue
+		}
+
+		op, exists := (*item)[strings.ToLower(route.Method)]
+		if !exists {
+			t.Errorf("Should document %s %s", route.Method, route.Path)
+			continue
+		}
+
+		if op.Summary == "" {
+			t.Errorf("Should have a summary for %s %s", route.Method, route.Path)
+		}
+
+		for _, param := range route.Params {
+			var found bool
+			for _, p := range op.Parameters {
+				if p.In == "path" && p.Name == param {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Should document path parameter %q for %s %s", param, route.Method, route.Path)
+			}
+		}
+	}
+
+	// Routes are documented with the authorization their middleware
+	// enforces.
+
+	for _, route := range app.Routes() {
+		if route.Method != http.MethodDelete || route.Path != "/apikeys/:api_key_id" {
+			continue
+		}
+
+		if !route.Secured || len(route.AuthRules) != 1 || route.AuthRules[0] != role.PermissionAPIKeysWrite {
+			t.Errorf("Should document the permission of %s %s: got %t %v", route.Method, route.Path, route.Secured, route.AuthRules)
+		}
+
+		if diff := cmp.Diff(route.Statuses(), []int{http.StatusNoContent, http.StatusBadRequest, http.StatusNotFound}); diff != "" {
+			t.Errorf("Should document the statuses of %s %s:\n%s", route.Method, route.Path, diff)
+		}
+	}
+
+	// Every documented operation must resolve to a live route.
+
+	params := regexp.MustCompile(`\{[^}]+\}`)
+
+	var operations int
+	for path, item := range doc.Paths {
+		for method := range *item {
+			operations++
+
+			r := httptest.NewRequest(strings.ToUpper(method), params.ReplaceAllString(path, "x"), nil)
+			if _, found := app.Lookup(httptest.NewRecorder(), r); !found {
+				t.Errorf("Should find a live route for %s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	if operations != len(routes) {
+		t.Errorf("Should document every route once: got %d operations, exp %d", operations, len(routes))
+	}
+
+	// Every referenced schema must be defined.
+
+	refs := regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`)
+	for _, match := range refs.FindAllStringSubmatch(w.Body.String(), -1) {
+		if _, exists := doc.Components.Schemas[match[1]]; !exists {
+			t.Errorf("Should define the referenced schema %q", match[1])
+		}
+	}
+}
+
+func Test_ErrorRegistry(t *testing.T) {
+
+	// Products have no routes yet and the idempotency middleware handles the
+	// missing key itself.
+	errtest.Unmapped(t, "../../../../business/core",
+		"product.ErrNotFound",
+		"idempotency.ErrNotFound",
+	)
+}
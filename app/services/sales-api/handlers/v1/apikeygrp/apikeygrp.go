@@ -0,0 +1,113 @@
+// Package apikeygrp maintains the group of handlers for api key access.
+package apikeygrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/sys/validate"
+	v1 "github.com/qcbit/service/business/web/v1"
+	"github.com/qcbit/service/business/web/v1/paging"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// Handlers manages the set of api key endpoints.
+type Handlers struct {
+	apikey *apikey.Core
+}
+
+// New constructs a handlers for route access.
+func New(apikey *apikey.Core) *Handlers {
+	return &Handlers{
+		apikey: apikey,
+	}
+}
+
+// Create issues a new api key for a user. The plain text key is only
+// returned by this call.
+func (h *Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppNewKey
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	nk, err := toCoreNewKey(app)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	key, plain, err := h.apikey.Create(ctx, nk)
+	if err != nil {
+
+		// The owner is part of the request, it isn't the resource.
+		if errors.Is(err, user.ErrNotFound) {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+		return fmt.Errorf("create: nk[%+v]: %w", nk, err)
+	}
+
+	resp := AppCreatedKey{
+		AppKey: toAppKey(key),
+		Key:    plain,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusCreated)
+}
+
+// Revoke revokes an api key so it can no longer be used.
+func (h *Handlers) Revoke(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	keyID, err := validate.ParseUUID(ctx, "api_key_id", web.Param(r, "api_key_id"))
+	if err != nil {
+		return err
+	}
+
+	key, err := h.apikey.QueryByID(ctx, keyID)
+	if err != nil {
+		return fmt.Errorf("querybyid: keyID[%s]: %w", keyID, err)
+	}
+
+	if _, err := h.apikey.Revoke(ctx, key); err != nil {
+		return fmt.Errorf("revoke: keyID[%s]: %w", keyID, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns a list of api keys with paging.
+func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	page, err := paging.ParseRequest(r)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		return err
+	}
+
+	orderBy, err := parseOrder(r)
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.apikey.Query(ctx, filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	items := make([]AppKey, len(keys))
+	for i, key := range keys {
+		items[i] = toAppKey(key)
+	}
+
+	total, err := h.apikey.Count(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+
+	return web.Respond(ctx, w, paging.NewResponse(items, total, page.Number, page.RowsPerPage), http.StatusOK)
+}
@@ -0,0 +1,20 @@
+package apikeygrp
+
+import (
+	"net/http"
+
+	"github.com/qcbit/service/business/core/apikey"
+	v1 "github.com/qcbit/service/business/web/v1"
+)
+
+// init registers the responses for the errors of the api key core.
+func init() {
+	v1.RegisterError(apikey.ErrNotFound, http.StatusNotFound, "api_key_not_found", "api key not found")
+	v1.RegisterError(apikey.ErrInvalidKey, http.StatusUnauthorized, "invalid_api_key", "api key is not valid")
+	v1.RegisterError(apikey.ErrExpired, http.StatusUnauthorized, "api_key_expired", "api key has expired")
+	v1.RegisterError(apikey.ErrRevoked, http.StatusUnauthorized, "api_key_revoked", "api key has been revoked")
+	v1.RegisterError(apikey.ErrInvalidRoles, http.StatusBadRequest, "invalid_api_key_roles", "api key roles must be a subset of the owner's roles")
+	v1.RegisterError(apikey.ErrInvalidOwner, http.StatusUnauthorized, "invalid_api_key_owner", "api key owner is not enabled")
+	v1.RegisterError(apikey.ErrOwnerDisabled, http.StatusBadRequest, "api_key_owner_disabled", "api keys can't be issued for a disabled user")
+	v1.RegisterError(apikey.ErrInvalidDate, http.StatusBadRequest, "invalid_api_key_expiration", "api key expiration must be in the future")
+}
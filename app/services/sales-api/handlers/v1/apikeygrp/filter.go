@@ -0,0 +1,38 @@
+package apikeygrp
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+func parseFilter(r *http.Request) (apikey.QueryFilter, error) {
+	values := r.URL.Query()
+
+	var filter apikey.QueryFilter
+
+	if keyID := values.Get("api_key_id"); keyID != "" {
+		id, err := uuid.Parse(keyID)
+		if err != nil {
+			return apikey.QueryFilter{}, validate.NewFieldsError("api_key_id", err)
+		}
+		filter.WithKeyID(id)
+	}
+
+	if userID := values.Get("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return apikey.QueryFilter{}, validate.NewFieldsError("user_id", err)
+		}
+		filter.WithUserID(id)
+	}
+
+	if name := values.Get("name"); name != "" {
+		filter.WithName(name)
+	}
+
+	return filter, nil
+}
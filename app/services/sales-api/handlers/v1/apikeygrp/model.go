@@ -0,0 +1,109 @@
+package apikeygrp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+// AppKey represents information about an individual api key. The plain text
+// value of the key is never part of this model.
+type AppKey struct {
+	ID           string   `json:"id"`
+	UserID       string   `json:"userID"`
+	Name         string   `json:"name"`
+	Prefix       string   `json:"prefix"`
+	Roles        []string `json:"roles"`
+	DateExpires  string   `json:"dateExpires"`
+	DateLastUsed string   `json:"dateLastUsed,omitempty"`
+	DateRevoked  string   `json:"dateRevoked,omitempty"`
+	DateCreated  string   `json:"dateCreated"`
+}
+
+func toAppKey(key apikey.Key) AppKey {
+	roles := make([]string, len(key.Roles))
+	for i, role := range key.Roles {
+		roles[i] = role.Name()
+	}
+
+	app := AppKey{
+		ID:          key.ID.String(),
+		UserID:      key.UserID.String(),
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Roles:       roles,
+		DateExpires: key.DateExpires.Format(time.RFC3339),
+		DateCreated: key.DateCreated.Format(time.RFC3339),
+	}
+
+	if !key.DateLastUsed.IsZero() {
+		app.DateLastUsed = key.DateLastUsed.Format(time.RFC3339)
+	}
+
+	if !key.DateRevoked.IsZero() {
+		app.DateRevoked = key.DateRevoked.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+// AppCreatedKey is returned when a key is created. It is the only time the
+// plain text value of the key is made available.
+type AppCreatedKey struct {
+	AppKey
+	Key string `json:"key"`
+}
+
+// -----------------------------------------------------------------------------
+
+// AppNewKey contains information needed to create a new api key.
+type AppNewKey struct {
+	UserID      string   `json:"userID" validate:"required,uuid"`
+	Name        string   `json:"name" validate:"required"`
+	Roles       []string `json:"roles" validate:"required,min=1,dive,role"`
+	DateExpires string   `json:"dateExpires" validate:"required"`
+}
+
+func toCoreNewKey(app AppNewKey) (apikey.NewKey, error) {
+	userID, err := uuid.Parse(app.UserID)
+	if err != nil {
+		return apikey.NewKey{}, fmt.Errorf("parsing user id: %w", err)
+	}
+
+	roles := make([]user.Role, len(app.Roles))
+	for i, roleStr := range app.Roles {
+		role, err := user.ParseRole(roleStr)
+		if err != nil {
+			return apikey.NewKey{}, fmt.Errorf("parsing role: %w", err)
+		}
+		roles[i] = role
+	}
+
+	expires, err := time.Parse(time.RFC3339, app.DateExpires)
+	if err != nil {
+		return apikey.NewKey{}, fmt.Errorf("parsing expiration date: %w", err)
+	}
+
+	nk := apikey.NewKey{
+		UserID:      userID,
+		Name:        app.Name,
+		Roles:       roles,
+		DateExpires: expires,
+	}
+
+	return nk, nil
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppNewKey) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package apikeygrp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/data/order"
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+var orderByFields = map[string]struct{}{
+	apikey.OrderByID:          {},
+	apikey.OrderByUserID:      {},
+	apikey.OrderByName:        {},
+	apikey.OrderByDateExpires: {},
+	apikey.OrderByDateCreated: {},
+}
+
+func parseOrder(r *http.Request) (order.By, error) {
+	orderBy, err := order.Parse(r, apikey.DefaultOrderBy)
+	if err != nil {
+		return order.By{}, err
+	}
+
+	if _, exists := orderByFields[orderBy.Field]; !exists {
+		return order.By{}, validate.NewFieldsError("orderBy", errors.New("invalid order by field"))
+	}
+
+	return orderBy, nil
+}
@@ -0,0 +1,44 @@
+// Package docgrp maintains the group of handlers for api documentation.
+package docgrp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/qcbit/service/foundation/openapi"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// RouteLister declares the behavior required to list the routes being
+// documented.
+type RouteLister interface {
+	Routes() []*web.Route
+}
+
+// Handlers manages the set of documentation endpoints.
+type Handlers struct {
+	routes RouteLister
+	cfg    openapi.Config
+
+	once sync.Once
+	doc  openapi.Document
+}
+
+// New constructs a handlers for route access.
+func New(routes RouteLister, cfg openapi.Config) *Handlers {
+	return &Handlers{
+		routes: routes,
+		cfg:    cfg,
+	}
+}
+
+// OpenAPI returns the OpenAPI document describing the routes. The document is
+// generated on the first call, once every route has been registered.
+func (h *Handlers) OpenAPI(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.once.Do(func() {
+		h.doc = openapi.Generate(h.cfg, h.routes.Routes())
+	})
+
+	return web.Respond(ctx, w, h.doc, http.StatusOK)
+}
@@ -0,0 +1,17 @@
+package mfagrp
+
+import (
+	"net/http"
+
+	"github.com/qcbit/service/business/core/mfa"
+	v1 "github.com/qcbit/service/business/web/v1"
+)
+
+// init registers the responses for the errors of the mfa core.
+func init() {
+	v1.RegisterError(mfa.ErrNotFound, http.StatusNotFound, "mfa_not_found", "mfa enrollment not found")
+	v1.RegisterError(mfa.ErrNotEnrolled, http.StatusBadRequest, "mfa_not_enrolled", "user is not enrolled in mfa")
+	v1.RegisterError(mfa.ErrAlreadyEnrolled, http.StatusConflict, "mfa_already_enrolled", "user is already enrolled in mfa")
+	v1.RegisterError(mfa.ErrInvalidCode, http.StatusBadRequest, "invalid_mfa_code", "mfa code is not valid")
+	v1.RegisterError(mfa.ErrRecoveryNotFound, http.StatusBadRequest, "invalid_mfa_code", "mfa code is not valid")
+}
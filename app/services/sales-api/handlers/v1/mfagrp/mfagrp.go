@@ -0,0 +1,112 @@
+// Package mfagrp maintains the group of handlers for mfa access.
+package mfagrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/mfa"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/web/auth"
+	"github.com/qcbit/service/foundation/totp"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// Handlers manages the set of mfa endpoints. Every endpoint acts on the
+// authenticated user.
+type Handlers struct {
+	mfa  *mfa.Core
+	user *user.Core
+}
+
+// New constructs a handlers for route access.
+func New(mfa *mfa.Core, user *user.Core) *Handlers {
+	return &Handlers{
+		mfa:  mfa,
+		user: user,
+	}
+}
+
+// Enroll starts an mfa enrollment for the authenticated user.
+func (h *Handlers) Enroll(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := h.claimsUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	enr, uri, err := h.mfa.Enroll(ctx, usr)
+	if err != nil {
+		return fmt.Errorf("enroll: userID[%s]: %w", usr.ID, err)
+	}
+
+	resp := AppEnrollment{
+		Secret: totp.EncodeSecret(enr.Secret),
+		URI:    uri,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusCreated)
+}
+
+// Verify confirms the pending enrollment of the authenticated user and
+// returns their recovery codes.
+func (h *Handlers) Verify(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppCode
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	usr, err := h.claimsUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	codes, err := h.mfa.Confirm(ctx, usr, app.Code)
+	if err != nil {
+		return fmt.Errorf("confirm: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.Respond(ctx, w, AppRecoveryCodes{RecoveryCodes: codes}, http.StatusOK)
+}
+
+// Disable removes the mfa enrollment of the authenticated user.
+func (h *Handlers) Disable(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppCode
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	usr, err := h.claimsUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := h.mfa.Disable(ctx, usr, app.Code); err != nil {
+		return fmt.Errorf("disable: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// claimsUser returns the user the claims in the context were issued to.
+func (h *Handlers) claimsUser(ctx context.Context) (user.User, error) {
+	claims := auth.GetClaims(ctx)
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return user.User{}, auth.NewAuthError("invalid subject in claims")
+	}
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return user.User{}, auth.NewAuthError("unknown subject in claims")
+		}
+		return user.User{}, fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	return usr, nil
+}
@@ -0,0 +1,35 @@
+package mfagrp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+// AppEnrollment represents a pending mfa enrollment. The secret is only
+// returned when the enrollment is started.
+type AppEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// AppRecoveryCodes represents the recovery codes issued when an enrollment
+// is confirmed.
+type AppRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// AppCode contains a code generated by the user's authenticator or one of
+// their recovery codes.
+type AppCode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppCode) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package pwresetgrp
+
+import (
+	"net/http"
+
+	"github.com/qcbit/service/business/core/pwreset"
+	v1 "github.com/qcbit/service/business/web/v1"
+)
+
+// init registers the responses for the errors of the password reset core. A
+// reset that doesn't exist is reported like an invalid token so tokens can't
+// be probed.
+func init() {
+	v1.RegisterError(pwreset.ErrNotFound, http.StatusBadRequest, "invalid_reset_token", "password reset token is not valid")
+	v1.RegisterError(pwreset.ErrInvalidToken, http.StatusBadRequest, "invalid_reset_token", "password reset token is not valid")
+}
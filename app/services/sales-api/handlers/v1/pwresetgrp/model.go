@@ -0,0 +1,38 @@
+package pwresetgrp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+// AppRequestReset contains information needed to request a password reset.
+type AppRequestReset struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppRequestReset) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// AppConfirmReset contains information needed to complete a password reset.
+type AppConfirmReset struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required,password"`
+	PasswordConfirm string `json:"passwordConfirm" validate:"eqfield=Password"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppConfirmReset) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+	return nil
+}
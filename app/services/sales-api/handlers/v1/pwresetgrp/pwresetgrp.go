@@ -0,0 +1,59 @@
+// Package pwresetgrp maintains the group of handlers for password reset access.
+package pwresetgrp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+
+	"github.com/qcbit/service/business/core/pwreset"
+	"github.com/qcbit/service/business/sys/validate"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// Handlers manages the set of password reset endpoints.
+type Handlers struct {
+	reset *pwreset.Core
+}
+
+// New constructs a handlers for route access.
+func New(reset *pwreset.Core) *Handlers {
+	return &Handlers{
+		reset: reset,
+	}
+}
+
+// Request issues a password reset token for the specified email. The same
+// response is returned whether or not the email is registered.
+func (h *Handlers) Request(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppRequestReset
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	addr, err := mail.ParseAddress(app.Email)
+	if err != nil {
+		return validate.NewFieldsError("email", err)
+	}
+
+	if err := h.reset.Request(ctx, *addr); err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusAccepted)
+}
+
+// Confirm completes a password reset using a previously issued token.
+func (h *Handlers) Confirm(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppConfirmReset
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	if _, err := h.reset.Confirm(ctx, app.Token, app.Password); err != nil {
+		return fmt.Errorf("confirm: %w", err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
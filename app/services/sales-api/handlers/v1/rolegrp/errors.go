@@ -0,0 +1,18 @@
+package rolegrp
+
+import (
+	"net/http"
+
+	"github.com/qcbit/service/business/core/role"
+	v1 "github.com/qcbit/service/business/web/v1"
+)
+
+// init registers the responses for the errors of the role core.
+func init() {
+	v1.RegisterError(role.ErrNotFound, http.StatusNotFound, "role_not_found", "role not found")
+	v1.RegisterError(role.ErrUniqueName, http.StatusConflict, "role_name_not_unique", "role name is not unique")
+	v1.RegisterError(role.ErrInvalidName, http.StatusBadRequest, "invalid_role_name", "role name must be upper case letters, digits or underscores")
+	v1.RegisterError(role.ErrInvalidPermission, http.StatusBadRequest, "invalid_permission", "permission must be lower case words separated by colons")
+	v1.RegisterError(role.ErrInUse, http.StatusConflict, "role_in_use", "role is assigned and can't be deleted")
+	v1.RegisterError(role.ErrBuiltIn, http.StatusConflict, "role_built_in", "built-in roles can't be deleted")
+}
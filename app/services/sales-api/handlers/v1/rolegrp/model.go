@@ -0,0 +1,74 @@
+package rolegrp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qcbit/service/business/core/role"
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+// AppRole represents information about an individual role.
+type AppRole struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	DateCreated string   `json:"dateCreated"`
+	DateUpdated string   `json:"dateUpdated"`
+}
+
+func toAppRole(rol role.Role) AppRole {
+	perms := rol.Permissions
+	if perms == nil {
+		perms = []string{}
+	}
+
+	return AppRole{
+		Name:        rol.Name,
+		Description: rol.Description,
+		Permissions: perms,
+		DateCreated: rol.DateCreated.Format(time.RFC3339),
+		DateUpdated: rol.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// AppNewRole contains information needed to create a new role.
+type AppNewRole struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+func toCoreNewRole(app AppNewRole) role.NewRole {
+	return role.NewRole{
+		Name:        app.Name,
+		Description: app.Description,
+		Permissions: app.Permissions,
+	}
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppNewRole) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// AppUpdateRole contains information needed to update a role.
+type AppUpdateRole struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func toCoreUpdateRole(app AppUpdateRole) role.UpdateRole {
+	return role.UpdateRole{
+		Description: app.Description,
+		Permissions: app.Permissions,
+	}
+}
@@ -0,0 +1,109 @@
+// Package rolegrp maintains the group of handlers for role access.
+package rolegrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/qcbit/service/business/core/role"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// Handlers manages the set of role endpoints.
+type Handlers struct {
+	role *role.Core
+}
+
+// New constructs a handlers for route access.
+func New(role *role.Core) *Handlers {
+	return &Handlers{
+		role: role,
+	}
+}
+
+// Create adds a new role to the system.
+func (h *Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppNewRole
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	rol, err := h.role.Create(ctx, toCoreNewRole(app))
+	if err != nil {
+		return fmt.Errorf("create: app[%+v]: %w", app, err)
+	}
+
+	return web.Respond(ctx, w, toAppRole(rol), http.StatusCreated)
+}
+
+// Update updates a role in the system.
+func (h *Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppUpdateRole
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	name := web.Param(r, "name")
+
+	rol, err := h.role.QueryByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("querybyname: name[%s]: %w", name, err)
+	}
+
+	rol, err = h.role.Update(ctx, rol, toCoreUpdateRole(app))
+	if err != nil {
+		return fmt.Errorf("update: name[%s] app[%+v]: %w", name, app, err)
+	}
+
+	return web.Respond(ctx, w, toAppRole(rol), http.StatusOK)
+}
+
+// Delete removes a role from the system.
+func (h *Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	name := web.Param(r, "name")
+
+	rol, err := h.role.QueryByName(ctx, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, role.ErrNotFound):
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		default:
+			return fmt.Errorf("querybyname: name[%s]: %w", name, err)
+		}
+	}
+
+	if err := h.role.Delete(ctx, rol); err != nil {
+		return fmt.Errorf("delete: name[%s]: %w", name, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns the list of roles.
+func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	roles, err := h.role.Query(ctx)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	items := make([]AppRole, len(roles))
+	for i, rol := range roles {
+		items[i] = toAppRole(rol)
+	}
+
+	return web.Respond(ctx, w, items, http.StatusOK)
+}
+
+// QueryByName returns a role by its name.
+func (h *Handlers) QueryByName(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	name := web.Param(r, "name")
+
+	rol, err := h.role.QueryByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("querybyname: name[%s]: %w", name, err)
+	}
+
+	return web.Respond(ctx, w, toAppRole(rol), http.StatusOK)
+}
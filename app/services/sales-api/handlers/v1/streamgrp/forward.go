@@ -0,0 +1,53 @@
+package streamgrp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qcbit/service/business/core/product"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/sys/events"
+	"github.com/qcbit/service/business/web/v1/stream"
+)
+
+// streamTypes maps the domain events streamed to clients to the type of the
+// streamed event. Other domain events aren't streamed.
+var streamTypes = map[string]string{
+	user.EventCreated:    stream.TypeUserCreated,
+	user.EventUpdated:    stream.TypeUserUpdated,
+	user.EventDeleted:    stream.TypeUserDeleted,
+	product.EventCreated: stream.TypeProductCreated,
+	product.EventUpdated: stream.TypeProductUpdated,
+	product.EventDeleted: stream.TypeProductDeleted,
+}
+
+// Forward returns an event handler publishing the changes to users and
+// products to the broker, so they are streamed to the clients. The broker
+// only reaches the clients of its instance, so the handler must be
+// subscribed to a bus receiving every event in every instance.
+func Forward(broker *stream.Broker) events.Handler {
+	return func(ctx context.Context, ev events.Event) error {
+		typ, exists := streamTypes[ev.Type]
+		if !exists {
+			return nil
+		}
+
+		switch ev.Aggregate {
+		case user.EventAggregate:
+			data, err := events.Decode[user.EventData](ev)
+			if err != nil {
+				return fmt.Errorf("decode: %w", err)
+			}
+			broker.Publish(typ, data.ID.String(), toAppUser(data))
+
+		case product.EventAggregate:
+			data, err := events.Decode[product.EventData](ev)
+			if err != nil {
+				return fmt.Errorf("decode: %w", err)
+			}
+			broker.Publish(typ, data.UserID.String(), toAppProduct(data))
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package streamgrp
+
+import (
+	"time"
+
+	"github.com/qcbit/service/business/core/product"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/web/v1/stream"
+)
+
+// AppEvent represents a change streamed to the client in the data of an
+// event.
+type AppEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+	Time string `json:"time"`
+}
+
+func toAppEvent(ev stream.Event) AppEvent {
+	return AppEvent{
+		Type: ev.Type,
+		Data: ev.Data,
+		Time: ev.Time.Format(time.RFC3339),
+	}
+}
+
+// AppUser represents the user in the data of the user events.
+type AppUser struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	Department  string   `json:"department"`
+	Enabled     bool     `json:"enabled"`
+	DateCreated string   `json:"dateCreated"`
+	DateUpdated string   `json:"dateUpdated"`
+}
+
+func toAppUser(data user.EventData) AppUser {
+	return AppUser{
+		ID:          data.ID.String(),
+		Name:        data.Name,
+		Email:       data.Email,
+		Roles:       data.Roles,
+		Department:  data.Department,
+		Enabled:     data.Enabled,
+		DateCreated: data.DateCreated.Format(time.RFC3339),
+		DateUpdated: data.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+// AppProduct represents the product in the data of the product events.
+type AppProduct struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Cost        float64 `json:"cost"`
+	Quantity    int     `json:"quantity"`
+	UserID      string  `json:"userID"`
+	DateCreated string  `json:"dateCreated"`
+	DateUpdated string  `json:"dateUpdated"`
+}
+
+func toAppProduct(data product.EventData) AppProduct {
+	return AppProduct{
+		ID:          data.ID.String(),
+		Name:        data.Name,
+		Cost:        data.Cost,
+		Quantity:    data.Quantity,
+		UserID:      data.UserID.String(),
+		DateCreated: data.DateCreated.Format(time.RFC3339),
+		DateUpdated: data.DateUpdated.Format(time.RFC3339),
+	}
+}
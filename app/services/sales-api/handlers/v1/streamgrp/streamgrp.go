@@ -0,0 +1,176 @@
+// Package streamgrp maintains the group of handlers for streaming changes.
+package streamgrp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/qcbit/service/business/sys/validate"
+	"github.com/qcbit/service/business/web/auth"
+	"github.com/qcbit/service/business/web/v1/stream"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// EventResync is sent when the client resumed from an event that is no longer
+// in the replay buffer, the client must reload the data it holds.
+const EventResync = "resync"
+
+// defaultHeartbeat is used when the Config doesn't specify a heartbeat.
+const defaultHeartbeat = 15 * time.Second
+
+// Config represents the settings of the stream endpoints.
+type Config struct {
+
+	// Heartbeat is the interval of the comments that keep idle streams
+	// open.
+	Heartbeat time.Duration
+
+	// WriteTimeout is how long the client is given to receive every event.
+	WriteTimeout time.Duration
+
+	// Retry is how long clients wait before reconnecting.
+	Retry time.Duration
+}
+
+// Handlers manages the set of stream endpoints.
+type Handlers struct {
+	broker *stream.Broker
+	auth   *auth.Auth
+	cfg    Config
+}
+
+// New constructs a handlers for route access.
+func New(broker *stream.Broker, auth *auth.Auth, cfg Config) *Handlers {
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = defaultHeartbeat
+	}
+
+	return &Handlers{
+		broker: broker,
+		auth:   auth,
+		cfg:    cfg,
+	}
+}
+
+// Events streams the changes to users and products as Server-Sent Events.
+// Admins receive every event, other callers only the events about the
+// entities they own. A client reconnecting with a Last-Event-ID header first
+// receives the events it missed. The stream ends when the claims expire, and
+// on a heartbeat when the user was disabled or the session revoked, so the
+// client has to authenticate again to reconnect.
+func (h *Handlers) Events(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	lastID, resume, err := parseLastEventID(r)
+	if err != nil {
+		return validate.NewFieldsError("Last-Event-ID", err)
+	}
+
+	claims := auth.GetClaims(ctx)
+	admin := h.auth.Authorize(ctx, claims, auth.RuleAdminOnly) == nil
+
+	sub := h.broker.Subscribe(lastID, resume)
+	defer sub.Close()
+
+	s, err := web.NewStream(ctx, w, h.cfg.WriteTimeout, h.cfg.Retry)
+	if err != nil {
+		return fmt.Errorf("starting stream: %w", err)
+	}
+
+	send := func(ev stream.Event) error {
+		if !admin && ev.Owner != claims.Subject {
+			return nil
+		}
+
+		data, err := json.Marshal(toAppEvent(ev))
+		if err != nil {
+			return fmt.Errorf("marshal: eventID[%d]: %w", ev.ID, err)
+		}
+
+		return s.Send(web.Event{
+			ID:   strconv.FormatUint(ev.ID, 10),
+			Name: ev.Type,
+			Data: data,
+		})
+	}
+
+	// From here on a failed write means the client went away or stopped
+	// reading, which ends the stream. The response has started so there is
+	// nothing left to report to the client.
+	switch sub.Missed {
+	case true:
+		if s.Send(web.Event{Name: EventResync, Data: []byte("{}")}) != nil {
+			return nil
+		}
+
+	default:
+		for _, ev := range sub.Replay {
+			if send(ev) != nil {
+				return nil
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+
+	var expired <-chan time.Time
+	if claims.ExpiresAt != nil {
+		expiry := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer expiry.Stop()
+
+		expired = expiry.C
+	}
+
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+
+			// The subscriber was dropped, the client reconnects and
+			// resumes from the last event it received.
+			if !ok {
+				return nil
+			}
+
+			if send(ev) != nil {
+				return nil
+			}
+
+		case <-heartbeat.C:
+			if h.auth.Revalidate(ctx, claims) != nil {
+				return nil
+			}
+
+			if s.Comment("heartbeat") != nil {
+				return nil
+			}
+
+		case <-expired:
+			return nil
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// =============================================================================
+
+// parseLastEventID returns the id of the last event received by a
+// reconnecting client. The boolean is false for a new client.
+func parseLastEventID(r *http.Request) (uint64, bool, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, errors.New("invalid event id")
+	}
+
+	return id, true, nil
+}
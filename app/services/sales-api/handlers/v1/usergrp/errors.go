@@ -0,0 +1,18 @@
+package usergrp
+
+import (
+	"net/http"
+
+	"github.com/qcbit/service/business/core/user"
+	v1 "github.com/qcbit/service/business/web/v1"
+)
+
+// init registers the responses for the errors of the user core.
+func init() {
+	v1.RegisterError(user.ErrNotFound, http.StatusNotFound, "user_not_found", "user not found")
+	v1.RegisterError(user.ErrUniqueEmail, http.StatusConflict, "email_not_unique", "email is not unique")
+	v1.RegisterError(user.ErrAuthenticationFailure, http.StatusUnauthorized, "invalid_credentials", "authentication failed")
+	v1.RegisterError(user.ErrAccountLocked, http.StatusLocked, "account_locked", "account is temporarily locked")
+	v1.RegisterError(user.ErrWeakPassword, http.StatusBadRequest, "weak_password", "password does not meet the password policy")
+	v1.RegisterError(user.ErrUnknownRole, http.StatusBadRequest, "unknown_role", "role does not exist")
+}
@@ -52,7 +52,7 @@ func parseFilter(r *http.Request) (user.QueryFilter, error) {
 		filter.WithName(name)
 	}
 
-	if err := filter.Validate(); err != nil {
+	if err := filter.Validate(r.Context()); err != nil {
 		return user.QueryFilter{}, err
 	}
 
@@ -1,6 +1,7 @@
 package usergrp
 
 import (
+	"context"
 	"fmt"
 	"net/mail"
 	"time"
@@ -48,9 +49,9 @@ func toAppUser(usr user.User) AppUser {
 type AppNewUser struct {
 	Name            string   `json:"name" validate:"required"`
 	Email           string   `json:"email" validate:"required,email"`
-	Roles           []string `json:"roles" validate:"required"`
+	Roles           []string `json:"roles" validate:"required,dive,role"`
 	Department      string   `json:"department"`
-	Password        string   `json:"password" validate:"required"`
+	Password        string   `json:"password" validate:"required,password"`
 	PasswordConfirm string   `json:"passwordConfirm" validate:"eqfield=Password"`
 }
 
@@ -82,8 +83,8 @@ func toCoreNewUser(app AppNewUser) (user.NewUser, error) {
 }
 
 // Validate checks the data in the model is considered clean.
-func (app AppNewUser) Validate() error {
-	if err := validate.Check(app); err != nil {
+func (app AppNewUser) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
 		return fmt.Errorf("validating data: %w", err)
 	}
 	return nil
@@ -95,9 +96,9 @@ func (app AppNewUser) Validate() error {
 type AppUpdateUser struct {
 	Name            *string  `json:"name"`
 	Email           *string  `json:"email" validate:"omitempty,email"`
-	Roles           []string `json:"roles"`
+	Roles           []string `json:"roles" validate:"omitempty,dive,role"`
 	Department      *string  `json:"department"`
-	Password        *string  `json:"password"`
+	Password        *string  `json:"password" validate:"omitempty,password"`
 	PasswordConfirm *string  `json:"passwordConfirm" validate:"omitempty,eqfield=Password"`
 	Enabled         *bool    `json:"enabled"`
 }
@@ -138,8 +139,8 @@ func toCoreUpdateUser(app AppUpdateUser) (user.UpdateUser, error) {
 }
 
 // Validate checks the data in the model is considered clean.
-func (app AppUpdateUser) Validate() error {
-	if err := validate.Check(app); err != nil {
+func (app AppUpdateUser) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
 		return fmt.Errorf("validating data: %w", err)
 	}
 	return nil
@@ -147,6 +148,41 @@ func (app AppUpdateUser) Validate() error {
 
 // -----------------------------------------------------------------------------
 
+// AppUserRoles contains the set of roles to assign to a User.
+type AppUserRoles struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,role"`
+}
+
+func toCoreUserRoles(app AppUserRoles) ([]user.Role, error) {
+	roles := make([]user.Role, len(app.Roles))
+	for i, roleStr := range app.Roles {
+		role, err := user.ParseRole(roleStr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing role: %w", err)
+		}
+		roles[i] = role
+	}
+
+	return roles, nil
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppUserRoles) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, app); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// AppToken represents a token issued for an authenticated user.
+type AppToken struct {
+	Token string `json:"token"`
+}
+
+// -----------------------------------------------------------------------------
+
 // AppUserSummary represents information about an individual user and their products.
 type AppUserSummary struct {
 	UserID     string  `json:"userID"`
@@ -3,25 +3,41 @@ package usergrp
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/mail"
+	"time"
 
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/qcbit/service/business/core/mfa"
 	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/sys/validate"
+	"github.com/qcbit/service/business/web/auth"
 	v1 "github.com/qcbit/service/business/web/v1"
 	"github.com/qcbit/service/business/web/v1/paging"
 	"github.com/qcbit/service/foundation/web"
 )
 
+// tokenDuration is how long a token issued by the token endpoint is valid.
+const tokenDuration = time.Hour
+
 // Handlers manages the set of user endpoints.
 type Handlers struct {
 	user *user.Core
+	mfa  *mfa.Core
+	auth *auth.Auth
 }
 
 // New constructs a handlers for route access.
-func New(user *user.Core) *Handlers {
+func New(user *user.Core, mfa *mfa.Core, auth *auth.Auth) *Handlers {
 	return &Handlers{
 		user: user,
+		mfa:  mfa,
+		auth: auth,
 	}
 }
 
@@ -39,15 +55,42 @@ func (h *Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Re
 
 	usr, err := h.user.Create(ctx, nc)
 	if err != nil {
-		if errors.Is(err, user.ErrUniqueEmail) {
-			return v1.NewRequestError(err, http.StatusConflict)
-		}
 		return fmt.Errorf("create: usr[%+v]: %w", usr, err)
 	}
 
 	return web.Respond(ctx, w, toAppUser(usr), http.StatusCreated)
 }
 
+// AssignRoles replaces the set of roles assigned to a user.
+func (h *Handlers) AssignRoles(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppUserRoles
+	if err := web.Decode(r, &app); err != nil {
+		return err
+	}
+
+	userID, err := validate.ParseUUID(ctx, "user_id", web.Param(r, "user_id"))
+	if err != nil {
+		return err
+	}
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	roles, err := toCoreUserRoles(app)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	usr, err = h.user.Update(ctx, usr, user.UpdateUser{Roles: roles})
+	if err != nil {
+		return fmt.Errorf("update: userID[%s] roles[%v]: %w", userID, roles, err)
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
+}
+
 // Update updates a user in the system.
 // func (h *Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 // 	var app AppUpdateUser
@@ -118,6 +161,17 @@ func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Req
 		return err
 	}
 
+	// The version of the set is cheap to query, a client holding the current
+	// page doesn't need the users to be loaded.
+	ver, err := h.user.QueryVersion(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("queryversion: %w", err)
+	}
+
+	if web.CheckNotModified(ctx, w, r, versionETag(ver, r.URL.RawQuery), ver.DateUpdated) {
+		return nil
+	}
+
 	users, err := h.user.Query(ctx, filter, orderBy, page.Number, page.RowsPerPage)
 	if err != nil {
 		return fmt.Errorf("query: %w", err)
@@ -128,12 +182,7 @@ func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Req
 		items[i] = toAppUser(usr)
 	}
 
-	total, err := h.user.Count(ctx, filter)
-	if err != nil {
-		return fmt.Errorf("count: %w", err)
-	}
-
-	return web.Respond(ctx, w, paging.NewResponse(items, total, page.Number, page.RowsPerPage), http.StatusOK)
+	return web.Respond(ctx, w, paging.NewResponse(items, ver.Count, page.Number, page.RowsPerPage), http.StatusOK)
 }
 
 // QueryByID returns a user by its ID.
@@ -152,3 +201,105 @@ func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Req
 
 // 	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
 // }
+
+// Token provides an API token for the authenticated user. Users enrolled in
+// mfa must also provide a code in the X-MFA-Code header.
+func (h *Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	kid := web.Param(r, "kid")
+	if kid == "" {
+		return validate.NewFieldsError("kid", errors.New("missing kid"))
+	}
+
+	email, pass, ok := r.BasicAuth()
+	if !ok {
+		return auth.NewAuthError("must provide email and password in Basic auth")
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return auth.NewAuthErrorWithCode(auth.CodeInvalidCredentials, "invalid email format")
+	}
+
+	usr, err := h.user.Authenticate(ctx, *addr, pass)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound), errors.Is(err, user.ErrAuthenticationFailure):
+			return auth.NewAuthErrorWithCode(auth.CodeInvalidCredentials, err.Error())
+		default:
+			return fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	if !usr.Enabled {
+		return auth.NewAuthErrorWithCode(auth.CodeAccountDisabled, "user disabled")
+	}
+
+	amr, err := h.secondFactor(ctx, usr, r.Header.Get(auth.MFAHeader))
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.user.CompleteLogin(ctx, usr); err != nil {
+		return fmt.Errorf("completelogin: userID[%s]: %w", usr.ID, err)
+	}
+
+	now := time.Now()
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   usr.ID.String(),
+			Issuer:    h.auth.Issuer(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles: usr.Roles,
+		AMR:   amr,
+	}
+
+	token, err := h.auth.GenerateToken(kid, claims)
+	if err != nil {
+		return fmt.Errorf("generatetoken: %w", err)
+	}
+
+	return web.Respond(ctx, w, AppToken{Token: token}, http.StatusOK)
+}
+
+// secondFactor verifies the mfa code for users enrolled in mfa and returns
+// the authentication methods used. A failed code counts as a failed login.
+func (h *Handlers) secondFactor(ctx context.Context, usr user.User, code string) ([]string, error) {
+	amr := []string{auth.AMRPassword}
+
+	enrolled, err := h.mfa.Enrolled(ctx, usr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("enrolled: userID[%s]: %w", usr.ID, err)
+	}
+
+	if !enrolled {
+		return amr, nil
+	}
+
+	if code == "" {
+		return nil, auth.NewAuthErrorWithCode(auth.CodeMFARequired, "mfa code required in the %s header", auth.MFAHeader)
+	}
+
+	if _, err := h.mfa.Verify(ctx, usr.ID, code); err != nil {
+		if !errors.Is(err, mfa.ErrInvalidCode) {
+			return nil, fmt.Errorf("verify: userID[%s]: %w", usr.ID, err)
+		}
+
+		if err := h.user.RecordLoginFailure(ctx, usr); err != nil {
+			return nil, fmt.Errorf("recordloginfailure: userID[%s]: %w", usr.ID, err)
+		}
+
+		return nil, auth.NewAuthErrorWithCode(auth.CodeInvalidCredentials, err.Error())
+	}
+
+	return append(amr, auth.AMROTP), nil
+}
+
+// versionETag returns a weak entity tag for a page of users. The page depends
+// on the query string as well as the version of the set of users.
+func versionETag(ver user.Version, query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return web.WeakETag(fmt.Sprintf("%d-%d-%s", ver.Count, ver.DateUpdated.UnixNano(), hex.EncodeToString(sum[:8])))
+}
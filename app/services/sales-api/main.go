@@ -2,24 +2,43 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/ardanlabs/conf/v3"
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/apikey/stores/apikeydb"
+	"github.com/qcbit/service/business/core/idempotency"
+	"github.com/qcbit/service/business/core/idempotency/stores/idempotencydb"
+	"github.com/qcbit/service/business/core/role"
+	"github.com/qcbit/service/business/core/role/stores/roledb"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/core/user/stores/userdb"
 	database "github.com/qcbit/service/business/sys/database/pgx"
+	"github.com/qcbit/service/business/sys/events"
+	"github.com/qcbit/service/business/sys/events/stores/outboxdb"
+	"github.com/qcbit/service/business/sys/notify"
 	"github.com/qcbit/service/business/web/auth"
 	"github.com/qcbit/service/business/web/v1/debug"
+	"github.com/qcbit/service/business/web/v1/mid"
+	"github.com/qcbit/service/business/web/v1/stream"
+	"github.com/qcbit/service/foundation/certs"
 	"github.com/qcbit/service/foundation/keystore"
+	"github.com/qcbit/service/foundation/lifecycle"
 	"github.com/qcbit/service/foundation/logger"
+	"github.com/qcbit/service/foundation/tracer"
 	"go.uber.org/zap"
 
 	"github.com/qcbit/service/app/services/sales-api/handlers"
+	"github.com/qcbit/service/app/services/sales-api/handlers/v1/streamgrp"
 )
 
 var build = "develop"
@@ -58,6 +77,20 @@ func run(log *zap.SugaredLogger) error {
 			ShutdownTimeout time.Duration `conf:"default:20s,mask"`
 			APIHost         string        `conf:"default:0.0.0.0:3000"`
 			DebugHost       string        `conf:"default:0.0.0.0:4000"`
+			MaxBodyBytes    int64         `conf:"default:1048576"`
+			ErrorFormat     string        `conf:"default:legacy"`
+			ProblemTypeBase string        `conf:""`
+		}
+		TLS struct {
+			CertFile          string        `conf:""`
+			KeyFile           string        `conf:""`
+			ReloadInterval    time.Duration `conf:"default:1m"`
+			DebugClientCAFile string        `conf:""`
+		}
+		Shutdown struct {
+			DrainPeriod time.Duration `conf:"default:20s"`
+			HookTimeout time.Duration `conf:"default:5s"`
+			Timeout     time.Duration `conf:"default:50s"`
 		}
 		DB struct {
 			User         string `conf:"default:postgres"`
@@ -69,9 +102,83 @@ func run(log *zap.SugaredLogger) error {
 			DisableTLS   bool   `conf:"default:true"`
 		}
 		Auth struct {
-			KeysFolder string `conf:"default:zarf/keys/"`
-			ActiveKID  string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
-			Issuer     string `conf:"default:service project"`
+			KeysFolder         string        `conf:"default:zarf/keys/"`
+			ActiveKID          string        `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+			Issuer             string        `conf:"default:service project"`
+			PolicyFolder       string        `conf:""`
+			PolicyPollInterval time.Duration `conf:"default:10s"`
+		}
+		Users struct {
+			BcryptCost             int           `conf:"default:10"`
+			MaxFailedLogins        int           `conf:"default:5"`
+			LockoutDuration        time.Duration `conf:"default:15m"`
+			PasswordMinLength      int           `conf:"default:8"`
+			PasswordRequireUpper   bool          `conf:"default:true"`
+			PasswordRequireLower   bool          `conf:"default:true"`
+			PasswordRequireDigit   bool          `conf:"default:true"`
+			PasswordRequireSymbol  bool          `conf:"default:false"`
+			PasswordRejectPersonal bool          `conf:"default:true"`
+			PasswordResetTTL       time.Duration `conf:"default:30m"`
+		}
+		Notify struct {
+			Mode string `conf:"default:log"`
+			File string `conf:"default:zarf/notify/outbox.log"`
+		}
+		Load struct {
+			RequestTimeout time.Duration `conf:"default:5s"`
+			AuthTimeout    time.Duration `conf:"default:3s"`
+			MaxInFlight    int           `conf:"default:200"`
+			QueueTimeout   time.Duration `conf:"default:100ms"`
+		}
+		Idempotency struct {
+			TTL           time.Duration `conf:"default:24h"`
+			LockTimeout   time.Duration `conf:"default:1m"`
+			PurgeInterval time.Duration `conf:"default:1h"`
+		}
+		Events struct {
+			BatchSize     int           `conf:"default:100"`
+			PollInterval  time.Duration `conf:"default:1s"`
+			Retention     time.Duration `conf:"default:168h"`
+			PurgeInterval time.Duration `conf:"default:1h"`
+			MaxAttempts   int           `conf:"default:20"`
+			Backoff       time.Duration `conf:"default:1s"`
+			MaxBackoff    time.Duration `conf:"default:5m"`
+		}
+		Stream struct {
+			ReplaySize   int           `conf:"default:1000"`
+			BufferSize   int           `conf:"default:64"`
+			Heartbeat    time.Duration `conf:"default:15s"`
+			WriteTimeout time.Duration `conf:"default:10s"`
+			Retry        time.Duration `conf:"default:3s"`
+		}
+		Compress struct {
+			Enabled      bool     `conf:"default:true"`
+			MinSize      int      `conf:"default:1024"`
+			ContentTypes []string `conf:"default:application/json;application/problem+json;text/*"`
+		}
+		CORS struct {
+			AllowedOrigins   []string      `conf:""`
+			AllowedMethods   []string      `conf:"default:GET;POST;PUT;PATCH;DELETE"`
+			AllowedHeaders   []string      `conf:"default:Authorization;Content-Type;X-API-Key;X-MFA-Code;Idempotency-Key;If-None-Match;If-Modified-Since;Last-Event-ID;traceparent;tracestate"`
+			ExposedHeaders   []string      `conf:"default:X-Trace-ID;RateLimit-Limit;RateLimit-Remaining;RateLimit-Reset;Retry-After;Idempotent-Replayed;ETag"`
+			AllowCredentials bool          `conf:"default:false"`
+			MaxAge           time.Duration `conf:"default:10m"`
+		}
+		RateLimit struct {
+			TrustedProxies []string `conf:""`
+			ClientRate     float64  `conf:"default:50"`
+			ClientBurst    int      `conf:"default:100"`
+			UserRate       float64  `conf:"default:20"`
+			UserBurst      int      `conf:"default:40"`
+			AuthRate       float64  `conf:"default:0.2"`
+			AuthBurst      int      `conf:"default:5"`
+		}
+		Tracing struct {
+			ReporterURL   string        `conf:""`
+			ServiceName   string        `conf:"default:sales-api"`
+			Probability   float64       `conf:"default:0.05"`
+			BatchSize     int           `conf:"default:100"`
+			FlushInterval time.Duration `conf:"default:5s"`
 		}
 	}{
 		Version: conf.Version{
@@ -102,6 +209,32 @@ func run(log *zap.SugaredLogger) error {
 	}
 	log.Infow("startup", "config", out)
 
+	// -------------------------------------------------------------------------
+	// Lifecycle Support
+
+	// On shutdown the service reports it isn't ready, keeps serving while
+	// traffic drains, stops the servers, and then runs the hooks in the
+	// reverse order they are added below. The drain period must outlast the
+	// readiness probe failing, and the whole shutdown must complete within
+	// the termination grace period of the pod.
+	if cfg.Shutdown.Timeout > 0 && cfg.Shutdown.DrainPeriod >= cfg.Shutdown.Timeout {
+		return fmt.Errorf("shutdown drain period %v must be shorter than the shutdown timeout %v", cfg.Shutdown.DrainPeriod, cfg.Shutdown.Timeout)
+	}
+
+	lc := lifecycle.New(lifecycle.Config{
+		DrainPeriod: cfg.Shutdown.DrainPeriod,
+		HookTimeout: cfg.Shutdown.HookTimeout,
+		Timeout:     cfg.Shutdown.Timeout,
+		OnStep: func(step string) {
+			log.Infow("shutdown", "status", step)
+		},
+	})
+
+	lc.AddHook("logger", 0, func(ctx context.Context) error {
+		log.Sync()
+		return nil
+	})
+
 	// -------------------------------------------------------------------------
 	// Database Support
 
@@ -119,10 +252,31 @@ func run(log *zap.SugaredLogger) error {
 	if err != nil {
 		return fmt.Errorf("connecting to db: %w", err)
 	}
-	defer func() {
+	lc.AddHook("database", 0, func(ctx context.Context) error {
 		log.Infow("shutdown", "status", "stopping database support", "host", cfg.DB.Host)
-		db.Close()
-	}()
+		return db.Close()
+	})
+
+	// Background workers stop when the worker context is canceled.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	lc.AddHook("workers", 0, func(ctx context.Context) error {
+		stopWorkers()
+
+		done := make(chan struct{})
+		go func() {
+			workers.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 
 	// -------------------------------------------------------------------------
 	// Initialize authentication support
@@ -135,9 +289,42 @@ func run(log *zap.SugaredLogger) error {
 		return fmt.Errorf("reading keys: %w", err)
 	}
 
+	// Roles and the permissions they grant are stored in the database. The
+	// roles assigned to users are validated against the role store.
+	roleCore := role.NewCore(log, roledb.NewStore(log, db))
+
+	usrCfg := user.Config{
+		BcryptCost:      cfg.Users.BcryptCost,
+		MaxFailedLogins: cfg.Users.MaxFailedLogins,
+		LockoutDuration: cfg.Users.LockoutDuration,
+		PasswordPolicy: &user.PasswordPolicy{
+			MinLength:      cfg.Users.PasswordMinLength,
+			RequireUpper:   cfg.Users.PasswordRequireUpper,
+			RequireLower:   cfg.Users.PasswordRequireLower,
+			RequireDigit:   cfg.Users.PasswordRequireDigit,
+			RequireSymbol:  cfg.Users.PasswordRequireSymbol,
+			RejectPersonal: cfg.Users.PasswordRejectPersonal,
+		},
+		Roles: roleCore,
+	}
+
+	usrCore := user.NewCore(userdb.NewStore(log, db), usrCfg)
+
+	// Passwords in requests are validated against the same policy.
+	user.SetPasswordPolicy(*usrCfg.PasswordPolicy)
+
+	// API keys are validated against the database so service-to-service
+	// clients don't need long lived JWTs.
+	keyCore := apikey.NewCore(usrCore, apikeydb.NewStore(log, db))
+
 	authCfg := auth.Config{
-		Log:       log,
-		KeyLookup: ks,
+		Log:              log,
+		KeyLookup:        ks,
+		APIKeyLookup:     keyCore,
+		PermissionLookup: roleCore,
+		UserLookup:       usrCore,
+		Issuer:           cfg.Auth.Issuer,
+		PolicyFolder:     cfg.Auth.PolicyFolder,
 	}
 
 	auth, err := auth.New(authCfg)
@@ -145,13 +332,186 @@ func run(log *zap.SugaredLogger) error {
 		return fmt.Errorf("constructing auth: %w", err)
 	}
 
+	// When a policy folder is configured, poll it for changes and reload on
+	// SIGHUP. A policy that fails to compile is logged and never swapped in.
+	if cfg.Auth.PolicyFolder != "" {
+		log.Infow("startup", "status", "watching policy folder", "folder", cfg.Auth.PolicyFolder, "hash", auth.PolicyStatus().Hash)
+
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			ticker := time.NewTicker(cfg.Auth.PolicyPollInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+				case <-reload:
+					log.Infow("policy", "status", "reload requested", "signal", syscall.SIGHUP)
+				case <-workerCtx.Done():
+					return
+				}
+
+				swapped, err := auth.ReloadPolicies()
+				if err != nil {
+					log.Errorw("policy", "status", "reload failed, keeping active policy", "hash", auth.PolicyStatus().Hash, "ERROR", err)
+					continue
+				}
+
+				if swapped {
+					log.Infow("policy", "status", "policy reloaded", "hash", auth.PolicyStatus().Hash)
+				}
+			}
+		}()
+	}
+
+	// -------------------------------------------------------------------------
+	// Initialize notification support
+
+	log.Infow("startup", "status", "initializing notification support", "mode", cfg.Notify.Mode)
+
+	var notifier notify.Notifier
+	switch cfg.Notify.Mode {
+	case "log":
+		notifier = notify.NewLog(log)
+	case "file":
+		fn, err := notify.NewFile(cfg.Notify.File)
+		if err != nil {
+			return fmt.Errorf("constructing file notifier: %w", err)
+		}
+		notifier = fn
+	default:
+		return fmt.Errorf("unknown notify mode %q", cfg.Notify.Mode)
+	}
+
+	// -------------------------------------------------------------------------
+	// Initialize idempotency support
+
+	log.Infow("startup", "status", "initializing idempotency support", "ttl", cfg.Idempotency.TTL)
+
+	idemCore := idempotency.NewCore(idempotencydb.NewStore(log, db), idempotency.Config{
+		TTL:         cfg.Idempotency.TTL,
+		LockTimeout: cfg.Idempotency.LockTimeout,
+	})
+
+	// Recorded responses are kept until they expire, expired records are
+	// purged in the background.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+
+		ticker := time.NewTicker(cfg.Idempotency.PurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-workerCtx.Done():
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(workerCtx, time.Minute)
+			if err := idemCore.Purge(ctx); err != nil {
+				log.Errorw("idempotency", "status", "purging expired keys", "ERROR", err)
+			}
+			cancel()
+		}
+	}()
+
+	// -------------------------------------------------------------------------
+	// Start Tracing Support
+
+	// Spans are only recorded when a zipkin collector is configured.
+	var trc *tracer.Tracer
+	if cfg.Tracing.ReporterURL != "" {
+		log.Infow("startup", "status", "initializing tracing support", "reporter", cfg.Tracing.ReporterURL, "probability", cfg.Tracing.Probability)
+
+		trc = tracer.New(tracer.Config{
+			ServiceName:   cfg.Tracing.ServiceName,
+			ReporterURL:   cfg.Tracing.ReporterURL,
+			Probability:   cfg.Tracing.Probability,
+			BatchSize:     cfg.Tracing.BatchSize,
+			FlushInterval: cfg.Tracing.FlushInterval,
+			OnError: func(err error) {
+				log.Errorw("tracing", "status", "exporting spans", "ERROR", err)
+			},
+		})
+		lc.AddHook("tracer", cfg.Web.ShutdownTimeout, func(ctx context.Context) error {
+			log.Infow("shutdown", "status", "stopping tracing support", "exported", trc.Exported(), "dropped", trc.Dropped())
+			return trc.Shutdown(ctx)
+		})
+	}
+
+	// -------------------------------------------------------------------------
+	// Initialize TLS support
+
+	// When a certificate is configured both servers only accept TLS. The
+	// certificate files are checked for changes so rotated certificates are
+	// served without a restart.
+	var apiTLS, debugTLS *tls.Config
+
+	// debugProtect guards the debug routes, other than the probes, when
+	// client certificates are configured.
+	var debugProtect func(http.Handler) http.Handler
+
+	switch {
+	case cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "":
+		log.Infow("startup", "status", "initializing tls support", "cert", cfg.TLS.CertFile, "clientCA", cfg.TLS.DebugClientCAFile)
+
+		reloader, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return fmt.Errorf("loading certificate: %w", err)
+		}
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			reloader.Watch(workerCtx, cfg.TLS.ReloadInterval, func(err error) {
+				log.Errorw("tls", "status", "reload failed, keeping active certificate", "ERROR", err)
+			})
+		}()
+
+		apiTLS = certs.ServerConfig(reloader)
+		debugTLS = certs.ServerConfig(reloader)
+
+		if cfg.TLS.DebugClientCAFile != "" {
+			if err := certs.VerifyClientCerts(debugTLS, cfg.TLS.DebugClientCAFile); err != nil {
+				return fmt.Errorf("configuring debug client certificates: %w", err)
+			}
+			debugProtect = certs.RequireClientCert
+		}
+
+	case cfg.TLS.DebugClientCAFile != "":
+		return errors.New("debug client certificates require a certificate and key")
+	}
+
+	// serve starts the server, with TLS when it's configured.
+	serve := func(srv *http.Server) error {
+		if srv.TLSConfig != nil {
+			return srv.ListenAndServeTLS("", "")
+		}
+		return srv.ListenAndServe()
+	}
+
 	// -------------------------------------------------------------------------
 	// Start Debug Service
 
 	log.Infow("startup", "status", "debug v1 router started", "host", cfg.Web.DebugHost)
 
+	debugSrv := http.Server{
+		Addr:      cfg.Web.DebugHost,
+		Handler:   debug.Mux(build, log, db, auth, lc.Ready, debugProtect),
+		TLSConfig: debugTLS,
+		ErrorLog:  zap.NewStdLog(log.Desugar()),
+	}
+
 	go func() {
-		if err := http.ListenAndServe(cfg.Web.DebugHost, debug.Mux(build, log, db)); err != nil {
+		if err := serve(&debugSrv); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Errorw("shutdown", "status", "debug v1 router closed", "host", cfg.Web.DebugHost, "ERROR", err)
 		}
 	}()
@@ -161,14 +521,123 @@ func run(log *zap.SugaredLogger) error {
 
 	log.Infow("startup", "status", "initializing V1 API support")
 
+	proxies, err := mid.ParseTrustedProxies(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("parsing trusted proxies: %w", err)
+	}
+
+	switch cfg.Web.ErrorFormat {
+	case mid.ErrorFormatLegacy, mid.ErrorFormatProblem:
+	default:
+		return fmt.Errorf("unknown error format %q", cfg.Web.ErrorFormat)
+	}
+
+	rateLimits := handlers.RateLimits{
+		Client: mid.RateLimitConfig{Name: "client", Rate: cfg.RateLimit.ClientRate, Burst: cfg.RateLimit.ClientBurst, TrustedProxies: proxies},
+		User:   mid.RateLimitConfig{Name: "user", Rate: cfg.RateLimit.UserRate, Burst: cfg.RateLimit.UserBurst, TrustedProxies: proxies},
+		Auth:   mid.RateLimitConfig{Name: "auth", Rate: cfg.RateLimit.AuthRate, Burst: cfg.RateLimit.AuthBurst, TrustedProxies: proxies},
+	}
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+	// Changes are streamed to clients, recent changes are kept for clients
+	// resuming the stream.
+	broker := stream.NewBroker(stream.Config{
+		ReplaySize: cfg.Stream.ReplaySize,
+		BufferSize: cfg.Stream.BufferSize,
+	})
+
+	// -------------------------------------------------------------------------
+	// Start Domain Events Support
+
+	log.Infow("startup", "status", "initializing domain events support", "poll", cfg.Events.PollInterval)
+
+	// The cores write the events of every change to the outbox, the relay
+	// delivers them to the subscribers once the change is committed. The
+	// relay of one instance delivers each event, the subscribers of the
+	// broadcast bus follow the deliveries and receive every event in every
+	// instance, so every client streaming changes sees them.
+	outbox := outboxdb.NewStore(log, db)
+	eventsCfg := events.Config{
+		BatchSize:     cfg.Events.BatchSize,
+		PollInterval:  cfg.Events.PollInterval,
+		Retention:     cfg.Events.Retention,
+		PurgeInterval: cfg.Events.PurgeInterval,
+		Retry: events.Retry{
+			MaxAttempts: cfg.Events.MaxAttempts,
+			Backoff:     cfg.Events.Backoff,
+			MaxBackoff:  cfg.Events.MaxBackoff,
+		},
+	}
+
+	bus := events.NewBus()
+	relay := events.NewRelay(log, outbox, bus, eventsCfg)
+
+	broadcast := events.NewBus()
+	broadcast.Subscribe("stream", streamgrp.Forward(broker))
+	follower := events.NewFollower(log, outbox, broadcast, eventsCfg)
+
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		relay.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		follower.Run(workerCtx)
+	}()
+
+	var compress *mid.CompressConfig
+	if cfg.Compress.Enabled {
+		compress = &mid.CompressConfig{
+			MinSize:      cfg.Compress.MinSize,
+			ContentTypes: cfg.Compress.ContentTypes,
+		}
+	}
+
 	apiMux := handlers.APIMux(handlers.APIMuxConfig{
-		Shutdown: shutdown,
-		Log:      log,
-		Auth:     auth,
-		DB:       db,
+		Build:        build,
+		Shutdown:     shutdown,
+		Log:          log,
+		Auth:         auth,
+		DB:           db,
+		Role:         roleCore,
+		User:         usrCfg,
+		Notifier:     notifier,
+		ResetTTL:     cfg.Users.PasswordResetTTL,
+		MaxBodyBytes: cfg.Web.MaxBodyBytes,
+		Tracer:       trc,
+		RateLimits:   rateLimits,
+		Errors: mid.ErrorsConfig{
+			Format:          cfg.Web.ErrorFormat,
+			ProblemTypeBase: cfg.Web.ProblemTypeBase,
+		},
+		Load: handlers.Load{
+			Timeout:     cfg.Load.RequestTimeout,
+			AuthTimeout: cfg.Load.AuthTimeout,
+			Shed: mid.LoadShedConfig{
+				Name:         "v1",
+				MaxInFlight:  cfg.Load.MaxInFlight,
+				QueueTimeout: cfg.Load.QueueTimeout,
+			},
+		},
+		Idempotency: idemCore,
+		Broker:      broker,
+		Stream: streamgrp.Config{
+			Heartbeat:    cfg.Stream.Heartbeat,
+			WriteTimeout: cfg.Stream.WriteTimeout,
+			Retry:        cfg.Stream.Retry,
+		},
+		Compress: compress,
+		CORS: mid.CORSConfig{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		},
 	})
 
 	api := http.Server{
@@ -177,33 +646,60 @@ func run(log *zap.SugaredLogger) error {
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 		IdleTimeout:  cfg.Web.IdleTimeout,
+		TLSConfig:    apiTLS,
 		ErrorLog:     zap.NewStdLog(log.Desugar()),
 	}
 
+	// Shutdown waits for requests to complete, streams never do so they are
+	// ended as the shutdown starts.
+	api.RegisterOnShutdown(broker.Close)
+
 	serverErrors := make(chan error, 1)
 
 	go func() {
-		log.Infow("startup", "status", "api router started", "host", api.Addr)
-		serverErrors <- api.ListenAndServe()
+		log.Infow("startup", "status", "api router started", "host", api.Addr, "tls", api.TLSConfig != nil)
+		serverErrors <- serve(&api)
 	}()
 
+	// The api server is stopped before the debug server so readiness keeps
+	// being reported while requests drain.
+	lc.AddServer("api", &api, cfg.Web.ShutdownTimeout)
+	lc.AddServer("debug", &debugSrv, cfg.Web.ShutdownTimeout)
+	lc.SetReady(true)
+
 	// -------------------------------------------------------------------------
 	// Shutdown
 
 	select {
 	case err := <-serverErrors:
+
+		// There is no traffic to drain when the server failed.
+		lc.SetReady(false)
+		if err := lc.Shutdown(context.Background()); err != nil {
+			log.Errorw("shutdown", "status", "shutdown failed", "ERROR", err)
+		}
+
 		return fmt.Errorf("server error: %w", err)
 
 	case sig := <-shutdown:
 		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
 
-		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+		// A second signal cuts the drain period short.
+		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		if err := api.Shutdown(ctx); err != nil {
-			api.Close()
-			return fmt.Errorf("could not stop server gracefully: %w", err)
+		go func() {
+			select {
+			case sig := <-shutdown:
+				log.Infow("shutdown", "status", "skipping drain", "signal", sig)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		if err := lc.Shutdown(ctx); err != nil {
+			return fmt.Errorf("could not stop gracefully: %w", err)
 		}
 	}
 
@@ -42,7 +42,7 @@ func main() {
 		}
 
 		// I like always having a traceid present in the logs.
-		traceID := "00000000-0000-0000-0000-000000000000"
+		traceID := "00000000000000000000000000000000"
 		if v, ok := m["trace_id"]; ok {
 			traceID = fmt.Sprintf("%v", v)
 		}
@@ -0,0 +1,252 @@
+// Package apikey provides the core business API for issuing and validating
+// API keys used by service-to-service clients.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/data/order"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound      = errors.New("api key not found")
+	ErrInvalidKey    = errors.New("api key is not valid")
+	ErrExpired       = errors.New("api key has expired")
+	ErrRevoked       = errors.New("api key has been revoked")
+	ErrInvalidRoles  = errors.New("api key roles must be a subset of the owner's roles")
+	ErrInvalidOwner  = errors.New("api key owner is not enabled")
+	ErrOwnerDisabled = errors.New("api keys can't be issued for a disabled user")
+	ErrInvalidDate   = errors.New("api key expiration must be in the future")
+)
+
+// Set of values used to construct and display keys.
+const (
+	keyPrefix     = "qcb_"
+	keyBytes      = 32
+	displayLength = len(keyPrefix) + 8
+)
+
+// lastUsedResolution is how stale the last used timestamp can become before
+// it is written back to storage. This keeps a write off every request.
+const lastUsedResolution = time.Minute
+
+// Storer interface declares the behavior this package needs to persists and
+// retrieve data.
+type Storer interface {
+	Create(ctx context.Context, key Key) error
+	Revoke(ctx context.Context, key Key) error
+	UpdateLastUsed(ctx context.Context, key Key) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Key, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, keyID uuid.UUID) (Key, error)
+	QueryByHash(ctx context.Context, hash string) (Key, error)
+}
+
+// Core manages the set of APIs for api key access.
+type Core struct {
+	usrCore *user.Core
+	storer  Storer
+}
+
+// NewCore constructs a core for api key access.
+func NewCore(usrCore *user.Core, storer Storer) *Core {
+	return &Core{
+		usrCore: usrCore,
+		storer:  storer,
+	}
+}
+
+// Create issues a new API key for the specified user. The plain text key is
+// returned alongside the stored key and can't be recovered afterwards.
+func (c *Core) Create(ctx context.Context, nk NewKey) (Key, string, error) {
+	now := time.Now()
+
+	if !nk.DateExpires.After(now) {
+		return Key{}, "", ErrInvalidDate
+	}
+
+	usr, err := c.usrCore.QueryByID(ctx, nk.UserID)
+	if err != nil {
+		return Key{}, "", fmt.Errorf("query: userID[%s]: %w", nk.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return Key{}, "", ErrOwnerDisabled
+	}
+
+	if !subsetOf(nk.Roles, usr.Roles) {
+		return Key{}, "", ErrInvalidRoles
+	}
+
+	plain, err := generate()
+	if err != nil {
+		return Key{}, "", fmt.Errorf("generate: %w", err)
+	}
+
+	key := Key{
+		ID:          uuid.New(),
+		UserID:      nk.UserID,
+		Name:        nk.Name,
+		Prefix:      plain[:displayLength],
+		Hash:        hash(plain),
+		Roles:       nk.Roles,
+		DateExpires: nk.DateExpires,
+		DateCreated: now,
+	}
+
+	if err := c.storer.Create(ctx, key); err != nil {
+		return Key{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return key, plain, nil
+}
+
+// Revoke marks the key as revoked so it can no longer be used.
+func (c *Core) Revoke(ctx context.Context, key Key) (Key, error) {
+	if key.Revoked() {
+		return key, nil
+	}
+
+	key.DateRevoked = time.Now()
+
+	if err := c.storer.Revoke(ctx, key); err != nil {
+		return Key{}, fmt.Errorf("revoke: %w", err)
+	}
+
+	return key, nil
+}
+
+// Query retrieves a list of existing api keys from the database.
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Key, error) {
+	keys, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Count returns the total number of api keys in the store.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return c.storer.Count(ctx, filter)
+}
+
+// QueryByID gets the specified api key from the database.
+func (c *Core) QueryByID(ctx context.Context, keyID uuid.UUID) (Key, error) {
+	key, err := c.storer.QueryByID(ctx, keyID)
+	if err != nil {
+		return Key{}, fmt.Errorf("query: keyID[%s]: %w", keyID, err)
+	}
+
+	return key, nil
+}
+
+// =============================================================================
+
+// Authenticate finds the key for the plain text value provided by a client
+// and verifies it can still be used. The returned key only carries the roles
+// its owner still holds. On success the last used timestamp of the key is
+// refreshed.
+func (c *Core) Authenticate(ctx context.Context, plain string) (Key, error) {
+	key, err := c.storer.QueryByHash(ctx, hash(plain))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Key{}, ErrInvalidKey
+		}
+		return Key{}, fmt.Errorf("query: %w", err)
+	}
+
+	now := time.Now()
+
+	switch {
+	case key.Revoked():
+		return Key{}, ErrRevoked
+	case !key.DateExpires.After(now):
+		return Key{}, ErrExpired
+	}
+
+	usr, err := c.usrCore.QueryByID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return Key{}, ErrInvalidOwner
+		}
+		return Key{}, fmt.Errorf("query: userID[%s]: %w", key.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return Key{}, ErrInvalidOwner
+	}
+
+	// The key never grants more than its owner holds now, roles removed from
+	// the owner after the key was created are dropped from the key.
+	key.Roles = intersect(key.Roles, usr.Roles)
+
+	if now.Sub(key.DateLastUsed) > lastUsedResolution {
+		key.DateLastUsed = now
+		if err := c.storer.UpdateLastUsed(ctx, key); err != nil {
+			return Key{}, fmt.Errorf("updatelastused: %w", err)
+		}
+	}
+
+	return key, nil
+}
+
+// =============================================================================
+
+// generate constructs a new random plain text key.
+func generate() (string, error) {
+	b := make([]byte, keyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hash returns the value stored for a plain text key. Keys carry enough
+// entropy that a fast hash is sufficient and allows lookups by hash.
+func hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// subsetOf reports if every role in roles exists in the set of roles.
+func subsetOf(roles []user.Role, set []user.Role) bool {
+	for _, role := range roles {
+		var found bool
+		for _, r := range set {
+			if role.Equal(r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// intersect returns the roles that also exist in the set of roles.
+func intersect(roles []user.Role, set []user.Role) []user.Role {
+	kept := make([]user.Role, 0, len(roles))
+	for _, role := range roles {
+		if subsetOf([]user.Role{role}, set) {
+			kept = append(kept, role)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,82 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/user"
+)
+
+func Test_AuthenticateRoles(t *testing.T) {
+	const plain = "qcb_plain"
+
+	now := time.Now()
+	usr := user.User{ID: uuid.New(), Enabled: true}
+	key := Key{
+		ID:           uuid.New(),
+		UserID:       usr.ID,
+		Hash:         hash(plain),
+		Roles:        []user.Role{user.RoleAdmin, user.RoleUser},
+		DateExpires:  now.Add(time.Hour),
+		DateLastUsed: now,
+	}
+
+	tt := []struct {
+		name  string
+		owner []user.Role
+		roles []user.Role
+	}{
+		{"unchanged", []user.Role{user.RoleAdmin, user.RoleUser}, []user.Role{user.RoleAdmin, user.RoleUser}},
+		{"demoted", []user.Role{user.RoleUser}, []user.Role{user.RoleUser}},
+		{"stripped", nil, []user.Role{}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			owner := usr
+			owner.Roles = tst.owner
+
+			usrCore := user.NewCore(users{usr: owner}, user.Config{})
+			core := NewCore(usrCore, keys{key: key})
+
+			got, err := core.Authenticate(context.Background(), plain)
+			if err != nil {
+				t.Fatalf("Should be able to authenticate the key: %s", err)
+			}
+
+			if diff := cmp.Diff(tst.roles, got.Roles); diff != "" {
+				t.Errorf("Should only keep the roles the owner still holds:\n%s", diff)
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+type users struct {
+	user.Storer
+	usr user.User
+}
+
+func (u users) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	if userID != u.usr.ID {
+		return user.User{}, user.ErrNotFound
+	}
+	return u.usr, nil
+}
+
+type keys struct {
+	Storer
+	key Key
+}
+
+func (k keys) QueryByHash(ctx context.Context, hash string) (Key, error) {
+	if hash != k.key.Hash {
+		return Key{}, ErrNotFound
+	}
+	return k.key, nil
+}
@@ -0,0 +1,27 @@
+package apikey
+
+import (
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+type QueryFilter struct {
+	ID     *uuid.UUID
+	UserID *uuid.UUID
+	Name   *string
+}
+
+// WithKeyID sets the ID field of the QueryFilter value.
+func (qf *QueryFilter) WithKeyID(keyID uuid.UUID) {
+	qf.ID = &keyID
+}
+
+// WithUserID sets the UserID field of the QueryFilter value.
+func (qf *QueryFilter) WithUserID(userID uuid.UUID) {
+	qf.UserID = &userID
+}
+
+// WithName sets the Name field of the QueryFilter value.
+func (qf *QueryFilter) WithName(name string) {
+	qf.Name = &name
+}
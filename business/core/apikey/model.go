@@ -0,0 +1,37 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/user"
+)
+
+// Key represents an API key issued to a user for service-to-service access.
+// The plain text value of the key is never stored, only its hash.
+type Key struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	Prefix       string
+	Hash         string
+	Roles        []user.Role
+	DateExpires  time.Time
+	DateLastUsed time.Time
+	DateRevoked  time.Time
+	DateCreated  time.Time
+}
+
+// Revoked reports if the key has been revoked.
+func (k Key) Revoked() bool {
+	return !k.DateRevoked.IsZero()
+}
+
+// NewKey contains information needed to create a new API key.
+type NewKey struct {
+	UserID      uuid.UUID
+	Name        string
+	Roles       []user.Role
+	DateExpires time.Time
+}
@@ -0,0 +1,16 @@
+package apikey
+
+import "github.com/qcbit/service/business/data/order"
+
+// DefaultOrderBy represents the default way we sort.
+var DefaultOrderBy = order.NewBy(OrderByDateCreated, order.DESC)
+
+// Set of fields that the results can be ordered by. These are the names
+// that should be used by the application layer.
+const (
+	OrderByID          = "keyid"
+	OrderByUserID      = "userid"
+	OrderByName        = "name"
+	OrderByDateExpires = "dateexpires"
+	OrderByDateCreated = "datecreated"
+)
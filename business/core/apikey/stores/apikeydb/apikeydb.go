@@ -0,0 +1,189 @@
+// Package apikeydb contains api key related CRUD functionality.
+package apikeydb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/data/order"
+	database "github.com/qcbit/service/business/sys/database/pgx"
+)
+
+// Store manages the set of APIs for api key database access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the API for data access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new api key into the database.
+func (s *Store) Create(ctx context.Context, key apikey.Key) error {
+	const q = `
+	INSERT INTO api_keys
+		(api_key_id, user_id, name, prefix, key_hash, roles, date_expires, date_last_used, date_revoked, date_created)
+	VALUES
+		(:api_key_id, :user_id, :name, :prefix, :key_hash, :roles, :date_expires, :date_last_used, :date_revoked, :date_created)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBKey(key)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Revoke records the date the api key was revoked.
+func (s *Store) Revoke(ctx context.Context, key apikey.Key) error {
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		"date_revoked" = :date_revoked
+	WHERE
+		api_key_id = :api_key_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBKey(key)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateLastUsed records the last time the api key was used.
+func (s *Store) UpdateLastUsed(ctx context.Context, key apikey.Key) error {
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		"date_last_used" = :date_last_used
+	WHERE
+		api_key_id = :api_key_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBKey(key)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing api keys from the database.
+func (s *Store) Query(ctx context.Context, filter apikey.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]apikey.Key, error) {
+	data := map[string]interface{}{
+		"offset":        (pageNumber - 1) * rowsPerPage,
+		"rows_per_page": rowsPerPage,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		api_keys`
+
+	buf := bytes.NewBufferString(q)
+	s.applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var dbKeys []dbKey
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &dbKeys); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreKeySlice(dbKeys), nil
+}
+
+// Count returns the total number of api keys in the DB.
+func (s *Store) Count(ctx context.Context, filter apikey.QueryFilter) (int, error) {
+	data := map[string]interface{}{}
+
+	const q = `
+	SELECT
+		COUNT(1)
+	FROM
+		api_keys`
+
+	buf := bytes.NewBufferString(q)
+	s.applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryByID gets the specified api key from the database.
+func (s *Store) QueryByID(ctx context.Context, keyID uuid.UUID) (apikey.Key, error) {
+	data := struct {
+		ID string `db:"api_key_id"`
+	}{
+		ID: keyID.String(),
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		api_keys
+	WHERE
+		api_key_id = :api_key_id`
+
+	var dbKey dbKey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbKey); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return apikey.Key{}, fmt.Errorf("namedquerystruct: %w", apikey.ErrNotFound)
+		}
+		return apikey.Key{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreKey(dbKey), nil
+}
+
+// QueryByHash gets the api key with the specified hash from the database.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (apikey.Key, error) {
+	data := struct {
+		Hash string `db:"key_hash"`
+	}{
+		Hash: hash,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		api_keys
+	WHERE
+		key_hash = :key_hash`
+
+	var dbKey dbKey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbKey); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return apikey.Key{}, fmt.Errorf("namedquerystruct: %w", apikey.ErrNotFound)
+		}
+		return apikey.Key{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreKey(dbKey), nil
+}
@@ -0,0 +1,33 @@
+package apikeydb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/qcbit/service/business/core/apikey"
+)
+
+func (s *Store) applyFilter(filter apikey.QueryFilter, data map[string]interface{}, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ID != nil {
+		data["api_key_id"] = *filter.ID
+		wc = append(wc, "api_key_id = :api_key_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = *filter.UserID
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Name != nil {
+		data["name"] = fmt.Sprintf("%%%s%%", *filter.Name)
+		wc = append(wc, "name LIKE :name")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
@@ -0,0 +1,90 @@
+package apikeydb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/sys/database/pgx/dbarray"
+)
+
+// dbKey represent the structure we need for moving data
+// between the app and the database.
+type dbKey struct {
+	ID           uuid.UUID      `db:"api_key_id"`
+	UserID       uuid.UUID      `db:"user_id"`
+	Name         string         `db:"name"`
+	Prefix       string         `db:"prefix"`
+	Hash         string         `db:"key_hash"`
+	Roles        dbarray.String `db:"roles"`
+	DateExpires  time.Time      `db:"date_expires"`
+	DateLastUsed sql.NullTime   `db:"date_last_used"`
+	DateRevoked  sql.NullTime   `db:"date_revoked"`
+	DateCreated  time.Time      `db:"date_created"`
+}
+
+func toDBKey(key apikey.Key) dbKey {
+	roles := make([]string, len(key.Roles))
+	for i, role := range key.Roles {
+		roles[i] = role.Name()
+	}
+
+	return dbKey{
+		ID:          key.ID,
+		UserID:      key.UserID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Hash:        key.Hash,
+		Roles:       roles,
+		DateExpires: key.DateExpires.UTC(),
+		DateLastUsed: sql.NullTime{
+			Time:  key.DateLastUsed.UTC(),
+			Valid: !key.DateLastUsed.IsZero(),
+		},
+		DateRevoked: sql.NullTime{
+			Time:  key.DateRevoked.UTC(),
+			Valid: !key.DateRevoked.IsZero(),
+		},
+		DateCreated: key.DateCreated.UTC(),
+	}
+}
+
+func toCoreKey(dbKey dbKey) apikey.Key {
+	// Stored roles were validated when they were written.
+	roles := make([]user.Role, len(dbKey.Roles))
+	for i, value := range dbKey.Roles {
+		roles[i].UnmarshalText([]byte(value))
+	}
+
+	key := apikey.Key{
+		ID:          dbKey.ID,
+		UserID:      dbKey.UserID,
+		Name:        dbKey.Name,
+		Prefix:      dbKey.Prefix,
+		Hash:        dbKey.Hash,
+		Roles:       roles,
+		DateExpires: dbKey.DateExpires.In(time.Local),
+		DateCreated: dbKey.DateCreated.In(time.Local),
+	}
+
+	if dbKey.DateLastUsed.Valid {
+		key.DateLastUsed = dbKey.DateLastUsed.Time.In(time.Local)
+	}
+
+	if dbKey.DateRevoked.Valid {
+		key.DateRevoked = dbKey.DateRevoked.Time.In(time.Local)
+	}
+
+	return key
+}
+
+func toCoreKeySlice(dbKeys []dbKey) []apikey.Key {
+	keys := make([]apikey.Key, len(dbKeys))
+	for i, dbKey := range dbKeys {
+		keys[i] = toCoreKey(dbKey)
+	}
+	return keys
+}
@@ -0,0 +1,25 @@
+package apikeydb
+
+import (
+	"fmt"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/data/order"
+)
+
+var orderByFields = map[string]string{
+	apikey.OrderByID:          "api_key_id",
+	apikey.OrderByUserID:      "user_id",
+	apikey.OrderByName:        "name",
+	apikey.OrderByDateExpires: "date_expires",
+	apikey.OrderByDateCreated: "date_created",
+}
+
+func orderByClause(orderBy order.By) (string, error) {
+	by, exists := orderByFields[orderBy.Field]
+	if !exists {
+		return "", fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	return " ORDER BY " + by + " " + orderBy.Direction, nil
+}
@@ -0,0 +1,143 @@
+// Package idempotency provides the core business API for recording the
+// responses of requests made with an idempotency key so retries of the
+// request receive the original response instead of repeating it.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound   = errors.New("idempotency key not found")
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+	ErrMismatch   = errors.New("idempotency key was used with a different request")
+)
+
+// Storer interface declares the behavior this package needs to persists and
+// retrieve data.
+type Storer interface {
+	Reserve(ctx context.Context, rec Record) (Record, bool, error)
+	Complete(ctx context.Context, rec Record) error
+	Release(ctx context.Context, rec Record) error
+	QueryByKey(ctx context.Context, scope string, key string) (Record, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// Config provides the settings for recording requests.
+type Config struct {
+
+	// TTL is how long a response is replayed for retries.
+	TTL time.Duration
+
+	// LockTimeout is how long a request holds the key before a retry can
+	// take over, in case the service stopped before completing it.
+	LockTimeout time.Duration
+}
+
+// Core manages the set of APIs for idempotency key access.
+type Core struct {
+	storer Storer
+	cfg    Config
+}
+
+// NewCore constructs a core for idempotency key access.
+func NewCore(storer Storer, cfg Config) *Core {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = time.Minute
+	}
+
+	return &Core{
+		storer: storer,
+		cfg:    cfg,
+	}
+}
+
+// Begin reserves the key for a request in the scope. When the key was used
+// before, the record of that request is returned instead and the caller
+// should replay it if it is completed. ErrInProgress is returned while the
+// first request is running and ErrMismatch when the key was used for a
+// request with a different fingerprint.
+func (c *Core) Begin(ctx context.Context, scope string, key string, fingerprint string) (Record, error) {
+	now := time.Now()
+
+	rec := Record{
+		Scope:           scope,
+		Key:             key,
+		Fingerprint:     fingerprint,
+		Header:          map[string][]string{},
+		DateLockedUntil: now.Add(c.cfg.LockTimeout),
+		DateExpires:     now.Add(c.cfg.TTL),
+		DateCreated:     now,
+	}
+
+	reserved, ok, err := c.storer.Reserve(ctx, rec)
+	if err != nil {
+		return Record{}, fmt.Errorf("reserve: %w", err)
+	}
+
+	if ok {
+		return reserved, nil
+	}
+
+	existing, err := c.storer.QueryByKey(ctx, scope, key)
+	if err != nil {
+
+		// The record expired between the two calls, the retry can try again.
+		if errors.Is(err, ErrNotFound) {
+			return Record{}, ErrInProgress
+		}
+		return Record{}, fmt.Errorf("query: %w", err)
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return Record{}, ErrMismatch
+	}
+
+	if !existing.Completed() {
+		return Record{}, ErrInProgress
+	}
+
+	return existing, nil
+}
+
+// Complete records the response of the request so it can be replayed.
+// ErrNotFound is returned when the reservation was taken over by a retry
+// after the lock timed out.
+func (c *Core) Complete(ctx context.Context, rec Record, statusCode int, header map[string][]string, body []byte) error {
+	rec.StatusCode = statusCode
+	rec.Header = header
+	rec.Body = body
+	rec.DateCompleted = time.Now()
+
+	if err := c.storer.Complete(ctx, rec); err != nil {
+		return fmt.Errorf("complete: %w", err)
+	}
+
+	return nil
+}
+
+// Release gives up the key of a request that failed so a retry executes the
+// request again.
+func (c *Core) Release(ctx context.Context, rec Record) error {
+	if err := c.storer.Release(ctx, rec); err != nil {
+		return fmt.Errorf("release: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes the records that expired.
+func (c *Core) Purge(ctx context.Context) error {
+	if err := c.storer.DeleteExpired(ctx, time.Now()); err != nil {
+		return fmt.Errorf("deleteexpired: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package idempotency
+
+import (
+	"time"
+)
+
+// Record represents a request made with an idempotency key and, once the
+// request is completed, the response to replay for retries of the request.
+type Record struct {
+	Scope           string
+	Key             string
+	Fingerprint     string
+	StatusCode      int
+	Header          map[string][]string
+	Body            []byte
+	DateCompleted   time.Time
+	DateLockedUntil time.Time
+	DateExpires     time.Time
+	DateCreated     time.Time
+}
+
+// Completed reports if the response of the request has been recorded.
+func (r Record) Completed() bool {
+	return !r.DateCompleted.IsZero()
+}
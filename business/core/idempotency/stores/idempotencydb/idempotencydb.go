@@ -0,0 +1,166 @@
+// Package idempotencydb contains idempotency key related CRUD functionality.
+package idempotencydb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/idempotency"
+	database "github.com/qcbit/service/business/sys/database/pgx"
+)
+
+// Store manages the set of APIs for idempotency key database access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the API for data access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Reserve inserts the record unless the key is already in use. A record that
+// expired, or that was never completed and whose lock timed out, is replaced
+// so the key can be used again. The insert is a single statement so only one
+// of several concurrent requests with the same key reserves it.
+func (s *Store) Reserve(ctx context.Context, rec idempotency.Record) (idempotency.Record, bool, error) {
+	const q = `
+	INSERT INTO idempotency_keys
+		(scope, idempotency_key, fingerprint, status_code, header, body, date_completed, date_locked_until, date_expires, date_created)
+	VALUES
+		(:scope, :idempotency_key, :fingerprint, :status_code, :header, :body, :date_completed, :date_locked_until, :date_expires, :date_created)
+	ON CONFLICT (scope, idempotency_key) DO UPDATE SET
+		fingerprint = EXCLUDED.fingerprint,
+		status_code = EXCLUDED.status_code,
+		header = EXCLUDED.header,
+		body = EXCLUDED.body,
+		date_completed = EXCLUDED.date_completed,
+		date_locked_until = EXCLUDED.date_locked_until,
+		date_expires = EXCLUDED.date_expires,
+		date_created = EXCLUDED.date_created
+	WHERE
+		idempotency_keys.date_expires < EXCLUDED.date_created OR
+		(idempotency_keys.date_completed IS NULL AND
+		idempotency_keys.date_locked_until < EXCLUDED.date_created AND
+		idempotency_keys.fingerprint = EXCLUDED.fingerprint)
+	RETURNING
+		*`
+
+	var dbRec dbRecord
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, toDBRecord(rec), &dbRec); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return idempotency.Record{}, false, nil
+		}
+		return idempotency.Record{}, false, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreRecord(dbRec), true, nil
+}
+
+// Complete records the response of the request. The update only succeeds
+// while the request still holds its reservation.
+func (s *Store) Complete(ctx context.Context, rec idempotency.Record) error {
+	const q = `
+	UPDATE
+		idempotency_keys
+	SET
+		"status_code" = :status_code,
+		"header" = :header,
+		"body" = :body,
+		"date_completed" = :date_completed
+	WHERE
+		scope = :scope AND
+		idempotency_key = :idempotency_key AND
+		date_created = :date_created AND
+		date_completed IS NULL
+	RETURNING
+		*`
+
+	var dbRec dbRecord
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, toDBRecord(rec), &dbRec); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", idempotency.ErrNotFound)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// Release removes the reservation of a request that wasn't completed.
+func (s *Store) Release(ctx context.Context, rec idempotency.Record) error {
+	const q = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		scope = :scope AND
+		idempotency_key = :idempotency_key AND
+		date_created = :date_created AND
+		date_completed IS NULL`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBRecord(rec)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByKey gets the record for the key in the scope from the database.
+func (s *Store) QueryByKey(ctx context.Context, scope string, key string) (idempotency.Record, error) {
+	data := struct {
+		Scope string `db:"scope"`
+		Key   string `db:"idempotency_key"`
+	}{
+		Scope: scope,
+		Key:   key,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		idempotency_keys
+	WHERE
+		scope = :scope AND
+		idempotency_key = :idempotency_key`
+
+	var dbRec dbRecord
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbRec); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return idempotency.Record{}, fmt.Errorf("namedquerystruct: %w", idempotency.ErrNotFound)
+		}
+		return idempotency.Record{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreRecord(dbRec), nil
+}
+
+// DeleteExpired removes the records that expired before the specified time.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) error {
+	data := struct {
+		Now time.Time `db:"now"`
+	}{
+		Now: now.UTC(),
+	}
+
+	const q = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		date_expires < :now`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
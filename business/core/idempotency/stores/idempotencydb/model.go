@@ -0,0 +1,72 @@
+package idempotencydb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/qcbit/service/business/core/idempotency"
+)
+
+// dbRecord represent the structure we need for moving data
+// between the app and the database.
+type dbRecord struct {
+	Scope           string       `db:"scope"`
+	Key             string       `db:"idempotency_key"`
+	Fingerprint     string       `db:"fingerprint"`
+	StatusCode      int          `db:"status_code"`
+	Header          string       `db:"header"`
+	Body            []byte       `db:"body"`
+	DateCompleted   sql.NullTime `db:"date_completed"`
+	DateLockedUntil time.Time    `db:"date_locked_until"`
+	DateExpires     time.Time    `db:"date_expires"`
+	DateCreated     time.Time    `db:"date_created"`
+}
+
+func toDBRecord(rec idempotency.Record) dbRecord {
+	header := "{}"
+	if len(rec.Header) > 0 {
+		if data, err := json.Marshal(rec.Header); err == nil {
+			header = string(data)
+		}
+	}
+
+	return dbRecord{
+		Scope:       rec.Scope,
+		Key:         rec.Key,
+		Fingerprint: rec.Fingerprint,
+		StatusCode:  rec.StatusCode,
+		Header:      header,
+		Body:        rec.Body,
+		DateCompleted: sql.NullTime{
+			Time:  rec.DateCompleted.UTC(),
+			Valid: !rec.DateCompleted.IsZero(),
+		},
+		DateLockedUntil: rec.DateLockedUntil.UTC(),
+		DateExpires:     rec.DateExpires.UTC(),
+		DateCreated:     rec.DateCreated.UTC(),
+	}
+}
+
+func toCoreRecord(dbRec dbRecord) idempotency.Record {
+	header := make(map[string][]string)
+	json.Unmarshal([]byte(dbRec.Header), &header)
+
+	rec := idempotency.Record{
+		Scope:           dbRec.Scope,
+		Key:             dbRec.Key,
+		Fingerprint:     dbRec.Fingerprint,
+		StatusCode:      dbRec.StatusCode,
+		Header:          header,
+		Body:            dbRec.Body,
+		DateLockedUntil: dbRec.DateLockedUntil.In(time.Local),
+		DateExpires:     dbRec.DateExpires.In(time.Local),
+		DateCreated:     dbRec.DateCreated.In(time.Local),
+	}
+
+	if dbRec.DateCompleted.Valid {
+		rec.DateCompleted = dbRec.DateCompleted.Time.In(time.Local)
+	}
+
+	return rec
+}
@@ -0,0 +1,244 @@
+// Package mfa provides the core business API for enrolling users in TOTP
+// multi-factor authentication and verifying their codes.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/foundation/totp"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound         = errors.New("mfa enrollment not found")
+	ErrNotEnrolled      = errors.New("user is not enrolled in mfa")
+	ErrAlreadyEnrolled  = errors.New("user is already enrolled in mfa")
+	ErrInvalidCode      = errors.New("mfa code is not valid")
+	ErrRecoveryNotFound = errors.New("recovery code not found")
+)
+
+// Set of values used to verify codes and construct recovery codes.
+const (
+	skew          = 1
+	recoveryCount = 10
+	recoveryBytes = 5
+)
+
+// Storer interface declares the behavior this package needs to persists and
+// retrieve data.
+type Storer interface {
+	Upsert(ctx context.Context, enr Enrollment) error
+	UpdateLastCounter(ctx context.Context, enr Enrollment) error
+	Delete(ctx context.Context, userID uuid.UUID) error
+	QueryByUserID(ctx context.Context, userID uuid.UUID) (Enrollment, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []RecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, now time.Time) error
+}
+
+// Core manages the set of APIs for mfa access.
+type Core struct {
+	storer Storer
+	issuer string
+}
+
+// NewCore constructs a core for mfa access. The issuer is displayed by
+// authenticator apps next to the account.
+func NewCore(storer Storer, issuer string) *Core {
+	return &Core{
+		storer: storer,
+		issuer: issuer,
+	}
+}
+
+// Enroll generates a new secret for the user and returns it along with the
+// provisioning URI for authenticator apps. The enrollment stays pending until
+// it is confirmed with Confirm.
+func (c *Core) Enroll(ctx context.Context, usr user.User) (Enrollment, string, error) {
+	enr, err := c.storer.QueryByUserID(ctx, usr.ID)
+	switch {
+	case err == nil && enr.Confirmed():
+		return Enrollment{}, "", ErrAlreadyEnrolled
+	case err != nil && !errors.Is(err, ErrNotFound):
+		return Enrollment{}, "", fmt.Errorf("query: userID[%s]: %w", usr.ID, err)
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return Enrollment{}, "", fmt.Errorf("newsecret: %w", err)
+	}
+
+	enr = Enrollment{
+		UserID:      usr.ID,
+		Secret:      secret,
+		DateCreated: time.Now(),
+	}
+
+	if err := c.storer.Upsert(ctx, enr); err != nil {
+		return Enrollment{}, "", fmt.Errorf("upsert: %w", err)
+	}
+
+	return enr, totp.ProvisioningURI(c.issuer, usr.Email.Address, secret), nil
+}
+
+// Confirm completes a pending enrollment with a code generated from the
+// secret. On success a new set of recovery codes is returned. The codes are
+// only returned once and can't be recovered afterwards.
+func (c *Core) Confirm(ctx context.Context, usr user.User, code string) ([]string, error) {
+	enr, err := c.storer.QueryByUserID(ctx, usr.ID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, fmt.Errorf("query: userID[%s]: %w", usr.ID, err)
+	}
+
+	if enr.Confirmed() {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	now := time.Now()
+
+	counter, ok := totp.Validate(enr.Secret, code, now, skew)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	enr.LastCounter = counter
+	enr.DateConfirmed = now
+
+	if err := c.storer.Upsert(ctx, enr); err != nil {
+		return nil, fmt.Errorf("upsert: %w", err)
+	}
+
+	plain, codes, err := newRecoveryCodes(usr.ID, now)
+	if err != nil {
+		return nil, fmt.Errorf("newrecoverycodes: %w", err)
+	}
+
+	if err := c.storer.ReplaceRecoveryCodes(ctx, usr.ID, codes); err != nil {
+		return nil, fmt.Errorf("replacerecoverycodes: %w", err)
+	}
+
+	return plain, nil
+}
+
+// Disable removes the enrollment and recovery codes of the user. A valid
+// code is required so a stolen session can't turn off mfa.
+func (c *Core) Disable(ctx context.Context, usr user.User, code string) error {
+	if _, err := c.Verify(ctx, usr.ID, code); err != nil {
+		return err
+	}
+
+	if err := c.storer.Delete(ctx, usr.ID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Enrolled reports if the user has a confirmed enrollment.
+func (c *Core) Enrolled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	enr, err := c.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return enr.Confirmed(), nil
+}
+
+// Verify checks the code provided by a user with a confirmed enrollment. The
+// code can be a TOTP code or one of the recovery codes. A TOTP code is
+// rejected if it, or a later code, has already been used and a recovery code
+// can only be used once.
+func (c *Core) Verify(ctx context.Context, userID uuid.UUID, code string) (Method, error) {
+	enr, err := c.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", ErrNotEnrolled
+		}
+		return "", fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	if !enr.Confirmed() {
+		return "", ErrNotEnrolled
+	}
+
+	now := time.Now()
+
+	if counter, ok := totp.Validate(enr.Secret, code, now, skew); ok {
+		if counter <= enr.LastCounter {
+			return "", ErrInvalidCode
+		}
+
+		enr.LastCounter = counter
+		if err := c.storer.UpdateLastCounter(ctx, enr); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return "", ErrInvalidCode
+			}
+			return "", fmt.Errorf("updatelastcounter: %w", err)
+		}
+
+		return MethodTOTP, nil
+	}
+
+	if err := c.storer.UseRecoveryCode(ctx, userID, hash(normalize(code)), now); err != nil {
+		if errors.Is(err, ErrRecoveryNotFound) {
+			return "", ErrInvalidCode
+		}
+		return "", fmt.Errorf("userecoverycode: %w", err)
+	}
+
+	return MethodRecovery, nil
+}
+
+// =============================================================================
+
+// newRecoveryCodes generates a set of recovery codes, returning the plain text
+// codes for the user and the hashed codes for storage.
+func newRecoveryCodes(userID uuid.UUID, now time.Time) ([]string, []RecoveryCode, error) {
+	plain := make([]string, recoveryCount)
+	codes := make([]RecoveryCode, recoveryCount)
+
+	for i := range plain {
+		b := make([]byte, recoveryBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		value := hex.EncodeToString(b)
+		plain[i] = value[:5] + "-" + value[5:]
+
+		codes[i] = RecoveryCode{
+			ID:          uuid.New(),
+			UserID:      userID,
+			Hash:        hash(value),
+			DateCreated: now,
+		}
+	}
+
+	return plain, codes, nil
+}
+
+// normalize removes the formatting users may type along with a recovery code.
+func normalize(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// hash returns the value stored for a recovery code.
+func hash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
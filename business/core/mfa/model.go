@@ -0,0 +1,41 @@
+package mfa
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Enrollment represents the TOTP secret enrolled for a user. An enrollment is
+// pending until the user proves they can generate codes for the secret.
+type Enrollment struct {
+	UserID        uuid.UUID
+	Secret        []byte
+	LastCounter   int64
+	DateConfirmed time.Time
+	DateCreated   time.Time
+}
+
+// Confirmed reports if the user has completed the enrollment.
+func (e Enrollment) Confirmed() bool {
+	return !e.DateConfirmed.IsZero()
+}
+
+// RecoveryCode represents a one-time code that can be used in place of a TOTP
+// code. Only the hash of the code is stored.
+type RecoveryCode struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Hash        string
+	DateUsed    time.Time
+	DateCreated time.Time
+}
+
+// Method describes how a user proved their second factor.
+type Method string
+
+// Set of methods a second factor can be proven with.
+const (
+	MethodTOTP     Method = "totp"
+	MethodRecovery Method = "recovery"
+)
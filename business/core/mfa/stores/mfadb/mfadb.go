@@ -0,0 +1,212 @@
+// Package mfadb contains mfa related CRUD functionality.
+package mfadb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/mfa"
+	database "github.com/qcbit/service/business/sys/database/pgx"
+)
+
+// Store manages the set of APIs for mfa database access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the API for data access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Upsert inserts or replaces the enrollment for a user.
+func (s *Store) Upsert(ctx context.Context, enr mfa.Enrollment) error {
+	const q = `
+	INSERT INTO user_mfa
+		(user_id, secret, last_counter, date_confirmed, date_created)
+	VALUES
+		(:user_id, :secret, :last_counter, :date_confirmed, :date_created)
+	ON CONFLICT (user_id) DO UPDATE SET
+		"secret" = EXCLUDED.secret,
+		"last_counter" = EXCLUDED.last_counter,
+		"date_confirmed" = EXCLUDED.date_confirmed,
+		"date_created" = EXCLUDED.date_created`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBEnrollment(enr)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateLastCounter records the time step of the last code used. The update
+// only succeeds when the time step is later than the recorded one, so a code
+// can't be used twice by concurrent requests.
+func (s *Store) UpdateLastCounter(ctx context.Context, enr mfa.Enrollment) error {
+	const q = `
+	UPDATE
+		user_mfa
+	SET
+		"last_counter" = :last_counter
+	WHERE
+		user_id = :user_id AND
+		last_counter < :last_counter
+	RETURNING
+		*`
+
+	var dbEnr dbEnrollment
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, toDBEnrollment(enr), &dbEnr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", mfa.ErrNotFound)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the enrollment and recovery codes for a user.
+func (s *Store) Delete(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		const qCodes = `
+		DELETE FROM
+			mfa_recovery_codes
+		WHERE
+			user_id = :user_id`
+
+		if err := database.NamedExecContext(ctx, s.log, tx, qCodes, data); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+
+		const qMFA = `
+		DELETE FROM
+			user_mfa
+		WHERE
+			user_id = :user_id`
+
+		if err := database.NamedExecContext(ctx, s.log, tx, qMFA, data); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+
+		return nil
+	}
+
+	return database.WithinTran(ctx, s.log, s.db, f)
+}
+
+// QueryByUserID gets the enrollment for the specified user from the database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) (mfa.Enrollment, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		user_mfa
+	WHERE
+		user_id = :user_id`
+
+	var dbEnr dbEnrollment
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbEnr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return mfa.Enrollment{}, fmt.Errorf("namedquerystruct: %w", mfa.ErrNotFound)
+		}
+		return mfa.Enrollment{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreEnrollment(dbEnr), nil
+}
+
+// ReplaceRecoveryCodes removes the existing recovery codes for a user and
+// inserts the specified codes.
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []mfa.RecoveryCode) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		const qDelete = `
+		DELETE FROM
+			mfa_recovery_codes
+		WHERE
+			user_id = :user_id`
+
+		if err := database.NamedExecContext(ctx, s.log, tx, qDelete, data); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+
+		const qInsert = `
+		INSERT INTO mfa_recovery_codes
+			(recovery_code_id, user_id, code_hash, date_used, date_created)
+		VALUES
+			(:recovery_code_id, :user_id, :code_hash, :date_used, :date_created)`
+
+		for _, code := range codes {
+			if err := database.NamedExecContext(ctx, s.log, tx, qInsert, toDBRecoveryCode(code)); err != nil {
+				return fmt.Errorf("namedexeccontext: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	return database.WithinTran(ctx, s.log, s.db, f)
+}
+
+// UseRecoveryCode marks the unused recovery code with the specified hash as
+// used.
+func (s *Store) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, now time.Time) error {
+	data := struct {
+		UserID   string    `db:"user_id"`
+		Hash     string    `db:"code_hash"`
+		DateUsed time.Time `db:"date_used"`
+	}{
+		UserID:   userID.String(),
+		Hash:     hash,
+		DateUsed: now.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		mfa_recovery_codes
+	SET
+		"date_used" = :date_used
+	WHERE
+		user_id = :user_id AND
+		code_hash = :code_hash AND
+		date_used IS NULL
+	RETURNING
+		*`
+
+	var dbCode dbRecoveryCode
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbCode); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", mfa.ErrRecoveryNotFound)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
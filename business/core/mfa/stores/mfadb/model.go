@@ -0,0 +1,71 @@
+package mfadb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/mfa"
+)
+
+// dbEnrollment represent the structure we need for moving data
+// between the app and the database.
+type dbEnrollment struct {
+	UserID        uuid.UUID    `db:"user_id"`
+	Secret        []byte       `db:"secret"`
+	LastCounter   int64        `db:"last_counter"`
+	DateConfirmed sql.NullTime `db:"date_confirmed"`
+	DateCreated   time.Time    `db:"date_created"`
+}
+
+func toDBEnrollment(enr mfa.Enrollment) dbEnrollment {
+	return dbEnrollment{
+		UserID:      enr.UserID,
+		Secret:      enr.Secret,
+		LastCounter: enr.LastCounter,
+		DateConfirmed: sql.NullTime{
+			Time:  enr.DateConfirmed.UTC(),
+			Valid: !enr.DateConfirmed.IsZero(),
+		},
+		DateCreated: enr.DateCreated.UTC(),
+	}
+}
+
+func toCoreEnrollment(dbEnr dbEnrollment) mfa.Enrollment {
+	enr := mfa.Enrollment{
+		UserID:      dbEnr.UserID,
+		Secret:      dbEnr.Secret,
+		LastCounter: dbEnr.LastCounter,
+		DateCreated: dbEnr.DateCreated.In(time.Local),
+	}
+
+	if dbEnr.DateConfirmed.Valid {
+		enr.DateConfirmed = dbEnr.DateConfirmed.Time.In(time.Local)
+	}
+
+	return enr
+}
+
+// =============================================================================
+
+type dbRecoveryCode struct {
+	ID          uuid.UUID    `db:"recovery_code_id"`
+	UserID      uuid.UUID    `db:"user_id"`
+	Hash        string       `db:"code_hash"`
+	DateUsed    sql.NullTime `db:"date_used"`
+	DateCreated time.Time    `db:"date_created"`
+}
+
+func toDBRecoveryCode(rc mfa.RecoveryCode) dbRecoveryCode {
+	return dbRecoveryCode{
+		ID:     rc.ID,
+		UserID: rc.UserID,
+		Hash:   rc.Hash,
+		DateUsed: sql.NullTime{
+			Time:  rc.DateUsed.UTC(),
+			Valid: !rc.DateUsed.IsZero(),
+		},
+		DateCreated: rc.DateCreated.UTC(),
+	}
+}
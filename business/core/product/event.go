@@ -0,0 +1,46 @@
+package product
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/sys/events"
+)
+
+// EventAggregate is the aggregate of the events about products.
+const EventAggregate = "product"
+
+// Set of event types published when products change.
+const (
+	EventCreated = "product.created"
+	EventUpdated = "product.updated"
+	EventDeleted = "product.deleted"
+)
+
+// EventData is the payload of the events about products. It holds the state
+// of the product after the change.
+type EventData struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Cost        float64   `json:"cost"`
+	Quantity    int       `json:"quantity"`
+	UserID      uuid.UUID `json:"userID"`
+	DateCreated time.Time `json:"dateCreated"`
+	DateUpdated time.Time `json:"dateUpdated"`
+}
+
+// newEvent constructs an event of the specified type about the product.
+func newEvent(typ string, prd Product) (events.Event, error) {
+	data := EventData{
+		ID:          prd.ID,
+		Name:        prd.Name,
+		Cost:        prd.Cost,
+		Quantity:    prd.Quantity,
+		UserID:      prd.UserID,
+		DateCreated: prd.DateCreated,
+		DateUpdated: prd.DateUpdated,
+	}
+
+	return events.New(EventAggregate, prd.ID, typ, data)
+}
@@ -1,6 +1,7 @@
 package product
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -17,8 +18,8 @@ type QueryFilter struct {
 }
 
 // Validate checks the data in the model is considered clean.
-func (qf *QueryFilter) Validate() error {
-	if err := validate.Check(qf); err != nil {
+func (qf *QueryFilter) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, qf); err != nil {
 		return fmt.Errorf("validate: %w", err)
 	}
 	return nil
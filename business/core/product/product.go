@@ -14,6 +14,7 @@ import (
 
 	"github.com/qcbit/service/business/core/user"
 	"github.com/qcbit/service/business/data/order"
+	"github.com/qcbit/service/business/sys/events"
 )
 
 // Set of error variables for CRUD operations.
@@ -22,11 +23,12 @@ var (
 )
 
 // Storer interface declares the behavior this package needs to perists and
-// retrieve data.
+// retrieve data. The events passed with a change must be written to the
+// outbox in the same transaction as the change.
 type Storer interface {
-	Create(ctx context.Context, prd Product) error
-	Update(ctx context.Context, prd Product) error
-	Delete(ctx context.Context, prd Product) error
+	Create(ctx context.Context, prd Product, evs ...events.Event) error
+	Update(ctx context.Context, prd Product, evs ...events.Event) error
+	Delete(ctx context.Context, prd Product, evs ...events.Event) error
 	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Product, error)
 	Count(ctx context.Context, filter QueryFilter) (int, error)
 	QueryByID(ctx context.Context, productID uuid.UUID) (Product, error)
@@ -66,7 +68,12 @@ func (c *Core) Create(ctx context.Context, np NewProduct) (Product, error) {
 		DateUpdated: now,
 	}
 
-	if err := c.storer.Create(ctx, prd); err != nil {
+	ev, err := newEvent(EventCreated, prd)
+	if err != nil {
+		return Product{}, fmt.Errorf("newevent: %w", err)
+	}
+
+	if err := c.storer.Create(ctx, prd, ev); err != nil {
 		return Product{}, fmt.Errorf("create: %w", err)
 	}
 
@@ -87,7 +94,12 @@ func (c *Core) Update(ctx context.Context, prd Product, up UpdateProduct) (Produ
 	}
 	prd.DateUpdated = time.Now()
 
-	if err := c.storer.Update(ctx, prd); err != nil {
+	ev, err := newEvent(EventUpdated, prd)
+	if err != nil {
+		return Product{}, fmt.Errorf("newevent: %w", err)
+	}
+
+	if err := c.storer.Update(ctx, prd, ev); err != nil {
 		return Product{}, fmt.Errorf("update: %w", err)
 	}
 
@@ -96,7 +108,12 @@ func (c *Core) Update(ctx context.Context, prd Product, up UpdateProduct) (Produ
 
 // Delete removes the product identified by a given ID.
 func (c *Core) Delete(ctx context.Context, prd Product) error {
-	if err := c.storer.Delete(ctx, prd); err != nil {
+	ev, err := newEvent(EventDeleted, prd)
+	if err != nil {
+		return fmt.Errorf("newevent: %w", err)
+	}
+
+	if err := c.storer.Delete(ctx, prd, ev); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
 
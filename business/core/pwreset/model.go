@@ -0,0 +1,23 @@
+package pwreset
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reset represents a request by a user to reset their password. Only the
+// hash of the token delivered to the user is stored.
+type Reset struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Hash        string
+	DateExpires time.Time
+	DateUsed    time.Time
+	DateCreated time.Time
+}
+
+// Used reports if the reset has already been used.
+func (r Reset) Used() bool {
+	return !r.DateUsed.IsZero()
+}
@@ -0,0 +1,171 @@
+// Package pwreset provides the core business API for resetting a forgotten
+// password through a single-use token delivered to the user.
+package pwreset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/sys/events"
+	"github.com/qcbit/service/business/sys/notify"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound     = errors.New("password reset not found")
+	ErrInvalidToken = errors.New("password reset token is not valid")
+)
+
+// tokenBytes is the amount of entropy carried by a reset token.
+const tokenBytes = 32
+
+// Storer interface declares the behavior this package needs to persists and
+// retrieve data.
+type Storer interface {
+	Create(ctx context.Context, rst Reset) error
+	Consume(ctx context.Context, rst Reset, usr user.User, evs ...events.Event) error
+	QueryByHash(ctx context.Context, hash string) (Reset, error)
+}
+
+// Core manages the set of APIs for password reset access.
+type Core struct {
+	usrCore  *user.Core
+	storer   Storer
+	notifier notify.Notifier
+	ttl      time.Duration
+}
+
+// NewCore constructs a core for password reset access. Tokens are valid for
+// the specified ttl.
+func NewCore(usrCore *user.Core, storer Storer, notifier notify.Notifier, ttl time.Duration) *Core {
+	return &Core{
+		usrCore:  usrCore,
+		storer:   storer,
+		notifier: notifier,
+		ttl:      ttl,
+	}
+}
+
+// Request issues a reset token for the user with the specified email and
+// delivers it through the notifier. To not disclose which emails are
+// registered, no error is returned when the user doesn't exist or is
+// disabled.
+func (c *Core) Request(ctx context.Context, email mail.Address) error {
+	usr, err := c.usrCore.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("query: email[%s]: %w", email, err)
+	}
+
+	if !usr.Enabled {
+		return nil
+	}
+
+	token, err := generate()
+	if err != nil {
+		return fmt.Errorf("generate: %w", err)
+	}
+
+	now := time.Now()
+
+	rst := Reset{
+		ID:          uuid.New(),
+		UserID:      usr.ID,
+		Hash:        hash(token),
+		DateExpires: now.Add(c.ttl),
+		DateCreated: now,
+	}
+
+	if err := c.storer.Create(ctx, rst); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	msg := notify.Message{
+		To:      mail.Address{Name: usr.Name, Address: usr.Email.Address},
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Use this token to reset your password. It expires at %s.\n\n%s", rst.DateExpires.UTC().Format(time.RFC3339), token),
+	}
+
+	if err := c.notifier.Notify(ctx, msg); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+
+	return nil
+}
+
+// Confirm validates the token and replaces the password of the user it was
+// issued for. The token can only be used once, the other tokens of the user
+// are invalidated and every session of the user is revoked.
+func (c *Core) Confirm(ctx context.Context, token string, password string) (user.User, error) {
+	rst, err := c.storer.QueryByHash(ctx, hash(token))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return user.User{}, ErrInvalidToken
+		}
+		return user.User{}, fmt.Errorf("query: %w", err)
+	}
+
+	now := time.Now()
+
+	if rst.Used() || !rst.DateExpires.After(now) {
+		return user.User{}, ErrInvalidToken
+	}
+
+	usr, err := c.usrCore.QueryByID(ctx, rst.UserID)
+	if err != nil {
+		return user.User{}, fmt.Errorf("query: userID[%s]: %w", rst.UserID, err)
+	}
+
+	if !usr.Enabled {
+		return user.User{}, ErrInvalidToken
+	}
+
+	// The password is validated before the token is consumed so a rejected
+	// password doesn't force the user to request a new token.
+	usr, ev, err := c.usrCore.PasswordReset(usr, password)
+	if err != nil {
+		return user.User{}, fmt.Errorf("passwordreset: %w", err)
+	}
+
+	// The token is used up in the same transaction that replaces the
+	// password, and takes every other token issued to the user with it.
+	rst.DateUsed = now
+	if err := c.storer.Consume(ctx, rst, usr, ev); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return user.User{}, ErrInvalidToken
+		}
+		return user.User{}, fmt.Errorf("consume: %w", err)
+	}
+
+	return usr, nil
+}
+
+// =============================================================================
+
+// generate constructs a new random token.
+func generate() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hash returns the value stored for a token.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,126 @@
+package pwreset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/sys/events"
+)
+
+func Test_ConfirmRejectsToken(t *testing.T) {
+	const token = "reset-token"
+	now := time.Now()
+
+	tt := []struct {
+		name string
+		rst  Reset
+	}{
+		{"unknown", Reset{}},
+		{"used", Reset{Hash: hash(token), DateExpires: now.Add(time.Hour), DateUsed: now.Add(-time.Minute)}},
+		{"expired", Reset{Hash: hash(token), DateExpires: now.Add(-time.Second)}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			tst.rst.ID = uuid.New()
+			tst.rst.UserID = uuid.New()
+
+			// The user core isn't reached for a rejected token.
+			core := NewCore(nil, &resets{tst.rst}, nil, time.Hour)
+
+			_, err := core.Confirm(context.Background(), token, "N3w-Passw0rd!")
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Should reject the token: got %v", err)
+			}
+		})
+	}
+}
+
+func Test_ConfirmSingleUse(t *testing.T) {
+	const token = "reset-token"
+
+	usr := user.User{ID: uuid.New(), Name: "Bill Kennedy", Enabled: true}
+	usrCore := user.NewCore(users{usr: usr}, user.Config{BcryptCost: bcrypt.MinCost})
+
+	rst := Reset{ID: uuid.New(), UserID: usr.ID, Hash: hash(token), DateExpires: time.Now().Add(time.Hour)}
+	other := Reset{ID: uuid.New(), UserID: usr.ID, Hash: hash("other-token"), DateExpires: time.Now().Add(time.Hour)}
+	core := NewCore(usrCore, &resets{rst, other}, nil, time.Hour)
+
+	got, err := core.Confirm(context.Background(), token, "N3w-Passw0rd!")
+	if err != nil {
+		t.Fatalf("Should be able to reset the password: %s", err)
+	}
+
+	if got.DateSessionsRevoked.IsZero() {
+		t.Errorf("Should revoke the sessions of the user.")
+	}
+
+	if _, err := core.Confirm(context.Background(), token, "An0ther-Passw0rd!"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Should reject a token used once: got %v", err)
+	}
+
+	if _, err := core.Confirm(context.Background(), "other-token", "An0ther-Passw0rd!"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Should reject the other tokens of the user once the password is reset: got %v", err)
+	}
+}
+
+// =============================================================================
+
+// resets is an in-memory Storer.
+type resets []Reset
+
+func (r *resets) Create(ctx context.Context, rst Reset) error {
+	return errors.New("not supported")
+}
+
+func (r *resets) Consume(ctx context.Context, rst Reset, usr user.User, evs ...events.Event) error {
+	i := -1
+	for j := range *r {
+		if (*r)[j].ID == rst.ID && !(*r)[j].Used() {
+			i = j
+		}
+	}
+
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	for j := range *r {
+		if (*r)[j].UserID == usr.ID && !(*r)[j].Used() {
+			(*r)[j].DateUsed = rst.DateUsed
+		}
+	}
+
+	return nil
+}
+
+func (r *resets) QueryByHash(ctx context.Context, hash string) (Reset, error) {
+	for _, rst := range *r {
+		if rst.Hash == hash {
+			return rst, nil
+		}
+	}
+
+	return Reset{}, ErrNotFound
+}
+
+// users is a user.Storer holding a single user. Only the methods used to
+// reset a password are implemented.
+type users struct {
+	user.Storer
+	usr user.User
+}
+
+func (u users) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	if userID != u.usr.ID {
+		return user.User{}, user.ErrNotFound
+	}
+
+	return u.usr, nil
+}
@@ -0,0 +1,51 @@
+package pwresetdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/core/pwreset"
+)
+
+// dbReset represent the structure we need for moving data
+// between the app and the database.
+type dbReset struct {
+	ID          uuid.UUID    `db:"reset_id"`
+	UserID      uuid.UUID    `db:"user_id"`
+	Hash        string       `db:"token_hash"`
+	DateExpires time.Time    `db:"date_expires"`
+	DateUsed    sql.NullTime `db:"date_used"`
+	DateCreated time.Time    `db:"date_created"`
+}
+
+func toDBReset(rst pwreset.Reset) dbReset {
+	return dbReset{
+		ID:          rst.ID,
+		UserID:      rst.UserID,
+		Hash:        rst.Hash,
+		DateExpires: rst.DateExpires.UTC(),
+		DateUsed: sql.NullTime{
+			Time:  rst.DateUsed.UTC(),
+			Valid: !rst.DateUsed.IsZero(),
+		},
+		DateCreated: rst.DateCreated.UTC(),
+	}
+}
+
+func toCoreReset(dbRst dbReset) pwreset.Reset {
+	rst := pwreset.Reset{
+		ID:          dbRst.ID,
+		UserID:      dbRst.UserID,
+		Hash:        dbRst.Hash,
+		DateExpires: dbRst.DateExpires.In(time.Local),
+		DateCreated: dbRst.DateCreated.In(time.Local),
+	}
+
+	if dbRst.DateUsed.Valid {
+		rst.DateUsed = dbRst.DateUsed.Time.In(time.Local)
+	}
+
+	return rst
+}
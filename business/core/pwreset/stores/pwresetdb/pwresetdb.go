@@ -0,0 +1,127 @@
+// Package pwresetdb contains password reset related CRUD functionality.
+package pwresetdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/pwreset"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/core/user/stores/userdb"
+	database "github.com/qcbit/service/business/sys/database/pgx"
+	"github.com/qcbit/service/business/sys/events"
+	"github.com/qcbit/service/business/sys/events/stores/outboxdb"
+)
+
+// Store manages the set of APIs for password reset database access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the API for data access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new password reset into the database.
+func (s *Store) Create(ctx context.Context, rst pwreset.Reset) error {
+	const q = `
+	INSERT INTO password_resets
+		(reset_id, user_id, token_hash, date_expires, date_used, date_created)
+	VALUES
+		(:reset_id, :user_id, :token_hash, :date_expires, :date_used, :date_created)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBReset(rst)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Consume marks the password reset used, invalidates the other unused resets
+// of the user and replaces the password of the user along with the events of
+// the change, all in one transaction. Only an unused reset can be consumed, so
+// a token can't be used twice by concurrent requests.
+func (s *Store) Consume(ctx context.Context, rst pwreset.Reset, usr user.User, evs ...events.Event) error {
+	f := func(tx *sqlx.Tx) error {
+		const qUse = `
+		UPDATE
+			password_resets
+		SET
+			"date_used" = :date_used
+		WHERE
+			reset_id = :reset_id AND
+			date_used IS NULL
+		RETURNING
+			*`
+
+		var dbRst dbReset
+		if err := database.NamedQueryStruct(ctx, s.log, tx, qUse, toDBReset(rst), &dbRst); err != nil {
+			if errors.Is(err, database.ErrDBNotFound) {
+				return fmt.Errorf("namedquerystruct: %w", pwreset.ErrNotFound)
+			}
+			return fmt.Errorf("namedquerystruct: %w", err)
+		}
+
+		const qInvalidate = `
+		UPDATE
+			password_resets
+		SET
+			"date_used" = :date_used
+		WHERE
+			user_id = :user_id AND
+			date_used IS NULL`
+
+		if err := database.NamedExecContext(ctx, s.log, tx, qInvalidate, toDBReset(rst)); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+
+		if err := userdb.UpdatePassword(ctx, s.log, tx, usr); err != nil {
+			return fmt.Errorf("update password: %w", err)
+		}
+
+		if err := outboxdb.Insert(ctx, s.log, tx, evs); err != nil {
+			return fmt.Errorf("insert events: %w", err)
+		}
+
+		return nil
+	}
+
+	return database.WithinTran(ctx, s.log, s.db, f)
+}
+
+// QueryByHash gets the password reset with the specified token hash from the
+// database.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (pwreset.Reset, error) {
+	data := struct {
+		Hash string `db:"token_hash"`
+	}{
+		Hash: hash,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		password_resets
+	WHERE
+		token_hash = :token_hash`
+
+	var dbRst dbReset
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbRst); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return pwreset.Reset{}, fmt.Errorf("namedquerystruct: %w", pwreset.ErrNotFound)
+		}
+		return pwreset.Reset{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreReset(dbRst), nil
+}
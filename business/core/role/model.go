@@ -0,0 +1,27 @@
+package role
+
+import (
+	"time"
+)
+
+// Role represents a named role and the set of permissions it grants.
+type Role struct {
+	Name        string
+	Description string
+	Permissions []string
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// NewRole contains information needed to create a new role.
+type NewRole struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// UpdateRole contains information needed to update a role.
+type UpdateRole struct {
+	Description *string
+	Permissions []string
+}
@@ -0,0 +1,13 @@
+package role
+
+// Set of permissions the service checks for. Roles can be granted any
+// permission name, these are the ones routes are protected with.
+const (
+	PermissionUsersRead    = "users:read"
+	PermissionUsersWrite   = "users:write"
+	PermissionRolesRead    = "roles:read"
+	PermissionRolesWrite   = "roles:write"
+	PermissionRolesAssign  = "roles:assign"
+	PermissionAPIKeysRead  = "apikeys:read"
+	PermissionAPIKeysWrite = "apikeys:write"
+)
@@ -0,0 +1,273 @@
+// Package role provides the core business API for managing the roles users
+// can be assigned and the permissions each role grants.
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/user"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound          = errors.New("role not found")
+	ErrUniqueName        = errors.New("role name is not unique")
+	ErrInvalidName       = errors.New("role name must be upper case letters, digits or underscores")
+	ErrInvalidPermission = errors.New("permission must be lower case words separated by colons")
+	ErrInUse             = errors.New("role is assigned and can't be deleted")
+	ErrBuiltIn           = errors.New("built-in roles can't be deleted")
+)
+
+var (
+	nameRegEx       = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	permissionRegEx = regexp.MustCompile(`^[a-z][a-z0-9_]*(:[a-z][a-z0-9_]*)*$`)
+)
+
+// cacheTTL is how long the set of roles is cached before it is reloaded from
+// the store. Changes made through another instance of the service become
+// visible within this window.
+const cacheTTL = 5 * time.Second
+
+// Storer interface declares the behavior this package needs to persists and
+// retrieve data.
+type Storer interface {
+	Create(ctx context.Context, rol Role) error
+	Update(ctx context.Context, rol Role) error
+	Delete(ctx context.Context, rol Role) error
+	Query(ctx context.Context) ([]Role, error)
+	QueryByName(ctx context.Context, name string) (Role, error)
+	CountAssigned(ctx context.Context, name string) (int, error)
+}
+
+// Core manages the set of APIs for role access.
+type Core struct {
+	log    *zap.SugaredLogger
+	storer Storer
+
+	mu     sync.RWMutex
+	cache  map[string]Role
+	loaded time.Time
+}
+
+// NewCore constructs a core for role api access.
+func NewCore(log *zap.SugaredLogger, storer Storer) *Core {
+	return &Core{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// Create adds a new role to the database.
+func (c *Core) Create(ctx context.Context, nr NewRole) (Role, error) {
+	if !nameRegEx.MatchString(nr.Name) {
+		return Role{}, ErrInvalidName
+	}
+
+	perms, err := normalize(nr.Permissions)
+	if err != nil {
+		return Role{}, err
+	}
+
+	now := time.Now()
+
+	rol := Role{
+		Name:        nr.Name,
+		Description: nr.Description,
+		Permissions: perms,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := c.storer.Create(ctx, rol); err != nil {
+		return Role{}, fmt.Errorf("create: %w", err)
+	}
+
+	c.invalidate()
+
+	return rol, nil
+}
+
+// Update replaces a role record in the database.
+func (c *Core) Update(ctx context.Context, rol Role, ur UpdateRole) (Role, error) {
+	if ur.Description != nil {
+		rol.Description = *ur.Description
+	}
+	if ur.Permissions != nil {
+		perms, err := normalize(ur.Permissions)
+		if err != nil {
+			return Role{}, err
+		}
+		rol.Permissions = perms
+	}
+	rol.DateUpdated = time.Now()
+
+	if err := c.storer.Update(ctx, rol); err != nil {
+		return Role{}, fmt.Errorf("update: %w", err)
+	}
+
+	c.invalidate()
+
+	return rol, nil
+}
+
+// Delete removes a role from the database. Built-in roles and roles that are
+// still assigned to a user or api key can't be deleted.
+func (c *Core) Delete(ctx context.Context, rol Role) error {
+	if rol.Name == user.RoleAdmin.Name() || rol.Name == user.RoleUser.Name() {
+		return ErrBuiltIn
+	}
+
+	n, err := c.storer.CountAssigned(ctx, rol.Name)
+	if err != nil {
+		return fmt.Errorf("countassigned: %w", err)
+	}
+
+	if n > 0 {
+		return ErrInUse
+	}
+
+	if err := c.storer.Delete(ctx, rol); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	c.invalidate()
+
+	return nil
+}
+
+// Query retrieves the list of roles from the database.
+func (c *Core) Query(ctx context.Context) ([]Role, error) {
+	roles, err := c.storer.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return roles, nil
+}
+
+// QueryByName gets the specified role from the database.
+func (c *Core) QueryByName(ctx context.Context, name string) (Role, error) {
+	rol, err := c.storer.QueryByName(ctx, name)
+	if err != nil {
+		return Role{}, fmt.Errorf("query: name[%s]: %w", name, err)
+	}
+
+	return rol, nil
+}
+
+// =============================================================================
+
+// Permissions returns the union of the permissions granted by the specified
+// roles. Unknown roles grant no permissions.
+func (c *Core) Permissions(ctx context.Context, roles []user.Role) ([]string, error) {
+	cache, err := c.roles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("roles: %w", err)
+	}
+
+	set := make(map[string]struct{})
+	for _, r := range roles {
+		for _, perm := range cache[r.Name()].Permissions {
+			set[perm] = struct{}{}
+		}
+	}
+
+	perms := make([]string, 0, len(set))
+	for perm := range set {
+		perms = append(perms, perm)
+	}
+	sort.Strings(perms)
+
+	return perms, nil
+}
+
+// Exists reports if a role with the specified name exists. It implements the
+// user.RoleLookup interface so assigned roles are validated against the
+// store.
+func (c *Core) Exists(ctx context.Context, name string) (bool, error) {
+	cache, err := c.roles(ctx)
+	if err != nil {
+		return false, fmt.Errorf("roles: %w", err)
+	}
+
+	if _, exists := cache[name]; exists {
+		return true, nil
+	}
+
+	// The role may have been created through another instance of the
+	// service since the cache was loaded.
+	if _, err := c.storer.QueryByName(ctx, name); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("query: name[%s]: %w", name, err)
+	}
+
+	c.invalidate()
+
+	return true, nil
+}
+
+// roles returns the cached set of roles, reloading them from the store when
+// the cache has expired.
+func (c *Core) roles(ctx context.Context) (map[string]Role, error) {
+	c.mu.RLock()
+	cache, loaded := c.cache, c.loaded
+	c.mu.RUnlock()
+
+	if cache != nil && time.Since(loaded) < cacheTTL {
+		return cache, nil
+	}
+
+	roles, err := c.storer.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	cache = make(map[string]Role, len(roles))
+	for _, rol := range roles {
+		cache[rol.Name] = rol
+	}
+
+	c.mu.Lock()
+	c.cache = cache
+	c.loaded = time.Now()
+	c.mu.Unlock()
+
+	return cache, nil
+}
+
+// invalidate forces the next access to reload the set of roles.
+func (c *Core) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache = nil
+}
+
+// normalize validates, sorts and removes duplicates from a set of permissions.
+func normalize(perms []string) ([]string, error) {
+	set := make(map[string]struct{}, len(perms))
+	for _, perm := range perms {
+		if !permissionRegEx.MatchString(perm) {
+			return nil, fmt.Errorf("%q: %w", perm, ErrInvalidPermission)
+		}
+		set[perm] = struct{}{}
+	}
+
+	out := make([]string, 0, len(set))
+	for perm := range set {
+		out = append(out, perm)
+	}
+	sort.Strings(out)
+
+	return out, nil
+}
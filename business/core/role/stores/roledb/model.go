@@ -0,0 +1,55 @@
+package roledb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/qcbit/service/business/core/role"
+	"github.com/qcbit/service/business/sys/database/pgx/dbarray"
+)
+
+// dbRole represent the structure we need for moving data
+// between the app and the database.
+type dbRole struct {
+	Name        string         `db:"name"`
+	Description sql.NullString `db:"description"`
+	Permissions dbarray.String `db:"permissions"`
+	DateCreated time.Time      `db:"date_created"`
+	DateUpdated time.Time      `db:"date_updated"`
+}
+
+func toDBRole(rol role.Role) dbRole {
+	perms := rol.Permissions
+	if perms == nil {
+		perms = []string{}
+	}
+
+	return dbRole{
+		Name: rol.Name,
+		Description: sql.NullString{
+			String: rol.Description,
+			Valid:  rol.Description != "",
+		},
+		Permissions: perms,
+		DateCreated: rol.DateCreated.UTC(),
+		DateUpdated: rol.DateUpdated.UTC(),
+	}
+}
+
+func toCoreRole(dbRol dbRole) role.Role {
+	return role.Role{
+		Name:        dbRol.Name,
+		Description: dbRol.Description.String,
+		Permissions: dbRol.Permissions,
+		DateCreated: dbRol.DateCreated.In(time.Local),
+		DateUpdated: dbRol.DateUpdated.In(time.Local),
+	}
+}
+
+func toCoreRoleSlice(dbRoles []dbRole) []role.Role {
+	roles := make([]role.Role, len(dbRoles))
+	for i, dbRol := range dbRoles {
+		roles[i] = toCoreRole(dbRol)
+	}
+	return roles
+}
@@ -0,0 +1,155 @@
+// Package roledb contains role related CRUD functionality.
+package roledb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/role"
+	database "github.com/qcbit/service/business/sys/database/pgx"
+)
+
+// Store manages the set of APIs for role database access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the API for data access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new role into the database.
+func (s *Store) Create(ctx context.Context, rol role.Role) error {
+	const q = `
+	INSERT INTO roles
+		(name, description, permissions, date_created, date_updated)
+	VALUES
+		(:name, :description, :permissions, :date_created, :date_updated)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBRole(rol)); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", role.ErrUniqueName)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a role document in the database.
+func (s *Store) Update(ctx context.Context, rol role.Role) error {
+	const q = `
+	UPDATE
+		roles
+	SET
+		"description" = :description,
+		"permissions" = :permissions,
+		"date_updated" = :date_updated
+	WHERE
+		name = :name`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBRole(rol)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a role from the database.
+func (s *Store) Delete(ctx context.Context, rol role.Role) error {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: rol.Name,
+	}
+
+	const q = `
+	DELETE FROM
+		roles
+	WHERE
+		name = :name`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the list of roles from the database.
+func (s *Store) Query(ctx context.Context) ([]role.Role, error) {
+	const q = `
+	SELECT
+		*
+	FROM
+		roles
+	ORDER BY
+		name`
+
+	var dbRoles []dbRole
+	if err := database.QuerySlice(ctx, s.log, s.db, q, &dbRoles); err != nil {
+		return nil, fmt.Errorf("queryslice: %w", err)
+	}
+
+	return toCoreRoleSlice(dbRoles), nil
+}
+
+// QueryByName gets the specified role from the database.
+func (s *Store) QueryByName(ctx context.Context, name string) (role.Role, error) {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		roles
+	WHERE
+		name = :name`
+
+	var dbRol dbRole
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbRol); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return role.Role{}, fmt.Errorf("namedquerystruct: %w", role.ErrNotFound)
+		}
+		return role.Role{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreRole(dbRol), nil
+}
+
+// CountAssigned returns the number of users and api keys the role is
+// assigned to.
+func (s *Store) CountAssigned(ctx context.Context, name string) (int, error) {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `
+	SELECT
+		(SELECT COUNT(1) FROM users WHERE :name = ANY(roles)) +
+		(SELECT COUNT(1) FROM api_keys WHERE :name = ANY(roles)) AS count`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &count); err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
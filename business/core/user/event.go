@@ -0,0 +1,56 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/sys/events"
+)
+
+// EventAggregate is the aggregate of the events about users.
+const EventAggregate = "user"
+
+// Set of event types published when users change.
+const (
+	EventCreated = "user.created"
+	EventUpdated = "user.updated"
+	EventDeleted = "user.deleted"
+	EventLocked  = "user.locked"
+)
+
+// EventData is the payload of the events about users. It holds the state of
+// the user after the change, without the password hash.
+type EventData struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	Email           string    `json:"email"`
+	Roles           []string  `json:"roles"`
+	Department      string    `json:"department"`
+	Enabled         bool      `json:"enabled"`
+	DateCreated     time.Time `json:"dateCreated"`
+	DateUpdated     time.Time `json:"dateUpdated"`
+	DateLockedUntil time.Time `json:"dateLockedUntil,omitempty"`
+}
+
+// newEvent constructs an event of the specified type about the user.
+func newEvent(typ string, usr User) (events.Event, error) {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
+
+	data := EventData{
+		ID:              usr.ID,
+		Name:            usr.Name,
+		Email:           usr.Email.Address,
+		Roles:           roles,
+		Department:      usr.Department,
+		Enabled:         usr.Enabled,
+		DateCreated:     usr.DateCreated,
+		DateUpdated:     usr.DateUpdated,
+		DateLockedUntil: usr.DateLockedUntil,
+	}
+
+	return events.New(EventAggregate, usr.ID, typ, data)
+}
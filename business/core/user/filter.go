@@ -1,10 +1,14 @@
 package user
 
 import (
+	"context"
+	"fmt"
 	"net/mail"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/sys/validate"
 )
 
 // QueryFilter holds the available fields a query can be filtered on.
@@ -17,10 +21,10 @@ type QueryFilter struct {
 }
 
 // Validate checks the data in the model is considered clean.
-func (qf *QueryFilter) Validate() error {
-	// if err := validate.Check(qf); err != nil {
-	// 	return fmt.Errorf("validate: %w", err)
-	// }
+func (qf *QueryFilter) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, qf); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
 	return nil
 }
 
@@ -0,0 +1,187 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/qcbit/service/business/sys/events"
+)
+
+func Test_Lockout(t *testing.T) {
+	const password = "Gopher2023"
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Should be able to hash the password: %s", err)
+	}
+
+	email := mail.Address{Address: "bill@example.com"}
+	store := users{
+		usr: User{ID: uuid.New(), Email: email, PasswordHash: hash, Enabled: true},
+	}
+
+	core := NewCore(&store, Config{
+		BcryptCost:      bcrypt.MinCost + 1,
+		MaxFailedLogins: 3,
+		LockoutDuration: time.Hour,
+	})
+
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := core.Authenticate(ctx, email, "wrong"); !errors.Is(err, ErrAuthenticationFailure) {
+			t.Fatalf("Should fail attempt %d with a wrong password: got %v", i, err)
+		}
+
+		if locked := store.usr.Locked(time.Now()); locked != (i == 3) {
+			t.Errorf("Should lock the account once the threshold is reached: attempt %d locked %t", i, locked)
+		}
+	}
+
+	if store.lockouts != 1 {
+		t.Errorf("Should record a single lockout: got %d", store.lockouts)
+	}
+
+	if _, err := core.Authenticate(ctx, email, password); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Should reject the right password while locked: got %v", err)
+	}
+
+	// The lockout expires.
+	store.usr.DateLockedUntil = time.Now().Add(-time.Second)
+
+	usr, err := core.Authenticate(ctx, email, password)
+	if err != nil {
+		t.Fatalf("Should authenticate once the lockout expired: %s", err)
+	}
+
+	if store.usr.FailedLogins == 0 {
+		t.Errorf("Should keep the failed logins until the login is complete.")
+	}
+
+	if _, err := core.CompleteLogin(ctx, usr); err != nil {
+		t.Fatalf("Should be able to complete the login: %s", err)
+	}
+
+	if store.usr.FailedLogins != 0 {
+		t.Errorf("Should reset the failed logins: got %d", store.usr.FailedLogins)
+	}
+
+	if cost, _ := bcrypt.Cost(store.usr.PasswordHash); cost != bcrypt.MinCost+1 {
+		t.Errorf("Should upgrade the password hash to the configured cost: got %d", cost)
+	}
+}
+
+func Test_LockoutSecondFactor(t *testing.T) {
+	const password = "Gopher2023"
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Should be able to hash the password: %s", err)
+	}
+
+	email := mail.Address{Address: "bill@example.com"}
+	store := users{
+		usr: User{ID: uuid.New(), Email: email, PasswordHash: hash, Enabled: true},
+	}
+
+	core := NewCore(&store, Config{
+		BcryptCost:      bcrypt.MinCost,
+		MaxFailedLogins: 3,
+		LockoutDuration: time.Hour,
+	})
+
+	ctx := context.Background()
+
+	// The right password followed by a wrong second factor, as the token
+	// handler does, must count towards the lockout.
+	for i := 1; i <= 3; i++ {
+		usr, err := core.Authenticate(ctx, email, password)
+		if err != nil {
+			t.Fatalf("Should accept the right password on attempt %d: %s", i, err)
+		}
+
+		if err := core.RecordLoginFailure(ctx, usr); err != nil {
+			t.Fatalf("Should be able to record the failed second factor: %s", err)
+		}
+	}
+
+	if !store.usr.Locked(time.Now()) || store.lockouts != 1 {
+		t.Errorf("Should lock the account after repeated wrong second factors: failed %d, lockouts %d", store.usr.FailedLogins, store.lockouts)
+	}
+
+	if _, err := core.Authenticate(ctx, email, password); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Should reject the right password while locked: got %v", err)
+	}
+}
+
+func Test_AuthenticateUnknownEmail(t *testing.T) {
+	store := users{
+		usr: User{ID: uuid.New(), Email: mail.Address{Address: "bill@example.com"}, Enabled: true},
+	}
+
+	core := NewCore(&store, Config{BcryptCost: bcrypt.MinCost + 1})
+
+	_, err := core.Authenticate(context.Background(), mail.Address{Address: "ed@example.com"}, "Gopher2023")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Should fail for an unknown email: got %v", err)
+	}
+
+	// The password is compared against a hash of the configured cost, like
+	// the password of a registered user.
+	if cost, err := bcrypt.Cost(core.dummy); err != nil || cost != bcrypt.MinCost+1 {
+		t.Errorf("Should compare the password against a hash of the configured cost: got %d, %v", cost, err)
+	}
+}
+
+// =============================================================================
+
+// users is a Storer holding a single user. Only the methods used to
+// authenticate are implemented, the password hash is the only column the
+// authentication may replace.
+type users struct {
+	Storer
+	usr      User
+	lockouts int
+}
+
+func (u *users) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
+	if email.Address != u.usr.Email.Address {
+		return User{}, ErrNotFound
+	}
+
+	return u.usr, nil
+}
+
+func (u *users) Update(ctx context.Context, usr User, evs ...events.Event) error {
+	return errors.New("the whole user must not be written")
+}
+
+func (u *users) UpdatePasswordHash(ctx context.Context, usr User) error {
+	u.usr.PasswordHash = usr.PasswordHash
+	return nil
+}
+
+func (u *users) RecordLoginFailure(ctx context.Context, usr User, maxFailed int, lockedUntil time.Time) (User, error) {
+	u.usr.FailedLogins++
+	if u.usr.FailedLogins >= maxFailed {
+		u.usr.DateLockedUntil = lockedUntil
+	}
+
+	return u.usr, nil
+}
+
+func (u *users) ResetLoginFailures(ctx context.Context, usr User) error {
+	u.usr.FailedLogins = 0
+	return nil
+}
+
+func (u *users) CreateLockout(ctx context.Context, lck Lockout, evs ...events.Event) error {
+	u.lockouts++
+	return nil
+}
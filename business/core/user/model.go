@@ -18,6 +18,41 @@ type User struct {
 	Enabled      bool
 	DateCreated  time.Time
 	DateUpdated  time.Time
+
+	FailedLogins        int
+	DateLockedUntil     time.Time
+	DateSessionsRevoked time.Time
+}
+
+// Locked reports if the user is locked out at the specified time.
+func (u User) Locked(now time.Time) bool {
+	return u.DateLockedUntil.After(now)
+}
+
+// SessionRevoked reports if a session issued at the specified time has been
+// revoked. Tokens record when they were issued to the second, so the time of
+// the revocation is compared to the second as well. Otherwise a session
+// started in the second of the revocation, after it, would be revoked.
+func (u User) SessionRevoked(issuedAt time.Time) bool {
+	return issuedAt.Before(u.DateSessionsRevoked.Truncate(time.Second))
+}
+
+// Version summarizes the state of a set of users. It changes whenever a user
+// in the set is added, updated or removed, so it can tell if a copy of the set
+// is current without loading it.
+type Version struct {
+	Count       int
+	DateUpdated time.Time
+}
+
+// Lockout represents an audit record of a user being locked out after too
+// many failed login attempts.
+type Lockout struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	FailedLogins    int
+	DateLockedUntil time.Time
+	DateCreated     time.Time
 }
 
 // NewUser contains information needed to create a new user.
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_SessionRevoked(t *testing.T) {
+	revoked := time.Date(2023, time.March, 1, 12, 0, 0, 600_000_000, time.UTC)
+	usr := User{DateSessionsRevoked: revoked}
+
+	tt := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"before", revoked.Add(-time.Second).Truncate(time.Second), true},
+		{"samesecond", revoked.Truncate(time.Second), false},
+		{"after", revoked.Add(time.Second).Truncate(time.Second), false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if got := usr.SessionRevoked(tst.issuedAt); got != tst.revoked {
+				t.Errorf("Should report the session revoked %t: got %t", tst.revoked, got)
+			}
+		})
+	}
+
+	if (User{}).SessionRevoked(revoked) {
+		t.Errorf("Should not revoke sessions when none were revoked.")
+	}
+}
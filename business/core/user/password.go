@@ -0,0 +1,98 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrWeakPassword is returned when a password doesn't satisfy the password
+// policy.
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// PasswordPolicy represents the rules a password must satisfy before it is
+// accepted for a user.
+type PasswordPolicy struct {
+	MinLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	RejectPersonal bool
+}
+
+// DefaultPasswordPolicy is the policy used when no policy is configured.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:      8,
+	RequireUpper:   true,
+	RequireLower:   true,
+	RequireDigit:   true,
+	RejectPersonal: true,
+}
+
+// Check validates the password against the policy. The name and email of the
+// user are used to reject passwords that contain personal information.
+func (p PasswordPolicy) Check(password string, name string, email string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("must be at least %d characters: %w", p.MinLength, ErrWeakPassword)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return fmt.Errorf("must contain an upper case letter: %w", ErrWeakPassword)
+	case p.RequireLower && !lower:
+		return fmt.Errorf("must contain a lower case letter: %w", ErrWeakPassword)
+	case p.RequireDigit && !digit:
+		return fmt.Errorf("must contain a digit: %w", ErrWeakPassword)
+	case p.RequireSymbol && !symbol:
+		return fmt.Errorf("must contain a symbol: %w", ErrWeakPassword)
+	}
+
+	if p.RejectPersonal && containsPersonal(password, name, email) {
+		return fmt.Errorf("must not contain the user's name or email: %w", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// containsPersonal reports if the password contains the email, the local part
+// of the email or any word of the name that is long enough to be meaningful.
+func containsPersonal(password string, name string, email string) bool {
+	const minWord = 3
+
+	pw := strings.ToLower(password)
+
+	var words []string
+	words = append(words, strings.Fields(strings.ToLower(name))...)
+
+	email = strings.ToLower(email)
+	if email != "" {
+		words = append(words, email)
+		if local, _, found := strings.Cut(email, "@"); found {
+			words = append(words, local)
+		}
+	}
+
+	for _, word := range words {
+		if len(word) >= minWord && strings.Contains(pw, word) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_PasswordPolicy(t *testing.T) {
+	symbols := DefaultPasswordPolicy
+	symbols.RequireSymbol = true
+
+	tt := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		valid    bool
+	}{
+		{"valid", DefaultPasswordPolicy, "Gopher2023", true},
+		{"short", DefaultPasswordPolicy, "Go2023", false},
+		{"runes", PasswordPolicy{MinLength: 4}, "äöüß", true},
+		{"noupper", DefaultPasswordPolicy, "gopher2023", false},
+		{"nolower", DefaultPasswordPolicy, "GOPHER2023", false},
+		{"nodigit", DefaultPasswordPolicy, "GopherGopher", false},
+		{"nosymbol", symbols, "Gopher2023", false},
+		{"symbol", symbols, "Gopher 2023!", true},
+		{"name", DefaultPasswordPolicy, "Kennedy2023x", false},
+		{"shortname", DefaultPasswordPolicy, "Bi1lGopher", true},
+		{"emaillocal", DefaultPasswordPolicy, "Xbkennedy99", false},
+		{"email", DefaultPasswordPolicy, "A1bkennedy@example.com", false},
+		{"personalallowed", PasswordPolicy{MinLength: 8}, "bkennedy", true},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			err := tst.policy.Check(tst.password, "Bi Kennedy", "bkennedy@example.com")
+
+			switch {
+			case tst.valid && err != nil:
+				t.Errorf("Should accept the password: %s", err)
+			case !tst.valid && !errors.Is(err, ErrWeakPassword):
+				t.Errorf("Should reject the password as weak: got %v", err)
+			}
+		})
+	}
+}
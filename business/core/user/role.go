@@ -1,43 +1,50 @@
 package user
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"regexp"
+)
 
-// Set of possible roles for a user.
+// Set of built-in roles for a user. These always exist.
 var (
 	RoleAdmin = Role{"ADMIN"}
 	RoleUser  = Role{"USER"}
 )
 
-// Set of known roles.
-var roles = map[string]Role{
+// Set of built-in roles that exist without a role lookup.
+var builtInRoles = map[string]Role{
 	RoleAdmin.name: RoleAdmin,
 	RoleUser.name:  RoleUser,
 }
 
+// roleNameRegEx matches the names roles can have.
+var roleNameRegEx = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// RoleLookup declares the behavior this package needs to validate role names
+// against the set of roles stored in the system.
+type RoleLookup interface {
+	Exists(ctx context.Context, name string) (bool, error)
+}
+
 // Role represents a role in the system.
 type Role struct {
 	name string
 }
 
-// ParseRole parses the string value and returns a role if one exists.
+// ParseRole parses the string value and returns a role if the value is a
+// valid role name. Whether the role exists is checked by the Core when the
+// role is assigned.
 func ParseRole(value string) (Role, error) {
-	role, exists := roles[value]
-	if !exists {
-		return Role{}, errors.New("invalid role")
+	if role, exists := builtInRoles[value]; exists {
+		return role, nil
 	}
 
-	return role, nil
-}
-
-// MustParseRole parses the string value and returns a role if one exists. If
-// an error occurs the function panics.
-func MustParseRole(value string) Role {
-	role, err := ParseRole(value)
-	if err != nil {
-		panic(err)
+	if !roleNameRegEx.MatchString(value) {
+		return Role{}, errors.New("invalid role")
 	}
 
-	return role
+	return Role{value}, nil
 }
 
 // Name returns the name of the role.
@@ -45,7 +52,9 @@ func (r Role) Name() string {
 	return r.name
 }
 
-// UnmarshalText implement the unmarshal interface for JSON conversions.
+// UnmarshalText implement the unmarshal interface for JSON conversions. The
+// name isn't validated, it is used for roles read back from claims and the
+// store which were validated when they were issued or written.
 func (r *Role) UnmarshalText(data []byte) error {
 	r.name = string(data)
 	return nil
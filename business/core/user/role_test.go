@@ -0,0 +1,82 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_ParseRole(t *testing.T) {
+	tt := []struct {
+		value string
+		valid bool
+	}{
+		{value: "ADMIN", valid: true},
+		{value: "USER", valid: true},
+		{value: "AUDITOR_2", valid: true},
+		{value: "auditor", valid: false},
+		{value: "2AUDITOR", valid: false},
+		{value: "", valid: false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.value, func(t *testing.T) {
+			role, err := ParseRole(tst.value)
+			if (err == nil) != tst.valid {
+				t.Fatalf("Should report valid %t: err %v", tst.valid, err)
+			}
+
+			if tst.valid && role.Name() != tst.value {
+				t.Errorf("Should keep the name: got %s", role.Name())
+			}
+		})
+	}
+}
+
+func Test_CheckRoles(t *testing.T) {
+	lookupErr := errors.New("database is down")
+
+	lookup := roleLookup(func(ctx context.Context, name string) (bool, error) {
+		switch name {
+		case "AUDITOR":
+			return true, nil
+		case "BROKEN":
+			return false, lookupErr
+		}
+		return false, nil
+	})
+
+	tt := []struct {
+		name   string
+		lookup RoleLookup
+		roles  []Role
+		exp    error
+	}{
+		{name: "builtin", lookup: nil, roles: []Role{RoleAdmin, RoleUser}, exp: nil},
+		{name: "nolookup", lookup: nil, roles: []Role{{"AUDITOR"}}, exp: ErrUnknownRole},
+		{name: "exists", lookup: lookup, roles: []Role{RoleUser, {"AUDITOR"}}, exp: nil},
+		{name: "unknown", lookup: lookup, roles: []Role{{"MISSING"}}, exp: ErrUnknownRole},
+		{name: "failure", lookup: lookup, roles: []Role{{"BROKEN"}}, exp: lookupErr},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			c := NewCore(nil, Config{Roles: tst.lookup})
+
+			err := c.checkRoles(context.Background(), tst.roles)
+			if tst.exp == nil && err != nil {
+				t.Fatalf("Should accept the roles: %s", err)
+			}
+			if tst.exp != nil && !errors.Is(err, tst.exp) {
+				t.Fatalf("Should return %q: got %v", tst.exp, err)
+			}
+		})
+	}
+}
+
+// roleLookup is a RoleLookup backed by a function.
+type roleLookup func(ctx context.Context, name string) (bool, error)
+
+func (f roleLookup) Exists(ctx context.Context, name string) (bool, error) {
+	return f(ctx, name)
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+// Set of validation tags registered by this package.
+const (
+
+	// TagRole validates a string is a valid role name.
+	TagRole = "role"
+
+	// TagPassword validates a string satisfies the password policy set with
+	// SetPasswordPolicy. The name and email of the user aren't known to the
+	// validator, they are still checked by the core.
+	TagPassword = "password"
+)
+
+// policy holds the password policy used by the password validator.
+var policy = struct {
+	mu     sync.RWMutex
+	policy PasswordPolicy
+}{
+	policy: DefaultPasswordPolicy,
+}
+
+// SetPasswordPolicy sets the policy the password validator checks passwords
+// against. Until a policy is set the default policy is used.
+func SetPasswordPolicy(p PasswordPolicy) {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+
+	policy.policy = p
+}
+
+func init() {
+	err := validate.RegisterValidator(TagRole, validRole, map[string]string{
+		"en": "{0} must be a valid role name",
+		"es": "{0} debe ser un nombre de rol válido",
+		"de": "{0} muss ein gültiger Rollenname sein",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = validate.RegisterValidator(TagPassword, validPassword, map[string]string{
+		"en": "{0} does not meet the password policy",
+		"es": "{0} no cumple la política de contraseñas",
+		"de": "{0} erfüllt die Passwortrichtlinie nicht",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	validate.RegisterStructRule(validQueryFilter, QueryFilter{})
+}
+
+// =============================================================================
+
+// validRole validates the field is a valid role name. Whether the role exists
+// is checked when it is assigned.
+func validRole(fl validator.FieldLevel) bool {
+	_, err := ParseRole(fl.Field().String())
+	return err == nil
+}
+
+// validPassword validates the field satisfies the password policy.
+func validPassword(fl validator.FieldLevel) bool {
+	policy.mu.RLock()
+	p := policy.policy
+	policy.mu.RUnlock()
+
+	return p.Check(fl.Field().String(), "", "") == nil
+}
+
+// validQueryFilter validates the range of created dates. Errors are reported
+// by the names of the query parameters the dates are parsed from.
+func validQueryFilter(sl validator.StructLevel) {
+	qf := sl.Current().Interface().(QueryFilter)
+
+	if !validate.ValidDateRange(qf.StartCreatedDate, qf.EndCreatedDate) {
+		sl.ReportError(qf.EndCreatedDate, "end_created_date", "EndCreatedDate", validate.TagDateRange, "start_created_date")
+	}
+}
@@ -22,6 +22,10 @@ type dbUser struct {
 	Department   sql.NullString `db:"department"`
 	DateCreated  time.Time      `db:"date_created"`
 	DateUpdated  time.Time      `db:"date_updated"`
+
+	FailedLogins        int          `db:"failed_logins"`
+	DateLockedUntil     sql.NullTime `db:"date_locked_until"`
+	DateSessionsRevoked sql.NullTime `db:"date_sessions_revoked"`
 }
 
 func toDBUser(usr user.User) dbUser {
@@ -43,6 +47,16 @@ func toDBUser(usr user.User) dbUser {
 		Enabled:     usr.Enabled,
 		DateCreated: usr.DateCreated.UTC(),
 		DateUpdated: usr.DateUpdated.UTC(),
+
+		FailedLogins: usr.FailedLogins,
+		DateLockedUntil: sql.NullTime{
+			Time:  usr.DateLockedUntil.UTC(),
+			Valid: !usr.DateLockedUntil.IsZero(),
+		},
+		DateSessionsRevoked: sql.NullTime{
+			Time:  usr.DateSessionsRevoked.UTC(),
+			Valid: !usr.DateSessionsRevoked.IsZero(),
+		},
 	}
 }
 
@@ -51,9 +65,10 @@ func toCoreUser(dbUsr dbUser) user.User {
 		Address: dbUsr.Email,
 	}
 
+	// Stored roles were validated when they were written.
 	roles := make([]user.Role, len(dbUsr.Roles))
 	for i, value := range dbUsr.Roles {
-		roles[i] = user.MustParseRole(value)
+		roles[i].UnmarshalText([]byte(value))
 	}
 
 	usr := user.User{
@@ -66,6 +81,16 @@ func toCoreUser(dbUsr dbUser) user.User {
 		Department:   dbUsr.Department.String,
 		DateCreated:  dbUsr.DateCreated.In(time.Local),
 		DateUpdated:  dbUsr.DateUpdated.In(time.Local),
+
+		FailedLogins: dbUsr.FailedLogins,
+	}
+
+	if dbUsr.DateLockedUntil.Valid {
+		usr.DateLockedUntil = dbUsr.DateLockedUntil.Time.In(time.Local)
+	}
+
+	if dbUsr.DateSessionsRevoked.Valid {
+		usr.DateSessionsRevoked = dbUsr.DateSessionsRevoked.Time.In(time.Local)
 	}
 
 	return usr
@@ -77,4 +102,24 @@ func toCoreUserSlice(dbUsers []dbUser) []user.User {
 		usrs[i] = toCoreUser(dbUsr)
 	}
 	return usrs
-}
\ No newline at end of file
+}
+
+// =============================================================================
+
+type dbLockout struct {
+	ID              uuid.UUID `db:"lockout_id"`
+	UserID          uuid.UUID `db:"user_id"`
+	FailedLogins    int       `db:"failed_logins"`
+	DateLockedUntil time.Time `db:"date_locked_until"`
+	DateCreated     time.Time `db:"date_created"`
+}
+
+func toDBLockout(lck user.Lockout) dbLockout {
+	return dbLockout{
+		ID:              lck.ID,
+		UserID:          lck.UserID,
+		FailedLogins:    lck.FailedLogins,
+		DateLockedUntil: lck.DateLockedUntil.UTC(),
+		DateCreated:     lck.DateCreated.UTC(),
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net/mail"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -15,9 +16,34 @@ import (
 	"github.com/qcbit/service/business/data/order"
 	database "github.com/qcbit/service/business/sys/database/pgx"
 	"github.com/qcbit/service/business/sys/database/pgx/dbarray"
+	"github.com/qcbit/service/business/sys/events"
+	"github.com/qcbit/service/business/sys/events/stores/outboxdb"
 	"go.uber.org/zap"
 )
 
+// UpdatePassword writes the password hash of the user and the date its
+// sessions were revoked. Other stores call it with their transaction, so the
+// password is replaced together with the change authorizing it.
+func UpdatePassword(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtContext, usr user.User) error {
+	const q = `
+	UPDATE
+		users
+	SET
+		"password_hash" = :password_hash,
+		"date_sessions_revoked" = :date_sessions_revoked,
+		"date_updated" = :date_updated
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, log, db, q, toDBUser(usr)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
 // Store manages the set of APIs for user database access.
 type Store struct {
 	log *zap.SugaredLogger
@@ -32,26 +58,31 @@ func NewStore(log *zap.SugaredLogger, db *sqlx.DB) *Store {
 	}
 }
 
-// Create inserts a new user into the database.
-func (s *Store) Create(ctx context.Context, usr user.User) error {
+// Create inserts a new user into the database along with the events of the
+// change.
+func (s *Store) Create(ctx context.Context, usr user.User, evs ...events.Event) error {
 	const q = `
 	INSERT INTO users
 		(user_id, name, email, password_hash, roles, enabled, department, date_created, date_updated)
 	VALUES
 		(:user_id, :name, :email, :password_hash, :roles, :enabled, :department, :date_created, :date_updated)`
 
-	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); err != nil {
-		if errors.Is(err, database.ErrDBDuplicatedEntry) {
-			return fmt.Errorf("namedexeccontext: %w", user.ErrUniqueEmail)
+	f := func(db sqlx.ExtContext) error {
+		if err := database.NamedExecContext(ctx, s.log, db, q, toDBUser(usr)); err != nil {
+			if errors.Is(err, database.ErrDBDuplicatedEntry) {
+				return fmt.Errorf("namedexeccontext: %w", user.ErrUniqueEmail)
+			}
+			return fmt.Errorf("namedexeccontext: %w", err)
 		}
-		return fmt.Errorf("namedexeccontext: %w", err)
+		return nil
 	}
 
-	return nil
+	return s.withEvents(ctx, evs, f)
 }
 
-// Update replaces a user document in the database.
-func (s *Store) Update(ctx context.Context, usr user.User) error {
+// Update replaces a user document in the database along with the events of
+// the change.
+func (s *Store) Update(ctx context.Context, usr user.User, evs ...events.Event) error {
 	const q = `
 	UPDATE
 		users
@@ -59,22 +90,29 @@ func (s *Store) Update(ctx context.Context, usr user.User) error {
 		"name" = :name,
 		"email" = :email,
 		"password_hash" = :password_hash,
+		"roles" = :roles,
 		"department" = :department,
+		"date_sessions_revoked" = :date_sessions_revoked,
 		"date_updated" = :date_updated
 	WHERE
 		user_id = :user_id`
 
-	if err := database.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); err != nil {
-		if errors.Is(err, database.ErrDBDuplicatedEntry) {
-			return user.ErrUniqueEmail
+	f := func(db sqlx.ExtContext) error {
+		if err := database.NamedExecContext(ctx, s.log, db, q, toDBUser(usr)); err != nil {
+			if errors.Is(err, database.ErrDBDuplicatedEntry) {
+				return user.ErrUniqueEmail
+			}
+			return fmt.Errorf("namedexeccontext: %w", err)
 		}
-		return fmt.Errorf("namedexeccontext: %w", err)
+		return nil
 	}
-	return nil
+
+	return s.withEvents(ctx, evs, f)
 }
 
-// Delete removes a user from the database.
-func (s *Store) Delete(ctx context.Context, usr user.User) error {
+// Delete removes a user from the database along with the events of the
+// change.
+func (s *Store) Delete(ctx context.Context, usr user.User, evs ...events.Event) error {
 	data := struct {
 		UserID string `db:"user_id"`
 	}{
@@ -87,11 +125,14 @@ func (s *Store) Delete(ctx context.Context, usr user.User) error {
 	WHERE
 		user_id = :user_id`
 
-	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
-		return fmt.Errorf("namedexeccontext: %w", err)
+	f := func(db sqlx.ExtContext) error {
+		if err := database.NamedExecContext(ctx, s.log, db, q, data); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+		return nil
 	}
 
-	return nil
+	return s.withEvents(ctx, evs, f)
 }
 
 // Query retrieves a list of existing users from the database.
@@ -149,6 +190,39 @@ func (s *Store) Count(ctx context.Context, filter user.QueryFilter) (int, error)
 	return count.Count, nil
 }
 
+// QueryVersion returns the number of users matching the filter and the last
+// time one of them was updated.
+func (s *Store) QueryVersion(ctx context.Context, filter user.QueryFilter) (user.Version, error) {
+	data := map[string]interface{}{}
+
+	const q = `
+	SELECT
+		COUNT(1) AS count,
+		MAX(date_updated) AS date_updated
+	FROM
+		users`
+
+	buf := bytes.NewBufferString(q)
+	s.applyFilter(filter, data, buf)
+
+	var ver struct {
+		Count       int          `db:"count"`
+		DateUpdated sql.NullTime `db:"date_updated"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &ver); err != nil {
+		return user.Version{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	v := user.Version{
+		Count: ver.Count,
+	}
+	if ver.DateUpdated.Valid {
+		v.DateUpdated = ver.DateUpdated.Time.In(time.Local)
+	}
+
+	return v, nil
+}
+
 // QueryByID gets the specified user from the database.
 func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
 	data := struct {
@@ -236,4 +310,131 @@ func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (user.User
 	}
 
 	return toCoreUser(dbUsr), nil
-}
\ No newline at end of file
+}
+
+// RecordLoginFailure increments the failed login counter for the user. When
+// the counter reaches maxFailed the user is locked until the specified time
+// and the counter starts over. The update is performed atomically so
+// concurrent failures are all counted.
+func (s *Store) RecordLoginFailure(ctx context.Context, usr user.User, maxFailed int, lockedUntil time.Time) (user.User, error) {
+	data := struct {
+		UserID          string    `db:"user_id"`
+		MaxFailed       int       `db:"max_failed"`
+		DateLockedUntil time.Time `db:"date_locked_until"`
+	}{
+		UserID:          usr.ID.String(),
+		MaxFailed:       maxFailed,
+		DateLockedUntil: lockedUntil.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		"failed_logins" = CASE WHEN failed_logins + 1 >= :max_failed THEN 0 ELSE failed_logins + 1 END,
+		"date_locked_until" = CASE WHEN failed_logins + 1 >= :max_failed THEN :date_locked_until ELSE date_locked_until END
+	WHERE
+		user_id = :user_id
+	RETURNING
+		*`
+
+	var dbUsr dbUser
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreUser(dbUsr), nil
+}
+
+// ResetLoginFailures clears the failed login counter for the user.
+func (s *Store) ResetLoginFailures(ctx context.Context, usr user.User) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: usr.ID.String(),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		"failed_logins" = 0
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePasswordHash replaces the password hash of the user, leaving the
+// rest of the row as it is.
+func (s *Store) UpdatePasswordHash(ctx context.Context, usr user.User) error {
+	data := struct {
+		UserID       string `db:"user_id"`
+		PasswordHash []byte `db:"password_hash"`
+	}{
+		UserID:       usr.ID.String(),
+		PasswordHash: usr.PasswordHash,
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		"password_hash" = :password_hash
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// CreateLockout inserts an audit record of a user being locked out along
+// with the events of the change.
+func (s *Store) CreateLockout(ctx context.Context, lck user.Lockout, evs ...events.Event) error {
+	const q = `
+	INSERT INTO user_lockouts
+		(lockout_id, user_id, failed_logins, date_locked_until, date_created)
+	VALUES
+		(:lockout_id, :user_id, :failed_logins, :date_locked_until, :date_created)`
+
+	f := func(db sqlx.ExtContext) error {
+		if err := database.NamedExecContext(ctx, s.log, db, q, toDBLockout(lck)); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+		return nil
+	}
+
+	return s.withEvents(ctx, evs, f)
+}
+
+// withEvents runs the change and writes the events to the outbox in the same
+// transaction. Changes without events run outside of a transaction.
+func (s *Store) withEvents(ctx context.Context, evs []events.Event, change func(db sqlx.ExtContext) error) error {
+	if len(evs) == 0 {
+		return change(s.db)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+
+		if err := outboxdb.Insert(ctx, s.log, tx, evs); err != nil {
+			return fmt.Errorf("insert events: %w", err)
+		}
+
+		return nil
+	}
+
+	return database.WithinTran(ctx, s.log, s.db, f)
+}
@@ -8,12 +8,14 @@ import (
 	"errors"
 	"fmt"
 	"net/mail"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/qcbit/service/business/data/order"
+	"github.com/qcbit/service/business/sys/events"
 )
 
 // Set of error variables for CRUD operations.
@@ -21,36 +23,97 @@ var (
 	ErrNotFound              = errors.New("user not found")
 	ErrUniqueEmail           = errors.New("email is not unique")
 	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrAccountLocked         = errors.New("account is temporarily locked")
+	ErrUnknownRole           = errors.New("role does not exist")
 )
 
+// Set of default values used when the Config doesn't specify a value.
+const (
+	defaultMaxFailedLogins = 5
+	defaultLockoutDuration = 15 * time.Minute
+)
+
+// Config represents the settings that control how passwords are stored and
+// verified. Zero values select the defaults. Roles validates the roles
+// assigned to users, without it only the built-in roles can be assigned.
+type Config struct {
+	BcryptCost      int
+	MaxFailedLogins int
+	LockoutDuration time.Duration
+	PasswordPolicy  *PasswordPolicy
+	Roles           RoleLookup
+}
+
 // Storer interface declares the behavior this package needs to persists and
-// retrieve data.
+// retrieve data. The events passed with a change must be written to the
+// outbox in the same transaction as the change.
 type Storer interface {
-	Create(ctx context.Context, usr User) error
-	Update(ctx context.Context, usr User) error
-	Delete(ctx context.Context, usr User) error
+	Create(ctx context.Context, usr User, evs ...events.Event) error
+	Update(ctx context.Context, usr User, evs ...events.Event) error
+	Delete(ctx context.Context, usr User, evs ...events.Event) error
 	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error)
 	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryVersion(ctx context.Context, filter QueryFilter) (Version, error)
 	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
 	QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]User, error)
 	QueryByEmail(ctx context.Context, email mail.Address) (User, error)
+	RecordLoginFailure(ctx context.Context, usr User, maxFailed int, lockedUntil time.Time) (User, error)
+	ResetLoginFailures(ctx context.Context, usr User) error
+	UpdatePasswordHash(ctx context.Context, usr User) error
+	CreateLockout(ctx context.Context, lck Lockout, evs ...events.Event) error
 }
 
 // Core manages the set of APIs for user access.
 type Core struct {
-	storer Storer
+	storer          Storer
+	bcryptCost      int
+	maxFailedLogins int
+	lockoutDuration time.Duration
+	passwordPolicy  PasswordPolicy
+	roles           RoleLookup
+
+	dummyOnce sync.Once
+	dummy     []byte
 }
 
 // NewCore constructs a core for user api access.
-func NewCore(storer Storer) *Core {
-	return &Core{
-		storer: storer,
+func NewCore(storer Storer, cfg Config) *Core {
+	c := Core{
+		storer:          storer,
+		bcryptCost:      bcrypt.DefaultCost,
+		maxFailedLogins: defaultMaxFailedLogins,
+		lockoutDuration: defaultLockoutDuration,
+		passwordPolicy:  DefaultPasswordPolicy,
+		roles:           cfg.Roles,
+	}
+
+	if cfg.BcryptCost != 0 {
+		c.bcryptCost = cfg.BcryptCost
 	}
+	if cfg.MaxFailedLogins != 0 {
+		c.maxFailedLogins = cfg.MaxFailedLogins
+	}
+	if cfg.LockoutDuration != 0 {
+		c.lockoutDuration = cfg.LockoutDuration
+	}
+	if cfg.PasswordPolicy != nil {
+		c.passwordPolicy = *cfg.PasswordPolicy
+	}
+
+	return &c
 }
 
 // Create a new user in the database.
 func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
+	if err := c.passwordPolicy.Check(nu.Password, nu.Name, nu.Email.Address); err != nil {
+		return User{}, fmt.Errorf("check: %w", err)
+	}
+
+	if err := c.checkRoles(ctx, nu.Roles); err != nil {
+		return User{}, fmt.Errorf("checkroles: %w", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), c.bcryptCost)
 	if err != nil {
 		return User{}, fmt.Errorf("generatefrompassword: %w", err)
 	}
@@ -69,7 +132,12 @@ func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
 		DateUpdated:  now,
 	}
 
-	if err := c.storer.Create(ctx, usr); err != nil {
+	ev, err := newEvent(EventCreated, usr)
+	if err != nil {
+		return User{}, fmt.Errorf("newevent: %w", err)
+	}
+
+	if err := c.storer.Create(ctx, usr, ev); err != nil {
 		return User{}, fmt.Errorf("create: %w", err)
 	}
 
@@ -85,10 +153,17 @@ func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error
 		usr.Email = *uu.Email
 	}
 	if uu.Roles != nil {
+		if err := c.checkRoles(ctx, uu.Roles); err != nil {
+			return User{}, fmt.Errorf("checkroles: %w", err)
+		}
 		usr.Roles = uu.Roles
 	}
 	if uu.Password != nil {
-		pw, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
+		if err := c.passwordPolicy.Check(*uu.Password, usr.Name, usr.Email.Address); err != nil {
+			return User{}, fmt.Errorf("check: %w", err)
+		}
+
+		pw, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), c.bcryptCost)
 		if err != nil {
 			return User{}, fmt.Errorf("generatefrompassword: %w", err)
 		}
@@ -102,16 +177,64 @@ func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error
 	}
 	usr.DateUpdated = time.Now()
 
-	if err := c.storer.Update(ctx, usr); err != nil {
+	ev, err := newEvent(EventUpdated, usr)
+	if err != nil {
+		return User{}, fmt.Errorf("newevent: %w", err)
+	}
+
+	if err := c.storer.Update(ctx, usr, ev); err != nil {
 		return User{}, fmt.Errorf("update: %w", err)
 	}
 
 	return usr, nil
 }
 
+// CheckPassword validates the password against the password policy for the
+// specified user without changing the user.
+func (c *Core) CheckPassword(usr User, password string) error {
+	if err := c.passwordPolicy.Check(password, usr.Name, usr.Email.Address); err != nil {
+		return fmt.Errorf("check: %w", err)
+	}
+
+	return nil
+}
+
+// PasswordReset returns the user with the password replaced and every session
+// issued before the reset revoked, along with the event of the change. The
+// change isn't stored, the caller stores it in the same transaction as the
+// change authorizing the reset.
+func (c *Core) PasswordReset(usr User, password string) (User, events.Event, error) {
+	if err := c.CheckPassword(usr, password); err != nil {
+		return User{}, events.Event{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), c.bcryptCost)
+	if err != nil {
+		return User{}, events.Event{}, fmt.Errorf("generatefrompassword: %w", err)
+	}
+
+	now := time.Now()
+
+	usr.PasswordHash = hash
+	usr.DateSessionsRevoked = now
+	usr.DateUpdated = now
+
+	ev, err := newEvent(EventUpdated, usr)
+	if err != nil {
+		return User{}, events.Event{}, fmt.Errorf("newevent: %w", err)
+	}
+
+	return usr, ev, nil
+}
+
 // Delete removes a user from the database.
 func (c *Core) Delete(ctx context.Context, usr User) error {
-	if err := c.storer.Delete(ctx, usr); err != nil {
+	ev, err := newEvent(EventDeleted, usr)
+	if err != nil {
+		return fmt.Errorf("newevent: %w", err)
+	}
+
+	if err := c.storer.Delete(ctx, usr, ev); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
 
@@ -133,6 +256,16 @@ func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
 	return c.storer.Count(ctx, filter)
 }
 
+// QueryVersion returns the version of the set of users matching the filter.
+func (c *Core) QueryVersion(ctx context.Context, filter QueryFilter) (Version, error) {
+	ver, err := c.storer.QueryVersion(ctx, filter)
+	if err != nil {
+		return Version{}, fmt.Errorf("queryversion: %w", err)
+	}
+
+	return ver, nil
+}
+
 // QueryByID gets the specified user from the database.
 func (c *Core) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
 	user, err := c.storer.QueryByID(ctx, userID)
@@ -168,15 +301,140 @@ func (c *Core) QueryByEmail(ctx context.Context, email mail.Address) (User, erro
 // Authenticate finds a user by their email and verifies their password. On
 // success, it returns a Claims User representing this user. The claims can be
 // used to generate a token for future authentication.
+//
+// Every failed attempt is counted against the user. Once the configured number
+// of failures is reached the account is locked for the lockout duration and
+// the lockout is recorded. When the bcrypt cost has changed, a correct
+// password upgrades the stored hash. The failures are only cleared by
+// CompleteLogin, once every authentication step succeeded.
 func (c *Core) Authenticate(ctx context.Context, email mail.Address, password string) (User, error) {
 	usr, err := c.QueryByEmail(ctx, email)
 	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			// The password is compared anyway, so the time to respond doesn't
+			// tell registered emails apart.
+			bcrypt.CompareHashAndPassword(c.dummyHash(), []byte(password))
+		}
 		return User{}, fmt.Errorf("query: email[%s]: %w", email, err)
 	}
 
+	now := time.Now()
+
+	if usr.Locked(now) {
+		return User{}, ErrAccountLocked
+	}
+
 	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
+		if err := c.recordFailure(ctx, usr, now); err != nil {
+			return User{}, fmt.Errorf("recordfailure: %w", err)
+		}
 		return User{}, fmt.Errorf("comparehashandpassword: %w", ErrAuthenticationFailure)
 	}
 
+	if cost, err := bcrypt.Cost(usr.PasswordHash); err == nil && cost != c.bcryptCost {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), c.bcryptCost)
+		if err != nil {
+			return User{}, fmt.Errorf("generatefrompassword: %w", err)
+		}
+		usr.PasswordHash = hash
+
+		// Only the hash is written so a concurrent change to the user isn't
+		// overwritten. The hash isn't part of the events, so the upgrade
+		// publishes none.
+		if err := c.storer.UpdatePasswordHash(ctx, usr); err != nil {
+			return User{}, fmt.Errorf("updatepasswordhash: %w", err)
+		}
+	}
+
+	return usr, nil
+}
+
+// CompleteLogin clears the failed logins of the user. It is called once the
+// password and any later authentication step, like a second factor,
+// succeeded, so failures of a later step keep counting towards the lockout.
+func (c *Core) CompleteLogin(ctx context.Context, usr User) (User, error) {
+	if usr.FailedLogins == 0 {
+		return usr, nil
+	}
+
+	if err := c.storer.ResetLoginFailures(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("resetloginfailures: %w", err)
+	}
+	usr.FailedLogins = 0
+
 	return usr, nil
 }
+
+// RecordLoginFailure counts a failed login attempt against the user. It is
+// used when a later authentication step, like a second factor, fails.
+func (c *Core) RecordLoginFailure(ctx context.Context, usr User) error {
+	return c.recordFailure(ctx, usr, time.Now())
+}
+
+// dummyHash returns a hash of the configured cost that matches no password,
+// to compare passwords against when there is no user.
+func (c *Core) dummyHash() []byte {
+	c.dummyOnce.Do(func() {
+		c.dummy, _ = bcrypt.GenerateFromPassword([]byte(uuid.NewString()), c.bcryptCost)
+	})
+
+	return c.dummy
+}
+
+// recordFailure counts a failed login attempt against the user and records a
+// lockout when the attempt locks the account.
+func (c *Core) recordFailure(ctx context.Context, usr User, now time.Time) error {
+	lockedUntil := now.Add(c.lockoutDuration)
+
+	usr, err := c.storer.RecordLoginFailure(ctx, usr, c.maxFailedLogins, lockedUntil)
+	if err != nil {
+		return fmt.Errorf("recordloginfailure: %w", err)
+	}
+
+	if !usr.Locked(now) {
+		return nil
+	}
+
+	lck := Lockout{
+		ID:              uuid.New(),
+		UserID:          usr.ID,
+		FailedLogins:    c.maxFailedLogins,
+		DateLockedUntil: usr.DateLockedUntil,
+		DateCreated:     now,
+	}
+
+	ev, err := newEvent(EventLocked, usr)
+	if err != nil {
+		return fmt.Errorf("newevent: %w", err)
+	}
+
+	if err := c.storer.CreateLockout(ctx, lck, ev); err != nil {
+		return fmt.Errorf("createlockout: %w", err)
+	}
+
+	return nil
+}
+
+// checkRoles validates the roles exist before they are assigned.
+func (c *Core) checkRoles(ctx context.Context, roles []Role) error {
+	for _, role := range roles {
+		if _, builtIn := builtInRoles[role.name]; builtIn {
+			continue
+		}
+
+		if c.roles == nil {
+			return fmt.Errorf("role[%s]: %w", role.name, ErrUnknownRole)
+		}
+
+		exists, err := c.roles.Exists(ctx, role.name)
+		if err != nil {
+			return fmt.Errorf("exists: role[%s]: %w", role.name, err)
+		}
+
+		if !exists {
+			return fmt.Errorf("role[%s]: %w", role.name, ErrUnknownRole)
+		}
+	}
+
+	return nil
+}
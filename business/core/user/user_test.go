@@ -13,6 +13,8 @@ import (
 	"github.com/qcbit/service/business/core/user"
 	"github.com/qcbit/service/business/data/dbtest"
 	"github.com/qcbit/service/business/data/order"
+	"github.com/qcbit/service/business/sys/events"
+	"github.com/qcbit/service/business/sys/events/stores/outboxdb"
 	"github.com/qcbit/service/foundation/docker"
 )
 
@@ -23,6 +25,7 @@ func TestMain(m *testing.M) {
 	c, err = dbtest.StartDB()
 	if err != nil {
 		fmt.Println(err)
+		m.Run()
 		return
 	}
 	defer dbtest.StopDB(c)
@@ -31,6 +34,10 @@ func TestMain(m *testing.M) {
 }
 
 func Test_User(t *testing.T) {
+	if c == nil {
+		t.Skip("database is not available")
+	}
+
 	t.Run("crud", crud)
 	t.Run("paging", paging)
 }
@@ -153,6 +160,26 @@ func crud(t *testing.T) {
 	if !errors.Is(err, user.ErrNotFound) {
 		t.Fatalf("Should NOT be able to retrieve user by ID: %s", err)
 	}
+
+	// -------------------------------------------------------------------------
+
+	var types []string
+	bus := events.NewBus()
+	bus.Subscribe("test", func(ctx context.Context, ev events.Event) error {
+		if ev.AggregateID == saved.ID {
+			types = append(types, ev.Type)
+		}
+		return nil
+	})
+
+	relay := events.NewRelay(test.Log, outboxdb.NewStore(test.Log, test.DB), bus, events.Config{})
+	if _, err := relay.Flush(ctx); err != nil {
+		t.Fatalf("Should be able to flush the outbox: %s", err)
+	}
+
+	if diff := cmp.Diff(types, []string{user.EventUpdated, user.EventDeleted}); diff != "" {
+		t.Errorf("Should deliver the events of the changes in order:\n%s", diff)
+	}
 }
 
 func paging(t *testing.T) {
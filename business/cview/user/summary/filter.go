@@ -1,6 +1,7 @@
 package usersummary
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -15,8 +16,8 @@ type QueryFilter struct {
 }
 
 // Validate checks the data in the model.
-func (qf *QueryFilter) Validate() error {
-	if err := validate.Check(qf); err != nil {
+func (qf *QueryFilter) Validate(ctx context.Context) error {
+	if err := validate.Check(ctx, qf); err != nil {
 		return fmt.Errorf("invalid query filter: %w", err)
 	}
 	return nil
@@ -43,3 +43,140 @@ JOIN
     products AS p ON p.user_id = u.user_id
 GROUP BY
     u.user_id
+
+-- Version: 1.04
+-- Description: Create table api_keys
+CREATE TABLE api_keys (
+	api_key_id     UUID        NOT NULL,
+	user_id        UUID        NOT NULL,
+	name           TEXT        NOT NULL,
+	prefix         TEXT        NOT NULL,
+	key_hash       TEXT UNIQUE NOT NULL,
+	roles          TEXT[]      NOT NULL,
+	date_expires   TIMESTAMP   NOT NULL,
+	date_last_used TIMESTAMP   NULL,
+	date_revoked   TIMESTAMP   NULL,
+	date_created   TIMESTAMP   NOT NULL,
+
+	PRIMARY KEY (api_key_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.05
+-- Description: Create table roles
+CREATE TABLE roles (
+	name         TEXT        NOT NULL,
+	description  TEXT        NULL,
+	permissions  TEXT[]      NOT NULL,
+	date_created TIMESTAMP   NOT NULL,
+	date_updated TIMESTAMP   NOT NULL,
+
+	PRIMARY KEY (name)
+);
+
+INSERT INTO roles (name, description, permissions, date_created, date_updated) VALUES
+	('ADMIN', 'Administrators of the system', '{users:read,users:write,roles:read,roles:write,roles:assign,apikeys:read,apikeys:write}', NOW(), NOW()),
+	('USER', 'Standard users of the system', '{users:read}', NOW(), NOW());
+
+-- Version: 1.06
+-- Description: Track failed logins and lockouts
+ALTER TABLE users
+	ADD COLUMN failed_logins     INT       NOT NULL DEFAULT 0,
+	ADD COLUMN date_locked_until TIMESTAMP NULL;
+
+CREATE TABLE user_lockouts (
+	lockout_id        UUID      NOT NULL,
+	user_id           UUID      NOT NULL,
+	failed_logins     INT       NOT NULL,
+	date_locked_until TIMESTAMP NOT NULL,
+	date_created      TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (lockout_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.07
+-- Description: Create table password_resets
+ALTER TABLE users
+	ADD COLUMN date_sessions_revoked TIMESTAMP NULL;
+
+CREATE TABLE password_resets (
+	reset_id     UUID        NOT NULL,
+	user_id      UUID        NOT NULL,
+	token_hash   TEXT UNIQUE NOT NULL,
+	date_expires TIMESTAMP   NOT NULL,
+	date_used    TIMESTAMP   NULL,
+	date_created TIMESTAMP   NOT NULL,
+
+	PRIMARY KEY (reset_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.08
+-- Description: Create tables for mfa enrollments and recovery codes
+CREATE TABLE user_mfa (
+	user_id        UUID      NOT NULL,
+	secret         BYTEA     NOT NULL,
+	last_counter   BIGINT    NOT NULL DEFAULT 0,
+	date_confirmed TIMESTAMP NULL,
+	date_created   TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (user_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE mfa_recovery_codes (
+	recovery_code_id UUID      NOT NULL,
+	user_id          UUID      NOT NULL,
+	code_hash        TEXT      NOT NULL,
+	date_used        TIMESTAMP NULL,
+	date_created     TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (recovery_code_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.09
+-- Description: Create table idempotency_keys
+CREATE TABLE idempotency_keys (
+	scope             TEXT      NOT NULL,
+	idempotency_key   TEXT      NOT NULL,
+	fingerprint       TEXT      NOT NULL,
+	status_code       INT       NOT NULL DEFAULT 0,
+	header            JSONB     NOT NULL DEFAULT '{}',
+	body              BYTEA     NULL,
+	date_completed    TIMESTAMP NULL,
+	date_locked_until TIMESTAMP NOT NULL,
+	date_expires      TIMESTAMP NOT NULL,
+	date_created      TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (scope, idempotency_key)
+);
+
+CREATE INDEX idempotency_keys_date_expires_idx ON idempotency_keys (date_expires);
+
+-- Version: 1.10
+-- Description: Create table outbox
+CREATE SEQUENCE outbox_delivery_seq;
+
+CREATE TABLE outbox (
+	sequence       BIGINT GENERATED ALWAYS AS IDENTITY,
+	event_id       UUID      NOT NULL,
+	aggregate      TEXT      NOT NULL,
+	aggregate_id   UUID      NOT NULL,
+	event_type     TEXT      NOT NULL,
+	data           JSONB     NOT NULL,
+	attempts       INT       NOT NULL DEFAULT 0,
+	last_error     TEXT      NULL,
+	date_retry     TIMESTAMP NULL,
+	delivery       BIGINT    NULL,
+	date_delivered TIMESTAMP NULL,
+	date_created   TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (sequence),
+	UNIQUE (event_id)
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (sequence) WHERE date_delivered IS NULL;
+CREATE INDEX outbox_delivery_idx ON outbox (delivery) WHERE delivery IS NOT NULL;
+CREATE INDEX outbox_failed_idx ON outbox (aggregate, aggregate_id) WHERE date_delivered IS NULL AND attempts > 0;
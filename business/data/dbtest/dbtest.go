@@ -12,6 +12,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/apikey/stores/apikeydb"
+	"github.com/qcbit/service/business/core/mfa"
+	"github.com/qcbit/service/business/core/mfa/stores/mfadb"
+	"github.com/qcbit/service/business/core/role"
+	"github.com/qcbit/service/business/core/role/stores/roledb"
 	"github.com/qcbit/service/business/core/user"
 	"github.com/qcbit/service/business/core/user/stores/userdb"
 	"github.com/qcbit/service/business/data/dbmigrate"
@@ -22,6 +28,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // StartDB starts a database instance.
@@ -233,14 +240,23 @@ func FloatPointer(f float64) *float64 {
 
 // CoreAPIs represents all the core api's needed for testing.
 type CoreAPIs struct {
-	User *user.Core
+	User   *user.Core
+	APIKey *apikey.Core
+	Role   *role.Core
+	MFA    *mfa.Core
 }
 
 func newCoreAPIs(log *zap.SugaredLogger, db *sqlx.DB) CoreAPIs {
-	usrCore := user.NewCore(userdb.NewStore(log, db))
+	usrCore := user.NewCore(userdb.NewStore(log, db), user.Config{BcryptCost: bcrypt.MinCost})
+	keyCore := apikey.NewCore(usrCore, apikeydb.NewStore(log, db))
+	roleCore := role.NewCore(log, roledb.NewStore(log, db))
+	mfaCore := mfa.NewCore(mfadb.NewStore(log, db), "service project")
 
 	return CoreAPIs{
-		User: usrCore,
+		User:   usrCore,
+		APIKey: keyCore,
+		Role:   roleCore,
+		MFA:    mfaCore,
 	}
 }
 
@@ -13,6 +13,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/qcbit/service/foundation/tracer"
 	"github.com/qcbit/service/foundation/web"
 	"go.uber.org/zap"
 )
@@ -109,6 +110,9 @@ func StatusCheck(ctx context.Context, db *sqlx.DB) error {
 func WithinTran(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
 	traceID := web.GetTraceID(ctx)
 
+	_, span := tracer.Start(ctx, "database.WithinTran")
+	defer span.End()
+
 	log.Infow("begin tran")
 	tx, err := db.Beginx()
 	if err != nil {
@@ -128,6 +132,7 @@ func WithinTran(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB, fn fun
 	}()
 
 	if err := fn(tx); err != nil {
+		span.SetError(err)
 		if pqerr, ok := err.(*pgconn.PgError); ok && pqerr.Code == uniqueViolation {
 			return ErrDBDuplicatedEntry
 		}
@@ -153,6 +158,10 @@ func ExecContext(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtContext
 func NamedExecContext(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtContext, query string, data any) error {
 	q := queryString(query, data)
 
+	ctx, span := tracer.Start(ctx, "database.NamedExecContext")
+	span.SetTag("db.statement", query)
+	defer span.End()
+
 	if _, ok := data.(struct{}); ok {
 		log.WithOptions(zap.AddCallerSkip(3)).Infow("database.NamedExecContext", "trace_id", web.GetTraceID(ctx), "query", q)
 	} else {
@@ -160,6 +169,7 @@ func NamedExecContext(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtCo
 	}
 
 	if _, err := sqlx.NamedExecContext(ctx, db, query, data); err != nil {
+		span.SetError(err)
 		if pqerr, ok := err.(*pgconn.PgError); ok {
 			switch pqerr.Code {
 			case undefinedTable:
@@ -199,6 +209,10 @@ func namedQuerySlice[T any](ctx context.Context, log *zap.SugaredLogger, db sqlx
 
 	log.WithOptions(zap.AddCallerSkip(3)).Infow("database.NamedQuerySlice", "trace_id", web.GetTraceID(ctx), "query", q)
 
+	ctx, span := tracer.Start(ctx, "database.NamedQuerySlice")
+	span.SetTag("db.statement", query)
+	defer span.End()
+
 	var rows *sqlx.Rows
 	var err error
 
@@ -224,6 +238,7 @@ func namedQuerySlice[T any](ctx context.Context, log *zap.SugaredLogger, db sqlx
 	}
 
 	if err != nil {
+		span.SetError(err)
 		if pqerr, ok := err.(*pgconn.PgError); ok && pqerr.Code == undefinedTable {
 			return ErrUndefinedTable
 		}
@@ -268,6 +283,10 @@ func namedQueryStruct(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtCo
 
 	log.WithOptions(zap.AddCallerSkip(3)).Infow("database.NamedQueryStruct", "trace_id", web.GetTraceID(ctx), "query", q)
 
+	ctx, span := tracer.Start(ctx, "database.NamedQueryStruct")
+	span.SetTag("db.statement", query)
+	defer span.End()
+
 	var rows *sqlx.Rows
 	var err error
 
@@ -293,6 +312,7 @@ func namedQueryStruct(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtCo
 	}
 
 	if err != nil {
+		span.SetError(err)
 		if pqerr, ok := err.(*pgconn.PgError); ok && pqerr.Code == undefinedTable {
 			return ErrUndefinedTable
 		}
@@ -0,0 +1,139 @@
+// Package events provides support for domain events. The core packages write
+// the events of a change to an outbox in the same transaction as the change,
+// and a relay delivers them from the outbox to the subscribers in the process.
+// The relay of a single instance delivers each event, a follower in every
+// instance passes the delivered events to the subscribers that need every
+// event in every instance.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event represents something that happened to an aggregate, like a user or a
+// product. Data holds the JSON encoded payload, which is specific to the type
+// of the event.
+type Event struct {
+	ID          uuid.UUID
+	Aggregate   string
+	AggregateID uuid.UUID
+	Type        string
+	Data        []byte
+	DateCreated time.Time
+}
+
+// New constructs an event of the specified type for the aggregate, encoding
+// the payload.
+func New(aggregate string, aggregateID uuid.UUID, typ string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("marshal: %s: %w", typ, err)
+	}
+
+	ev := Event{
+		ID:          uuid.New(),
+		Aggregate:   aggregate,
+		AggregateID: aggregateID,
+		Type:        typ,
+		Data:        data,
+		DateCreated: time.Now(),
+	}
+
+	return ev, nil
+}
+
+// Decode returns the payload of the event as the type published with it.
+func Decode[T any](ev Event) (T, error) {
+	var payload T
+	if err := json.Unmarshal(ev.Data, &payload); err != nil {
+		return payload, fmt.Errorf("unmarshal: %s: %w", ev.Type, err)
+	}
+
+	return payload, nil
+}
+
+// =============================================================================
+
+// Handler handles an event delivered to a subscriber. Events are delivered at
+// least once, so handlers must tolerate receiving the same event again.
+type Handler func(ctx context.Context, ev Event) error
+
+type subscriber struct {
+	name    string
+	types   map[string]bool
+	handler Handler
+}
+
+// Bus delivers events to the subscribers in the process.
+type Bus struct {
+	mu   sync.RWMutex
+	subs []subscriber
+}
+
+// NewBus constructs a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers the handler for the events of the specified types, or
+// for every event when no types are specified. The name identifies the
+// subscriber in errors.
+func (b *Bus) Subscribe(name string, handler Handler, types ...string) {
+	sub := subscriber{
+		name:    name,
+		handler: handler,
+	}
+
+	if len(types) > 0 {
+		sub.types = make(map[string]bool, len(types))
+		for _, typ := range types {
+			sub.types[typ] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs = append(b.subs, sub)
+}
+
+// Dispatch delivers the event to every subscriber of its type, in the order
+// they subscribed. A failing subscriber doesn't stop the delivery to the
+// others, the errors of all the failing subscribers are returned.
+func (b *Bus) Dispatch(ctx context.Context, ev Event) error {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if sub.types != nil && !sub.types[ev.Type] {
+			continue
+		}
+
+		if err := sub.handle(ctx, ev); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// handle calls the handler, turning a panic into an error so one subscriber
+// can't stop the relay.
+func (s subscriber) handle(ctx context.Context, ev Event) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+
+	return s.handler(ctx, ev)
+}
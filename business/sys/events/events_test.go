@@ -0,0 +1,350 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func Test_Decode(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	ev, err := New("user", uuid.New(), "user.created", payload{Name: "Bill"})
+	if err != nil {
+		t.Fatalf("Should be able to construct an event: %s", err)
+	}
+
+	got, err := Decode[payload](ev)
+	if err != nil {
+		t.Fatalf("Should be able to decode the event: %s", err)
+	}
+
+	if got.Name != "Bill" {
+		t.Errorf("Should decode the payload: got %q", got.Name)
+	}
+}
+
+func Test_Dispatch(t *testing.T) {
+	bus := NewBus()
+
+	var got []string
+	bus.Subscribe("all", func(ctx context.Context, ev Event) error {
+		got = append(got, "all:"+ev.Type)
+		return nil
+	})
+	bus.Subscribe("created", func(ctx context.Context, ev Event) error {
+		got = append(got, "created:"+ev.Type)
+		return nil
+	}, "user.created")
+	bus.Subscribe("broken", func(ctx context.Context, ev Event) error {
+		panic("broken subscriber")
+	}, "user.deleted")
+
+	if err := bus.Dispatch(context.Background(), Event{Type: "user.created"}); err != nil {
+		t.Errorf("Should deliver the event: %s", err)
+	}
+
+	if err := bus.Dispatch(context.Background(), Event{Type: "user.deleted"}); err == nil || err.Error() != "broken: panic: broken subscriber" {
+		t.Errorf("Should return the panic of a subscriber as an error: %v", err)
+	}
+
+	exp := []string{"all:user.created", "created:user.created", "all:user.deleted"}
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Errorf("Should deliver the events to the subscribers of their type:\n%s", diff)
+	}
+}
+
+func Test_Relay(t *testing.T) {
+	user1 := uuid.New()
+	user2 := uuid.New()
+
+	store := outbox{}
+	store.add(t, user1, "user.created")
+	store.add(t, user2, "user.created")
+	store.add(t, user1, "user.updated")
+	store.add(t, user2, "user.updated")
+
+	// The first delivery of the update of user1 fails.
+	failed := false
+
+	var got []string
+	bus := NewBus()
+	bus.Subscribe("test", func(ctx context.Context, ev Event) error {
+		if ev.AggregateID == user1 && ev.Type == "user.updated" && !failed {
+			failed = true
+			return errors.New("unavailable")
+		}
+
+		name := "user1"
+		if ev.AggregateID == user2 {
+			name = "user2"
+		}
+		got = append(got, name+":"+ev.Type)
+
+		return nil
+	})
+
+	relay := NewRelay(zap.NewNop().Sugar(), &store, bus, Config{BatchSize: 10, Retry: Retry{Backoff: time.Nanosecond}})
+
+	n, err := relay.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Should be able to flush the outbox: %s", err)
+	}
+	if n != 4 {
+		t.Errorf("Should read the pending events: got %d", n)
+	}
+
+	// The event that failed is delivered by the next flush.
+	if _, err := relay.Flush(context.Background()); err != nil {
+		t.Fatalf("Should be able to flush the outbox: %s", err)
+	}
+
+	exp := []string{"user1:user.created", "user2:user.created", "user2:user.updated", "user1:user.updated"}
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Errorf("Should deliver the events in order per aggregate:\n%s", diff)
+	}
+
+	if n, _ := relay.Flush(context.Background()); n != 0 {
+		t.Errorf("Should not deliver events again once delivered: got %d", n)
+	}
+}
+
+func Test_RelayHoldsBackAggregate(t *testing.T) {
+	user1 := uuid.New()
+
+	store := outbox{}
+	store.add(t, user1, "user.created")
+	store.add(t, user1, "user.updated")
+	store.add(t, user1, "user.deleted")
+
+	var got []string
+	bus := NewBus()
+	bus.Subscribe("test", func(ctx context.Context, ev Event) error {
+		if ev.Type == "user.updated" {
+			return errors.New("unavailable")
+		}
+		got = append(got, ev.Type)
+		return nil
+	})
+
+	relay := NewRelay(zap.NewNop().Sugar(), &store, bus, Config{Retry: Retry{Backoff: time.Nanosecond}})
+
+	for i := 0; i < 3; i++ {
+		if _, err := relay.Flush(context.Background()); err != nil {
+			t.Fatalf("Should be able to flush the outbox: %s", err)
+		}
+	}
+
+	if diff := cmp.Diff(got, []string{"user.created"}); diff != "" {
+		t.Errorf("Should hold back the events after the failed event:\n%s", diff)
+	}
+
+	if attempts := store.attempts[store.evs[1].ID]; attempts != 3 {
+		t.Errorf("Should retry the failed event on every flush: got %d attempts", attempts)
+	}
+}
+
+func Test_RelayParksFailedEvent(t *testing.T) {
+	user1 := uuid.New()
+	user2 := uuid.New()
+
+	store := outbox{}
+	store.add(t, user1, "user.created")
+	store.add(t, user1, "user.updated")
+	store.add(t, user2, "user.created")
+
+	var got []string
+	bus := NewBus()
+	bus.Subscribe("test", func(ctx context.Context, ev Event) error {
+		if ev.AggregateID == user1 {
+			return errors.New("poison")
+		}
+		got = append(got, ev.Type)
+		return nil
+	})
+
+	// The events of user1 fill the batch until the failed event is parked.
+	relay := NewRelay(zap.NewNop().Sugar(), &store, bus, Config{
+		BatchSize: 2,
+		Retry:     Retry{MaxAttempts: 2, Backoff: time.Nanosecond},
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := relay.Flush(context.Background()); err != nil {
+			t.Fatalf("Should be able to flush the outbox: %s", err)
+		}
+	}
+
+	if diff := cmp.Diff(got, []string{"user.created"}); diff != "" {
+		t.Errorf("Should deliver the events of other aggregates once the failed event is parked:\n%s", diff)
+	}
+
+	if attempts := store.attempts[store.evs[0].ID]; attempts != 2 {
+		t.Errorf("Should stop retrying the failed event after the max attempts: got %d attempts", attempts)
+	}
+
+	if store.delivered[store.evs[1].ID] {
+		t.Errorf("Should keep holding back the events after the parked event.")
+	}
+}
+
+func Test_RetryDelay(t *testing.T) {
+	retry := Retry{MaxAttempts: 10, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	tt := []struct {
+		attempts int
+		exp      time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{40, 5 * time.Second},
+	}
+
+	for _, tst := range tt {
+		if got := retry.Delay(tst.attempts); got != tst.exp {
+			t.Errorf("Should wait %v after %d attempts: got %v", tst.exp, tst.attempts, got)
+		}
+	}
+}
+
+func Test_Follower(t *testing.T) {
+	store := outbox{}
+	store.add(t, uuid.New(), "user.created")
+
+	relay := NewRelay(zap.NewNop().Sugar(), &store, NewBus(), Config{})
+	if _, err := relay.Flush(context.Background()); err != nil {
+		t.Fatalf("Should be able to flush the outbox: %s", err)
+	}
+
+	// Every instance follows the deliveries, whichever instance's relay
+	// delivered them.
+	var got [2][]string
+	var followers [2]*Follower
+	for i := range followers {
+		i := i
+
+		bus := NewBus()
+		bus.Subscribe("test", func(ctx context.Context, ev Event) error {
+			got[i] = append(got[i], ev.Type)
+			return nil
+		})
+
+		followers[i] = NewFollower(zap.NewNop().Sugar(), &store, bus, Config{BatchSize: 1})
+		if n, err := followers[i].Flush(context.Background()); err != nil || n != 0 {
+			t.Fatalf("Should skip the events delivered before starting: got %d, %v", n, err)
+		}
+	}
+
+	store.add(t, uuid.New(), "user.created")
+	store.add(t, uuid.New(), "user.updated")
+
+	if _, err := relay.Flush(context.Background()); err != nil {
+		t.Fatalf("Should be able to flush the outbox: %s", err)
+	}
+
+	for i, f := range followers {
+		for j := 0; j < 3; j++ {
+			if _, err := f.Flush(context.Background()); err != nil {
+				t.Fatalf("Should be able to follow the deliveries: %s", err)
+			}
+		}
+
+		if diff := cmp.Diff(got[i], []string{"user.created", "user.updated"}); diff != "" {
+			t.Errorf("Should pass every delivered event to follower %d once:\n%s", i, diff)
+		}
+	}
+}
+
+// =============================================================================
+
+// outbox is an in-memory Storer and FeedStorer.
+type outbox struct {
+	evs        []Event
+	delivered  map[uuid.UUID]bool
+	attempts   map[uuid.UUID]int
+	retries    map[uuid.UUID]time.Time
+	deliveries []Event
+}
+
+func (o *outbox) add(t *testing.T, aggregateID uuid.UUID, typ string) {
+	ev, err := New("user", aggregateID, typ, struct{}{})
+	if err != nil {
+		t.Fatalf("Should be able to construct an event: %s", err)
+	}
+
+	o.evs = append(o.evs, ev)
+}
+
+func (o *outbox) Claim(ctx context.Context, limit int, retry Retry, deliver func(evs []Event) Result) (int, error) {
+	if o.delivered == nil {
+		o.delivered = make(map[uuid.UUID]bool)
+		o.attempts = make(map[uuid.UUID]int)
+		o.retries = make(map[uuid.UUID]time.Time)
+	}
+
+	now := time.Now()
+	held := make(map[uuid.UUID]bool)
+
+	var pending []Event
+	for _, ev := range o.evs {
+		if o.delivered[ev.ID] {
+			continue
+		}
+
+		if o.attempts[ev.ID] >= retry.MaxAttempts || o.retries[ev.ID].After(now) {
+			held[ev.AggregateID] = true
+		}
+
+		if !held[ev.AggregateID] && len(pending) < limit {
+			pending = append(pending, ev)
+		}
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	res := deliver(pending)
+	for _, id := range res.Delivered {
+		o.delivered[id] = true
+		o.attempts[id]++
+
+		for _, ev := range pending {
+			if ev.ID == id {
+				o.deliveries = append(o.deliveries, ev)
+			}
+		}
+	}
+	for id := range res.Failed {
+		o.attempts[id]++
+		o.retries[id] = now.Add(retry.Delay(o.attempts[id]))
+	}
+
+	return len(pending), nil
+}
+
+func (o *outbox) Purge(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func (o *outbox) LastDelivery(ctx context.Context) (int64, error) {
+	return int64(len(o.deliveries)), nil
+}
+
+func (o *outbox) Delivered(ctx context.Context, after int64, limit int) ([]Event, int64, error) {
+	evs := o.deliveries[after:]
+	if len(evs) > limit {
+		evs = evs[:limit]
+	}
+
+	return evs, after + int64(len(evs)), nil
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FeedStorer interface declares the behavior the follower needs to read the
+// events delivered by the relays.
+type FeedStorer interface {
+	LastDelivery(ctx context.Context) (int64, error)
+	Delivered(ctx context.Context, after int64, limit int) ([]Event, int64, error)
+}
+
+// Follower passes the events delivered by the relay of any instance to the
+// subscribers of a bus in this instance. The relay delivers an event in one
+// instance only, subscribers keeping state per instance, like the clients
+// streaming changes, follow the deliveries instead so every instance receives
+// every event. The follower starts with the events delivered after it starts
+// and failed events aren't retried.
+type Follower struct {
+	log     *zap.SugaredLogger
+	storer  FeedStorer
+	bus     *Bus
+	cfg     Config
+	cursor  int64
+	started bool
+}
+
+// NewFollower constructs a follower passing the delivered events to the bus.
+// Only the BatchSize and PollInterval of the Config are used.
+func NewFollower(log *zap.SugaredLogger, storer FeedStorer, bus *Bus, cfg Config) *Follower {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+
+	return &Follower{
+		log:    log,
+		storer: storer,
+		bus:    bus,
+		cfg:    cfg,
+	}
+}
+
+// Run passes the delivered events to the bus until the context is done.
+// Errors are logged and the read is retried on the next poll.
+func (f *Follower) Run(ctx context.Context) {
+	poll := time.NewTimer(0)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-poll.C:
+		case <-ctx.Done():
+			return
+		}
+
+		n, err := f.Flush(ctx)
+		if err != nil {
+			f.log.Errorw("events", "status", "following delivered events", "ERROR", err)
+		}
+
+		// A full batch means more events are likely waiting.
+		wait := f.cfg.PollInterval
+		if err == nil && n == f.cfg.BatchSize {
+			wait = 0
+		}
+		poll.Reset(wait)
+	}
+}
+
+// Flush passes the next batch of delivered events to the bus and returns the
+// number of events read. The first call only records the last delivery, so
+// events delivered before the follower started are skipped. Flush must not be
+// called concurrently.
+func (f *Follower) Flush(ctx context.Context) (int, error) {
+	if !f.started {
+		last, err := f.storer.LastDelivery(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("last delivery: %w", err)
+		}
+
+		f.cursor = last
+		f.started = true
+	}
+
+	evs, cursor, err := f.storer.Delivered(ctx, f.cursor, f.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("delivered: %w", err)
+	}
+	f.cursor = cursor
+
+	for _, ev := range evs {
+		if err := f.bus.Dispatch(ctx, ev); err != nil {
+			f.log.Errorw("events", "status", "followed event not handled", "event_id", ev.ID, "type", ev.Type, "ERROR", err)
+		}
+	}
+
+	return len(evs), nil
+}
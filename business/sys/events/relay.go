@@ -0,0 +1,208 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Set of default values used when the Config doesn't specify a value.
+const (
+	defaultBatchSize     = 100
+	defaultPollInterval  = time.Second
+	defaultRetention     = 7 * 24 * time.Hour
+	defaultPurgeInterval = time.Hour
+	defaultMaxAttempts   = 20
+	defaultBackoff       = time.Second
+	defaultMaxBackoff    = 5 * time.Minute
+)
+
+// Storer interface declares the behavior the relay needs to read the outbox.
+type Storer interface {
+	Claim(ctx context.Context, limit int, retry Retry, deliver func(evs []Event) Result) (int, error)
+	Purge(ctx context.Context, before time.Time) error
+}
+
+// Retry represents how failed events are retried. A failed event waits
+// Backoff before it is read again, doubled for every further failure up to
+// MaxBackoff, and is parked once it failed MaxAttempts times. The later events
+// of an aggregate stay held back while its failed event waits or is parked,
+// without keeping the events of other aggregates from being read. A parked
+// event is only retried once its attempts are reset in the outbox.
+type Retry struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Delay returns how long an event that failed the specified number of times
+// waits before it is read again.
+func (r Retry) Delay(attempts int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempts && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+
+	return delay
+}
+
+// Result reports the outcome of delivering a batch of events from the outbox.
+// Delivered events are marked as delivered and the failed events are retried.
+type Result struct {
+	Delivered []uuid.UUID
+	Failed    map[uuid.UUID]error
+}
+
+// Config represents the settings of the relay.
+type Config struct {
+
+	// BatchSize is the number of events read from the outbox at once.
+	BatchSize int
+
+	// PollInterval is how long the relay waits for new events once the
+	// outbox is empty.
+	PollInterval time.Duration
+
+	// Retention is how long delivered events are kept in the outbox, they
+	// are purged every PurgeInterval.
+	Retention     time.Duration
+	PurgeInterval time.Duration
+
+	// Retry controls how failed events are retried.
+	Retry Retry
+}
+
+// Relay delivers the events written to the outbox to the subscribers of the
+// bus. An event is only marked as delivered once every subscriber handled it,
+// so events are delivered at least once. The events of an aggregate are
+// delivered in the order they were written and a failed event holds back the
+// later events of its aggregate until it is delivered, see Retry.
+type Relay struct {
+	log    *zap.SugaredLogger
+	storer Storer
+	bus    *Bus
+	cfg    Config
+}
+
+// NewRelay constructs a relay delivering the events of the outbox to the bus.
+func NewRelay(log *zap.SugaredLogger, storer Storer, bus *Bus, cfg Config) *Relay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultRetention
+	}
+	if cfg.PurgeInterval <= 0 {
+		cfg.PurgeInterval = defaultPurgeInterval
+	}
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.Retry.Backoff <= 0 {
+		cfg.Retry.Backoff = defaultBackoff
+	}
+	if cfg.Retry.MaxBackoff <= 0 {
+		cfg.Retry.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.Retry.MaxBackoff < cfg.Retry.Backoff {
+		cfg.Retry.MaxBackoff = cfg.Retry.Backoff
+	}
+
+	return &Relay{
+		log:    log,
+		storer: storer,
+		bus:    bus,
+		cfg:    cfg,
+	}
+}
+
+// Run delivers the events of the outbox until the context is done. Errors are
+// logged and the delivery is retried on the next poll.
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTimer(0)
+	defer poll.Stop()
+
+	purge := time.NewTicker(r.cfg.PurgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-poll.C:
+		case <-purge.C:
+			if err := r.storer.Purge(ctx, time.Now().Add(-r.cfg.Retention)); err != nil {
+				r.log.Errorw("events", "status", "purging delivered events", "ERROR", err)
+			}
+			continue
+		case <-ctx.Done():
+			return
+		}
+
+		n, err := r.Flush(ctx)
+		if err != nil {
+			r.log.Errorw("events", "status", "delivering events", "ERROR", err)
+		}
+
+		// A full batch means more events are likely waiting.
+		wait := r.cfg.PollInterval
+		if err == nil && n == r.cfg.BatchSize {
+			wait = 0
+		}
+		poll.Reset(wait)
+	}
+}
+
+// Flush delivers the next batch of events from the outbox and returns the
+// number of events read. Nothing is read while another relay is delivering.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	n, err := r.storer.Claim(ctx, r.cfg.BatchSize, r.cfg.Retry, func(evs []Event) Result {
+		return r.deliver(ctx, evs)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("claim: %w", err)
+	}
+
+	return n, nil
+}
+
+// deliver dispatches the events in order. Once an event fails, the later
+// events of its aggregate are left in the outbox so they are delivered after
+// it.
+func (r *Relay) deliver(ctx context.Context, evs []Event) Result {
+	res := Result{
+		Failed: make(map[uuid.UUID]error),
+	}
+
+	type aggregate struct {
+		name string
+		id   uuid.UUID
+	}
+	blocked := make(map[aggregate]bool)
+
+	for _, ev := range evs {
+		agg := aggregate{name: ev.Aggregate, id: ev.AggregateID}
+		if blocked[agg] {
+			continue
+		}
+
+		if err := r.bus.Dispatch(ctx, ev); err != nil {
+			r.log.Errorw("events", "status", "event not delivered", "event_id", ev.ID, "type", ev.Type, "ERROR", err)
+			res.Failed[ev.ID] = err
+			blocked[agg] = true
+			continue
+		}
+
+		res.Delivered = append(res.Delivered, ev.ID)
+	}
+
+	return res
+}
@@ -0,0 +1,57 @@
+package outboxdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/qcbit/service/business/sys/events"
+)
+
+// dbEvent represent the structure we need for moving data
+// between the app and the database.
+type dbEvent struct {
+	Sequence      int64          `db:"sequence"`
+	EventID       uuid.UUID      `db:"event_id"`
+	Aggregate     string         `db:"aggregate"`
+	AggregateID   uuid.UUID      `db:"aggregate_id"`
+	Type          string         `db:"event_type"`
+	Data          string         `db:"data"`
+	Attempts      int            `db:"attempts"`
+	LastError     sql.NullString `db:"last_error"`
+	DateRetry     sql.NullTime   `db:"date_retry"`
+	Delivery      sql.NullInt64  `db:"delivery"`
+	DateDelivered sql.NullTime   `db:"date_delivered"`
+	DateCreated   time.Time      `db:"date_created"`
+}
+
+func toDBEvent(ev events.Event) dbEvent {
+	return dbEvent{
+		EventID:     ev.ID,
+		Aggregate:   ev.Aggregate,
+		AggregateID: ev.AggregateID,
+		Type:        ev.Type,
+		Data:        string(ev.Data),
+		DateCreated: ev.DateCreated.UTC(),
+	}
+}
+
+func toCoreEvent(dbEv dbEvent) events.Event {
+	return events.Event{
+		ID:          dbEv.EventID,
+		Aggregate:   dbEv.Aggregate,
+		AggregateID: dbEv.AggregateID,
+		Type:        dbEv.Type,
+		Data:        []byte(dbEv.Data),
+		DateCreated: dbEv.DateCreated.In(time.Local),
+	}
+}
+
+func toCoreEventSlice(dbEvs []dbEvent) []events.Event {
+	evs := make([]events.Event, len(dbEvs))
+	for i, dbEv := range dbEvs {
+		evs[i] = toCoreEvent(dbEv)
+	}
+	return evs
+}
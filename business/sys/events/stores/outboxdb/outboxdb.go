@@ -0,0 +1,311 @@
+// Package outboxdb contains the outbox of the domain events.
+package outboxdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	database "github.com/qcbit/service/business/sys/database/pgx"
+	"github.com/qcbit/service/business/sys/events"
+)
+
+// relayLock is the key of the advisory lock held by the relay delivering the
+// events, so only one instance of the service delivers them at a time and
+// the events of an aggregate stay in order. Holding the lock also numbers the
+// deliveries in commit order, which the followers of every instance rely on.
+const relayLock = 0x6f7574626f78
+
+// Insert writes the events to the outbox. Stores call it with the transaction
+// of the change the events describe, so the events are only delivered when
+// the change is committed. The events must be written after the change so
+// concurrent changes to an aggregate write their events in commit order.
+func Insert(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtContext, evs []events.Event) error {
+	const q = `
+	INSERT INTO outbox
+		(event_id, aggregate, aggregate_id, event_type, data, date_created)
+	VALUES
+		(:event_id, :aggregate, :aggregate_id, :event_type, :data, :date_created)`
+
+	for _, ev := range evs {
+		if err := database.NamedExecContext(ctx, log, db, q, toDBEvent(ev)); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Store manages the set of APIs for outbox database access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the API for data access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// ready is the condition of the events that can be read: pending events of
+// aggregates whose failed event, if any, is due for a retry and isn't parked.
+// A failed event holds back the later events of its aggregate, so it is always
+// the oldest pending event of the aggregate.
+const ready = `
+	o.date_delivered IS NULL AND
+	NOT EXISTS (
+		SELECT
+			1
+		FROM
+			outbox AS f
+		WHERE
+			f.aggregate = o.aggregate AND
+			f.aggregate_id = o.aggregate_id AND
+			f.date_delivered IS NULL AND
+			f.attempts > 0 AND
+			f.sequence <= o.sequence AND
+			(f.attempts >= :max_attempts OR f.date_retry > :now)
+	)`
+
+// Claim reads the oldest events ready for delivery and passes them to the
+// deliver function, then marks the delivered events and records the failures
+// along with the time of their retry. It all happens in one transaction
+// holding the relay lock, when another relay holds the lock nothing is read. A
+// failed commit leaves the events undelivered so they are delivered again.
+func (s *Store) Claim(ctx context.Context, limit int, retry events.Retry, deliver func(evs []events.Event) events.Result) (int, error) {
+	data := struct {
+		MaxAttempts int       `db:"max_attempts"`
+		Now         time.Time `db:"now"`
+		Limit       int       `db:"limit"`
+	}{
+		MaxAttempts: retry.MaxAttempts,
+		Now:         time.Now().UTC(),
+		Limit:       limit,
+	}
+
+	// The relay polls the outbox, so the absence of events ready for delivery
+	// is detected without a transaction and without logging the query.
+	qExists, args, err := sqlx.Named(`SELECT EXISTS (SELECT 1 FROM outbox AS o WHERE `+ready+`)`, data)
+	if err != nil {
+		return 0, fmt.Errorf("named: %w", err)
+	}
+
+	var pending bool
+	if err := s.db.QueryRowContext(ctx, s.db.Rebind(qExists), args...).Scan(&pending); err != nil {
+		return 0, fmt.Errorf("queryrowcontext: %w", err)
+	}
+
+	if !pending {
+		return 0, nil
+	}
+
+	var n int
+
+	f := func(tx *sqlx.Tx) error {
+		lock := struct {
+			Key int64 `db:"key"`
+		}{
+			Key: relayLock,
+		}
+
+		const qLock = `
+		SELECT
+			pg_try_advisory_xact_lock(:key) AS locked`
+
+		var locked struct {
+			Locked bool `db:"locked"`
+		}
+		if err := database.NamedQueryStruct(ctx, s.log, tx, qLock, lock, &locked); err != nil {
+			return fmt.Errorf("namedquerystruct: %w", err)
+		}
+
+		if !locked.Locked {
+			return nil
+		}
+
+		const qPending = `
+		SELECT
+			o.*
+		FROM
+			outbox AS o
+		WHERE` + ready + `
+		ORDER BY
+			o.sequence
+		LIMIT :limit`
+
+		var dbEvs []dbEvent
+		if err := database.NamedQuerySlice(ctx, s.log, tx, qPending, data, &dbEvs); err != nil {
+			return fmt.Errorf("namedqueryslice: %w", err)
+		}
+
+		n = len(dbEvs)
+		if n == 0 {
+			return nil
+		}
+
+		res := deliver(toCoreEventSlice(dbEvs))
+
+		const qDelivered = `
+		UPDATE
+			outbox
+		SET
+			"attempts" = attempts + 1,
+			"last_error" = NULL,
+			"date_retry" = NULL,
+			"delivery" = nextval('outbox_delivery_seq'),
+			"date_delivered" = :date_delivered
+		WHERE
+			event_id = :event_id`
+
+		now := time.Now().UTC()
+		for _, id := range res.Delivered {
+			data := struct {
+				EventID       string    `db:"event_id"`
+				DateDelivered time.Time `db:"date_delivered"`
+			}{
+				EventID:       id.String(),
+				DateDelivered: now,
+			}
+
+			if err := database.NamedExecContext(ctx, s.log, tx, qDelivered, data); err != nil {
+				return fmt.Errorf("namedexeccontext: %w", err)
+			}
+		}
+
+		const qFailed = `
+		UPDATE
+			outbox
+		SET
+			"attempts" = attempts + 1,
+			"last_error" = :last_error,
+			"date_retry" = :date_retry
+		WHERE
+			event_id = :event_id`
+
+		attempts := make(map[uuid.UUID]int, len(dbEvs))
+		for _, dbEv := range dbEvs {
+			attempts[dbEv.EventID] = dbEv.Attempts + 1
+		}
+
+		for id, err := range res.Failed {
+			data := struct {
+				EventID   string    `db:"event_id"`
+				LastError string    `db:"last_error"`
+				DateRetry time.Time `db:"date_retry"`
+			}{
+				EventID:   id.String(),
+				LastError: err.Error(),
+				DateRetry: now.Add(retry.Delay(attempts[id])),
+			}
+
+			if err := database.NamedExecContext(ctx, s.log, tx, qFailed, data); err != nil {
+				return fmt.Errorf("namedexeccontext: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	if err := database.WithinTran(ctx, s.log, s.db, f); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// LastDelivery returns the number of the last delivered event, or zero when
+// no event was delivered.
+func (s *Store) LastDelivery(ctx context.Context) (int64, error) {
+	const q = `
+	SELECT
+		COALESCE(MAX(delivery), 0) AS delivery
+	FROM
+		outbox`
+
+	var last struct {
+		Delivery int64 `db:"delivery"`
+	}
+	if err := database.QueryStruct(ctx, s.log, s.db, q, &last); err != nil {
+		return 0, fmt.Errorf("querystruct: %w", err)
+	}
+
+	return last.Delivery, nil
+}
+
+// Delivered returns the events delivered after the specified delivery in the
+// order they were delivered, along with the number of the last one returned.
+func (s *Store) Delivered(ctx context.Context, after int64, limit int) ([]events.Event, int64, error) {
+
+	// The followers poll the outbox, so the absence of new deliveries is
+	// detected without logging the query.
+	const qExists = `SELECT EXISTS (SELECT 1 FROM outbox WHERE delivery > $1)`
+
+	var delivered bool
+	if err := s.db.QueryRowContext(ctx, qExists, after).Scan(&delivered); err != nil {
+		return nil, 0, fmt.Errorf("queryrowcontext: %w", err)
+	}
+
+	if !delivered {
+		return nil, after, nil
+	}
+
+	data := struct {
+		After int64 `db:"after"`
+		Limit int   `db:"limit"`
+	}{
+		After: after,
+		Limit: limit,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		outbox
+	WHERE
+		delivery > :after
+	ORDER BY
+		delivery
+	LIMIT :limit`
+
+	var dbEvs []dbEvent
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbEvs); err != nil {
+		return nil, 0, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	if len(dbEvs) == 0 {
+		return nil, after, nil
+	}
+
+	return toCoreEventSlice(dbEvs), dbEvs[len(dbEvs)-1].Delivery.Int64, nil
+}
+
+// Purge removes the events delivered before the specified time.
+func (s *Store) Purge(ctx context.Context, before time.Time) error {
+	data := struct {
+		Before time.Time `db:"before"`
+	}{
+		Before: before.UTC(),
+	}
+
+	const q = `
+	DELETE FROM
+		outbox
+	WHERE
+		date_delivered < :before`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package notify provides support for delivering notifications to users.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Message represents a notification to be delivered to a user.
+type Message struct {
+	To      mail.Address
+	Subject string
+	Body    string
+}
+
+// Notifier declares the behavior required to deliver a message. Implementations
+// can deliver through email, SMS or any other channel.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// =============================================================================
+
+// Log is a Notifier that writes messages to the service log. It is intended
+// for development since the message body is logged in the clear.
+type Log struct {
+	log *zap.SugaredLogger
+}
+
+// NewLog constructs a Notifier that writes messages to the log.
+func NewLog(log *zap.SugaredLogger) *Log {
+	return &Log{
+		log: log,
+	}
+}
+
+// Notify writes the message to the log.
+func (l *Log) Notify(ctx context.Context, msg Message) error {
+	l.log.Infow("notify", "to", msg.To.Address, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// =============================================================================
+
+// File is a Notifier that appends messages as JSON documents, one per line,
+// to a local file. It allows notifications to be inspected offline.
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile constructs a Notifier that appends messages to the specified file.
+// The folder holding the file is created if it doesn't exist.
+func NewFile(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating folder: %w", err)
+	}
+
+	return &File{
+		path: path,
+	}, nil
+}
+
+// Notify appends the message to the file.
+func (f *File) Notify(ctx context.Context, msg Message) error {
+	doc := struct {
+		Date    time.Time `json:"date"`
+		To      string    `json:"to"`
+		Subject string    `json:"subject"`
+		Body    string    `json:"body"`
+	}{
+		Date:    time.Now().UTC(),
+		To:      msg.To.String(),
+		Subject: msg.Subject,
+		Body:    msg.Body,
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
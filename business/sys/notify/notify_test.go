@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify", "messages.jsonl")
+
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatalf("Should be able to construct the notifier: %s", err)
+	}
+
+	msgs := []Message{
+		{To: mail.Address{Name: "Bill", Address: "bill@example.com"}, Subject: "first", Body: "line one\nline two"},
+		{To: mail.Address{Address: "ann@example.com"}, Subject: "second", Body: "body"},
+	}
+
+	for _, msg := range msgs {
+		if err := f.Notify(context.Background(), msg); err != nil {
+			t.Fatalf("Should be able to notify: %s", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Should create the file in the missing folder: %s", err)
+	}
+	defer file.Close()
+
+	type doc struct {
+		To      string `json:"to"`
+		Subject string `json:"subject"`
+		Body    string `json:"body"`
+	}
+
+	var got []doc
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var d doc
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			t.Fatalf("Should write one JSON document per line: %s", err)
+		}
+		got = append(got, d)
+	}
+
+	exp := []doc{
+		{To: `"Bill" <bill@example.com>`, Subject: "first", Body: "line one\nline two"},
+		{To: "<ann@example.com>", Subject: "second", Body: "body"},
+	}
+
+	if diff := cmp.Diff(got, exp); diff != "" {
+		t.Errorf("Should append every message in order:\n%s", diff)
+	}
+}
@@ -0,0 +1,131 @@
+package validate
+
+import (
+	"reflect"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// germanMessages are the German error messages of the validation tags, the
+// validator doesn't provide German translations itself.
+var germanMessages = map[string]string{
+	"required":  "{0} ist ein Pflichtfeld",
+	"email":     "{0} muss eine gültige E-Mail-Adresse sein",
+	"eq":        "{0} ist nicht gleich {1}",
+	"ne":        "{0} darf nicht gleich {1} sein",
+	"eqfield":   "{0} muss gleich {1} sein",
+	"nefield":   "{0} darf nicht gleich {1} sein",
+	"oneof":     "{0} muss einer der folgenden Werte sein: {1}",
+	"numeric":   "{0} muss ein gültiger numerischer Wert sein",
+	"number":    "{0} muss eine gültige Zahl sein",
+	"alpha":     "{0} darf nur Buchstaben enthalten",
+	"alphanum":  "{0} darf nur Buchstaben und Ziffern enthalten",
+	"boolean":   "{0} muss ein gültiger boolescher Wert sein",
+	"url":       "{0} muss eine gültige URL sein",
+	"uuid":      "{0} muss eine gültige UUID sein",
+	"uuid4":     "{0} muss eine gültige UUID der Version 4 sein",
+	"datetime":  "{0} entspricht nicht dem Format {1}",
+	"e164":      "{0} muss eine gültige Telefonnummer im E.164-Format sein",
+	"iscolor":   "{0} muss eine gültige Farbe sein",
+	"json":      "{0} muss eine gültige JSON-Zeichenkette sein",
+	"lowercase": "{0} darf nur Kleinbuchstaben enthalten",
+	"uppercase": "{0} darf nur Großbuchstaben enthalten",
+}
+
+// germanSizeMessages are the German messages of the tags comparing a size,
+// which depend on the kind of the field.
+var germanSizeMessages = map[string]map[reflect.Kind]string{
+	"min": {
+		reflect.String: "{0} muss mindestens {1} Zeichen lang sein",
+		reflect.Slice:  "{0} muss mindestens {1} Einträge enthalten",
+		reflect.Int:    "{0} muss {1} oder größer sein",
+	},
+	"max": {
+		reflect.String: "{0} darf höchstens {1} Zeichen lang sein",
+		reflect.Slice:  "{0} darf höchstens {1} Einträge enthalten",
+		reflect.Int:    "{0} muss {1} oder kleiner sein",
+	},
+	"len": {
+		reflect.String: "{0} muss genau {1} Zeichen lang sein",
+		reflect.Slice:  "{0} muss genau {1} Einträge enthalten",
+		reflect.Int:    "{0} muss gleich {1} sein",
+	},
+	"gt": {
+		reflect.String: "{0} muss länger als {1} Zeichen sein",
+		reflect.Slice:  "{0} muss mehr als {1} Einträge enthalten",
+		reflect.Int:    "{0} muss größer als {1} sein",
+	},
+	"gte": {
+		reflect.String: "{0} muss mindestens {1} Zeichen lang sein",
+		reflect.Slice:  "{0} muss mindestens {1} Einträge enthalten",
+		reflect.Int:    "{0} muss {1} oder größer sein",
+	},
+	"lt": {
+		reflect.String: "{0} muss kürzer als {1} Zeichen sein",
+		reflect.Slice:  "{0} muss weniger als {1} Einträge enthalten",
+		reflect.Int:    "{0} muss kleiner als {1} sein",
+	},
+	"lte": {
+		reflect.String: "{0} darf höchstens {1} Zeichen lang sein",
+		reflect.Slice:  "{0} darf höchstens {1} Einträge enthalten",
+		reflect.Int:    "{0} muss {1} oder kleiner sein",
+	},
+}
+
+// registerGermanTranslations registers the German error messages.
+func registerGermanTranslations(v *validator.Validate, trans ut.Translator) {
+	for tag, msg := range germanMessages {
+		tag, msg := tag, msg
+
+		register := func(trans ut.Translator) error {
+			return trans.Add(tag, msg, false)
+		}
+
+		translate := func(trans ut.Translator, fe validator.FieldError) string {
+			t, err := trans.T(tag, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return t
+		}
+
+		v.RegisterTranslation(tag, trans, register, translate)
+	}
+
+	for tag, msgs := range germanSizeMessages {
+		tag, msgs := tag, msgs
+
+		register := func(trans ut.Translator) error {
+			for kind, msg := range msgs {
+				if err := trans.Add(sizeKey(tag, kind), msg, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		translate := func(trans ut.Translator, fe validator.FieldError) string {
+			t, err := trans.T(sizeKey(tag, fe.Kind()), fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return t
+		}
+
+		v.RegisterTranslation(tag, trans, register, translate)
+	}
+}
+
+// sizeKey returns the translation key of a size tag for the kind of field.
+// Every kind that isn't a string or a collection is compared as a number.
+func sizeKey(tag string, kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return tag + "-" + reflect.String.String()
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return tag + "-" + reflect.Slice.String()
+	default:
+		return tag + "-" + reflect.Int.String()
+	}
+}
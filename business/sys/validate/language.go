@@ -0,0 +1,73 @@
+package validate
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when the client accepts none of the supported
+// languages.
+const DefaultLanguage = "en"
+
+// Languages lists the languages error messages are available in.
+var Languages = []string{"en", "es", "de"}
+
+type ctxKey int
+
+const langKey ctxKey = 1
+
+// WithLanguage sets the language of the error messages in the context. The
+// language is negotiated from the value of an Accept-Language header, regional
+// variants such as es-MX fall back to their base language.
+func WithLanguage(ctx context.Context, acceptLanguage string) context.Context {
+	return context.WithValue(ctx, langKey, NegotiateLanguage(acceptLanguage))
+}
+
+// GetLanguage returns the language of the error messages set in the context.
+func GetLanguage(ctx context.Context) string {
+	lang, ok := ctx.Value(langKey).(string)
+	if !ok {
+		return DefaultLanguage
+	}
+	return lang
+}
+
+// NegotiateLanguage returns the supported language the client prefers
+// according to the value of an Accept-Language header.
+func NegotiateLanguage(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		if tag != "" && q > 0 {
+			tags = append(tags, weighted{tag: strings.ToLower(strings.TrimSpace(tag)), q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, w := range tags {
+		base, _, _ := strings.Cut(w.tag, "-")
+		if _, exists := translators[base]; exists {
+			return base
+		}
+	}
+
+	return DefaultLanguage
+}
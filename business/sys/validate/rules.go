@@ -0,0 +1,117 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// Set of validation tags provided by this package.
+const (
+
+	// TagID validates a string holds a UUID in any of the formats accepted
+	// by the uuid package, such as the ID of a path parameter.
+	TagID = "id"
+
+	// TagDateRange is reported by struct rules when the end of a date range
+	// is before its start. The parameter names the start of the range.
+	TagDateRange = "daterange"
+)
+
+// registerRules registers the validators and messages of the tags provided by
+// this package.
+func registerRules() {
+	err := RegisterValidator(TagID, validID, map[string]string{
+		"en": "{0} must be a valid id",
+		"es": "{0} debe ser un id válido",
+		"de": "{0} muss eine gültige ID sein",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = RegisterTranslation(TagDateRange, map[string]string{
+		"en": "{0} must not be before {1}",
+		"es": "{0} no debe ser anterior a {1}",
+		"de": "{0} darf nicht vor {1} liegen",
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RegisterValidator registers a validation tag along with its error messages,
+// keyed by language as with RegisterTranslation. Validators must be
+// registered from an init function, before any value is validated.
+func RegisterValidator(tag string, fn validator.Func, messages map[string]string) error {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		return fmt.Errorf("registering validator %q: %w", tag, err)
+	}
+
+	if err := RegisterTranslation(tag, messages); err != nil {
+		return fmt.Errorf("registering translation %q: %w", tag, err)
+	}
+
+	return nil
+}
+
+// RegisterStructRule registers a rule that validates the struct types as a
+// whole, for checks that span several fields. The rule reports errors with
+// StructLevel.ReportError using a tag whose messages are registered with
+// RegisterTranslation. Rules must be registered from an init function,
+// before any value is validated.
+func RegisterStructRule(rule validator.StructLevelFunc, types ...any) {
+	validate.RegisterStructValidation(rule, types...)
+}
+
+// CheckParam validates a single value that doesn't belong to a model, such as
+// a path parameter, against the validation tags. Errors are reported for the
+// named field.
+func CheckParam(ctx context.Context, name string, value string, tags string) error {
+
+	// The value is validated as the only field of a struct so the errors
+	// carry the name of the parameter.
+	typ := reflect.StructOf([]reflect.StructField{
+		{
+			Name: "Value",
+			Type: reflect.TypeOf(value),
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:%q validate:%q`, name, tags)),
+		},
+	})
+
+	val := reflect.New(typ).Elem()
+	val.Field(0).SetString(value)
+
+	return Check(ctx, val.Interface())
+}
+
+// ParseUUID validates and parses the named parameter as a UUID.
+func ParseUUID(ctx context.Context, name string, value string) (uuid.UUID, error) {
+	if err := CheckParam(ctx, name, value, "required,"+TagID); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return uuid.MustParse(value), nil
+}
+
+// ValidDateRange reports if the end of the range isn't before its start. A
+// range missing either end is valid.
+func ValidDateRange(start *time.Time, end *time.Time) bool {
+	if start == nil || end == nil {
+		return true
+	}
+
+	return !end.Before(*start)
+}
+
+// =============================================================================
+
+// validID validates the field holds a UUID.
+func validID(fl validator.FieldLevel) bool {
+	_, err := uuid.Parse(fl.Field().String())
+	return err == nil
+}
@@ -2,32 +2,44 @@
 package validate
 
 import (
+	"context"
 	"reflect"
 	"strings"
 
+	"github.com/go-playground/locales/de"
 	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
 )
 
 // validate holds the settings and caches for validating request struct values.
 var validate *validator.Validate
 
-// translator is a cache of locale and translation information.
-var translator ut.Translator
+// translators holds the translator of every supported language, keyed by the
+// language code.
+var translators map[string]ut.Translator
 
 func init() {
 
 	// Instantiate a validator.
 	validate = validator.New()
 
-	// Create a translator for english so the error messages are
-	// more human-readable than technical.
-	translator, _ = ut.New(en.New(), en.New()).GetTranslator("en")
+	// Create a translator for every supported language so the error
+	// messages are more human-readable than technical.
+	uni := ut.New(en.New(), en.New(), es.New(), de.New())
 
-	// Register the english error messages for use.
-	en_translations.RegisterDefaultTranslations(validate, translator)
+	translators = make(map[string]ut.Translator)
+	for _, lang := range Languages {
+		translators[lang], _ = uni.GetTranslator(lang)
+	}
+
+	// Register the error messages of every language for use.
+	en_translations.RegisterDefaultTranslations(validate, translators["en"])
+	es_translations.RegisterDefaultTranslations(validate, translators["es"])
+	registerGermanTranslations(validate, translators["de"])
 
 	// Use JSON tag names for errors instead of Go struct names.
 	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -37,10 +49,13 @@ func init() {
 		}
 		return name
 	})
+
+	registerRules()
 }
 
-// Check validates the provided model against it's declared tags.
-func Check(val any) error {
+// Check validates the provided model against it's declared tags. The error
+// messages are in the language set in the context by WithLanguage.
+func Check(ctx context.Context, val any) error {
 	if err := validate.Struct(val); err != nil {
 
 		// Use a type assertion to get the real error value.
@@ -49,6 +64,8 @@ func Check(val any) error {
 			return err
 		}
 
+		translator := translators[GetLanguage(ctx)]
+
 		var fields FieldErrors
 		for _, verror := range verrors {
 			field := FieldError{
@@ -63,3 +80,33 @@ func Check(val any) error {
 
 	return nil
 }
+
+// RegisterTranslation registers the error messages of a validation tag, keyed
+// by language. A message can refer to the field with {0} and to the parameter
+// of the tag with {1}. Languages without a message use the English one.
+func RegisterTranslation(tag string, messages map[string]string) error {
+	for _, lang := range Languages {
+		msg, exists := messages[lang]
+		if !exists {
+			msg = messages[DefaultLanguage]
+		}
+
+		register := func(trans ut.Translator) error {
+			return trans.Add(tag, msg, true)
+		}
+
+		translate := func(trans ut.Translator, fe validator.FieldError) string {
+			t, err := trans.T(tag, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return t
+		}
+
+		if err := validate.RegisterTranslation(tag, translators[lang], register, translate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
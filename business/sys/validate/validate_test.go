@@ -0,0 +1,157 @@
+package validate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+type person struct {
+	Name  string   `json:"name" validate:"required"`
+	Email string   `json:"email" validate:"omitempty,email"`
+	Roles []string `json:"roles" validate:"required,min=1"`
+}
+
+func Test_NegotiateLanguage(t *testing.T) {
+	tt := []struct {
+		header string
+		exp    string
+	}{
+		{header: "", exp: "en"},
+		{header: "es-MX,es;q=0.9,en;q=0.8", exp: "es"},
+		{header: "fr-FR, de;q=0.7, en;q=0.5", exp: "de"},
+		{header: "en;q=0.2, DE-at;q=0.9", exp: "de"},
+		{header: "fr, it", exp: "en"},
+		{header: "es;q=0", exp: "en"},
+	}
+
+	for _, tst := range tt {
+		if got := NegotiateLanguage(tst.header); got != tst.exp {
+			t.Errorf("Should negotiate the language of %q: got %s, exp %s", tst.header, got, tst.exp)
+		}
+	}
+}
+
+func Test_CheckLanguage(t *testing.T) {
+	p := person{Email: "bill", Roles: []string{}}
+
+	tt := []struct {
+		lang string
+		exp  map[string]string
+	}{
+		{lang: "en", exp: map[string]string{"name": "name is a required field", "email": "email must be a valid email address", "roles": "roles must contain at least 1 item"}},
+		{lang: "es", exp: map[string]string{"name": "name es un campo requerido", "email": "email debe ser una dirección de correo electrónico válida", "roles": "roles debe contener al menos 1 elemento"}},
+		{lang: "de", exp: map[string]string{"name": "name ist ein Pflichtfeld", "email": "email muss eine gültige E-Mail-Adresse sein", "roles": "roles muss mindestens 1 Einträge enthalten"}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.lang, func(t *testing.T) {
+			err := Check(WithLanguage(context.Background(), tst.lang), p)
+
+			fields := GetFieldErrors(err).Fields()
+			for field, exp := range tst.exp {
+				if fields[field] != exp {
+					t.Errorf("Should translate the %s error: got %q, exp %q", field, fields[field], exp)
+				}
+			}
+		})
+	}
+}
+
+func Test_RegisterTranslation(t *testing.T) {
+	type widget struct {
+		Code string `json:"code" validate:"required,excludesall=!"`
+	}
+
+	err := RegisterTranslation("excludesall", map[string]string{
+		"en": "{0} can't contain {1}",
+		"de": "{0} darf {1} nicht enthalten",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to register the translation: %s", err)
+	}
+
+	for lang, exp := range map[string]string{"en": "code can't contain !", "es": "code can't contain !", "de": "code darf ! nicht enthalten"} {
+		err := Check(WithLanguage(context.Background(), lang), widget{Code: "a!"})
+		if got := GetFieldErrors(err).Fields()["code"]; got != exp {
+			t.Errorf("Should use the registered %s message: got %q, exp %q", lang, got, exp)
+		}
+	}
+}
+
+func Test_RegisterValidator(t *testing.T) {
+	type shape struct {
+		Kind  string     `json:"kind" validate:"required,shape"`
+		Start *time.Time `json:"start"`
+		End   *time.Time `json:"end"`
+	}
+
+	err := RegisterValidator("shape", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() == "circle" || fl.Field().String() == "square"
+	}, map[string]string{
+		"en": "{0} must be a known shape",
+		"es": "{0} debe ser una forma conocida",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to register the validator: %s", err)
+	}
+
+	RegisterStructRule(func(sl validator.StructLevel) {
+		s := sl.Current().Interface().(shape)
+		if !ValidDateRange(s.Start, s.End) {
+			sl.ReportError(s.End, "end", "End", TagDateRange, "start")
+		}
+	}, shape{})
+
+	now := time.Now()
+	before := now.Add(-time.Hour)
+
+	tt := []struct {
+		name string
+		lang string
+		val  shape
+		exp  map[string]string
+	}{
+		{name: "valid", lang: "en", val: shape{Kind: "circle", Start: &before, End: &now}, exp: map[string]string{}},
+		{name: "open", lang: "en", val: shape{Kind: "square", End: &before}, exp: map[string]string{}},
+		{name: "kind", lang: "es", val: shape{Kind: "line"}, exp: map[string]string{"kind": "kind debe ser una forma conocida"}},
+		{name: "fallback", lang: "de", val: shape{Kind: "line"}, exp: map[string]string{"kind": "kind must be a known shape"}},
+		{name: "range", lang: "en", val: shape{Kind: "circle", Start: &now, End: &before}, exp: map[string]string{"end": "end must not be before start"}},
+		{name: "rangede", lang: "de", val: shape{Kind: "circle", Start: &now, End: &before}, exp: map[string]string{"end": "end darf nicht vor start liegen"}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			err := Check(WithLanguage(context.Background(), tst.lang), tst.val)
+
+			fields := GetFieldErrors(err).Fields()
+			if diff := cmp.Diff(fields, tst.exp); diff != "" {
+				t.Errorf("Should report the expected errors:\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ParseUUID(t *testing.T) {
+	id := uuid.New()
+
+	got, err := ParseUUID(context.Background(), "user_id", strings.ToUpper(id.String()))
+	if err != nil {
+		t.Fatalf("Should be able to parse the id: %s", err)
+	}
+	if got != id {
+		t.Errorf("Should get back the id: got %s, exp %s", got, id)
+	}
+
+	for value, exp := range map[string]string{"": "user_id is a required field", "123": "user_id must be a valid id"} {
+		_, err := ParseUUID(context.Background(), "user_id", value)
+		if got := GetFieldErrors(err).Fields()["user_id"]; got != exp {
+			t.Errorf("Should reject %q: got %q, exp %q", value, got, exp)
+		}
+	}
+}
@@ -7,23 +7,47 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
 	"go.uber.org/zap"
 
+	"github.com/qcbit/service/business/core/apikey"
 	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/foundation/tracer"
 )
 
 // ErrForbidden is returned when a user is not authorized to perform an action.
 var ErrForbidden = errors.New("attempted action is not allowed")
 
-// Claims represents the authorization claims transmitted via a JWT.
+// ErrMFARequired is returned when the policy requires the user to have
+// authenticated with a second factor and they haven't.
+var ErrMFARequired = errors.New("multi-factor authentication is required")
+
+// ErrAPIKeysUnsupported is returned when an api key is provided but auth was
+// constructed without a lookup for api keys.
+var ErrAPIKeysUnsupported = errors.New("api keys are not supported")
+
+// Set of authentication methods recorded in the amr claim.
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRAPIKey   = "apikey"
+)
+
+// Claims represents the authorization claims transmitted via a JWT. The AMR
+// field lists the methods used to authenticate the subject.
 type Claims struct {
 	jwt.RegisteredClaims
 	Roles []user.Role `json:"roles"`
+	AMR   []string    `json:"amr,omitempty"`
 }
 
 // KeyLookup declares a method set of behavior for looking up
@@ -34,39 +58,103 @@ type KeyLookup interface {
 	PublicKey(kid string) (pem string, err error)
 }
 
-// Config represents information required to initialize auth.
+// APIKeyLookup declares a method set of behavior for resolving the API key
+// presented by a client into the key it represents.
+type APIKeyLookup interface {
+	Authenticate(ctx context.Context, key string) (apikey.Key, error)
+	QueryByID(ctx context.Context, keyID uuid.UUID) (apikey.Key, error)
+}
+
+// PermissionLookup declares a method set of behavior for resolving the set of
+// permissions granted by a set of roles.
+type PermissionLookup interface {
+	Permissions(ctx context.Context, roles []user.Role) ([]string, error)
+}
+
+// UserLookup declares a method set of behavior for retrieving the user a
+// token was issued to.
+type UserLookup interface {
+	QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error)
+}
+
+// Config represents information required to initialize auth. When a
+// PolicyFolder is provided, the rego documents found there override the
+// embedded policies. API keys are only accepted when an APIKeyLookup is
+// provided and permissions are only available to the policies when a
+// PermissionLookup is provided. When a UserLookup is provided, tokens for
+// disabled users or revoked sessions are rejected.
 type Config struct {
-	Log       *zap.SugaredLogger
-	KeyLookup KeyLookup
-	Issuer    string
+	Log              *zap.SugaredLogger
+	KeyLookup        KeyLookup
+	APIKeyLookup     APIKeyLookup
+	PermissionLookup PermissionLookup
+	UserLookup       UserLookup
+	Issuer           string
+	PolicyFolder     string
 }
 
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 type Auth struct {
-	log       *zap.SugaredLogger
-	keyLookup KeyLookup
-	method    jwt.SigningMethod
-	parser    *jwt.Parser
-	issuer    string
-	mu        sync.RWMutex
-	cache     map[string]string
+	log              *zap.SugaredLogger
+	keyLookup        KeyLookup
+	apiKeyLookup     APIKeyLookup
+	permissionLookup PermissionLookup
+	userLookup       UserLookup
+	method           jwt.SigningMethod
+	parser           *jwt.Parser
+	issuer           string
+	mu               sync.RWMutex
+	cache            map[string]string
+
+	policyFolder string
+	policy       atomic.Pointer[policy]
+	policyMu     sync.Mutex
+	policyStatus PolicyStatus
 }
 
 // New creates an Auth to support authentication/authorization.
 func New(cfg Config) (*Auth, error) {
 	a := Auth{
-		log:       cfg.Log,
-		keyLookup: cfg.KeyLookup,
-		method:    jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
-		parser:    jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
-		issuer:    cfg.Issuer,
-		cache:     make(map[string]string),
+		log:              cfg.Log,
+		keyLookup:        cfg.KeyLookup,
+		apiKeyLookup:     cfg.APIKeyLookup,
+		permissionLookup: cfg.PermissionLookup,
+		userLookup:       cfg.UserLookup,
+		method:           jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
+		parser:           jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
+		issuer:           cfg.Issuer,
+		cache:            make(map[string]string),
+
+		policyFolder: cfg.PolicyFolder,
+	}
+
+	source, authentication, authorization, err := readPolicy(cfg.PolicyFolder)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy: %w", err)
+	}
+
+	p, err := compilePolicy(source, authentication, authorization)
+	if err != nil {
+		return nil, fmt.Errorf("compiling policy: %w", err)
+	}
+
+	a.policy.Store(p)
+	a.policyStatus = PolicyStatus{
+		Source:      p.source,
+		Hash:        p.hash,
+		LoadedAt:    p.loadedAt,
+		LastAttempt: p.loadedAt,
 	}
 
 	return &a, nil
 }
 
+// Issuer returns the issuer tokens are generated and validated for.
+func (a *Auth) Issuer() string {
+	return a.issuer
+}
+
 // GenerateToken generates a signed JWT token string representing the user Claims.
 func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
 	token := jwt.NewWithClaims(a.method, claims)
@@ -126,34 +214,236 @@ func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, er
 		"ISS":   a.issuer,
 	}
 
-	if err := a.opaPolicyEvaluation(ctx, opaAuthentication, RuleAuthenticate, input); err != nil {
+	if err := a.opaPolicyEvaluation(ctx, a.policy.Load().authentication, RuleAuthenticate, input); err != nil {
 		return Claims{}, fmt.Errorf("authentication failed : %w", err)
 	}
 
-	// Check the database for this user to verify they are still enabled.
+	// Check the database for this user to verify they are still enabled and
+	// the session hasn't been revoked.
+
+	if err := a.checkUser(ctx, claims); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// checkUser verifies the user the claims were issued to is still enabled and
+// the session represented by the claims hasn't been revoked.
+func (a *Auth) checkUser(ctx context.Context, claims Claims) error {
+	if a.userLookup == nil {
+		return nil
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return fmt.Errorf("parsing subject: %w", err)
+	}
+
+	usr, err := a.userLookup.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("query user: %w", err)
+	}
+
+	if !usr.Enabled {
+		return errors.New("user disabled")
+	}
+
+	// API keys aren't sessions, they are revoked on their own.
+	if hasAMR(claims, AMRAPIKey) {
+		return nil
+	}
+
+	if claims.IssuedAt == nil || usr.SessionRevoked(claims.IssuedAt.Time) {
+		return errors.New("session revoked")
+	}
+
+	return nil
+}
+
+// Revalidate verifies the claims of an authenticated request are still valid,
+// for requests that outlive their authentication like streams. The claims
+// must not have expired, the user must still be enabled and the session or
+// API key the claims came from must not have been revoked.
+func (a *Auth) Revalidate(ctx context.Context, claims Claims) error {
+	if claims.ExpiresAt != nil && !time.Now().Before(claims.ExpiresAt.Time) {
+		return errors.New("claims expired")
+	}
+
+	if hasAMR(claims, AMRAPIKey) {
+		if a.apiKeyLookup == nil {
+			return ErrAPIKeysUnsupported
+		}
+
+		keyID, err := uuid.Parse(claims.ID)
+		if err != nil {
+			return fmt.Errorf("parsing key id: %w", err)
+		}
+
+		k, err := a.apiKeyLookup.QueryByID(ctx, keyID)
+		if err != nil {
+			return fmt.Errorf("query api key: %w", err)
+		}
+
+		if k.Revoked() {
+			return errors.New("api key revoked")
+		}
+	}
+
+	return a.checkUser(ctx, claims)
+}
+
+// AuthenticateAPIKey validates the API key presented by a client and returns
+// the claims it represents. The claims have the same shape as the claims of a
+// JWT so the rest of the system doesn't need to know how the caller
+// authenticated.
+func (a *Auth) AuthenticateAPIKey(ctx context.Context, key string) (Claims, error) {
+	if a.apiKeyLookup == nil {
+		return Claims{}, ErrAPIKeysUnsupported
+	}
+
+	k, err := a.apiKeyLookup.Authenticate(ctx, key)
+	if err != nil {
+		return Claims{}, fmt.Errorf("authenticating api key: %w", err)
+	}
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        k.ID.String(),
+			Subject:   k.UserID.String(),
+			Issuer:    a.issuer,
+			ExpiresAt: jwt.NewNumericDate(k.DateExpires),
+			IssuedAt:  jwt.NewNumericDate(k.DateCreated),
+		},
+		Roles: k.Roles,
+		AMR:   []string{AMRAPIKey},
+	}
 
 	return claims, nil
 }
 
 // Authorize attempts to authorize the user with the provided input roles, if
 // none of the input roles are within the user's claims, we return an error
-// otherwise the user is authorized.
+// otherwise the user is authorized. The permissions granted by the roles are
+// also provided to the policy so rules can check permissions instead of role
+// names. Users with a role the policy requires mfa for must have
+// authenticated with a second factor.
 func (a *Auth) Authorize(ctx context.Context, claims Claims, rule string) error {
-	input := map[string]any{
-		"Roles":   claims.Roles,
-		"Subject": claims.Subject,
-		"UserID":  claims.Subject,
+	input, err := a.authorizationInput(ctx, claims)
+	if err != nil {
+		return err
+	}
+
+	return a.authorize(ctx, rule, input)
+}
+
+// AuthorizePermission attempts to authorize the user by checking the roles
+// in the user's claims grant the specified permission.
+func (a *Auth) AuthorizePermission(ctx context.Context, claims Claims, permission string) error {
+	input, err := a.authorizationInput(ctx, claims)
+	if err != nil {
+		return err
+	}
+	input["Permission"] = permission
+
+	return a.authorize(ctx, RulePermission, input)
+}
+
+// authorize evaluates the mfa rule and then the specified rule. Both are
+// evaluated against the same policy, so a reload in between can't decide the
+// request with two different policies.
+func (a *Auth) authorize(ctx context.Context, rule string, input map[string]any) error {
+	authorization := a.policy.Load().authorization
+
+	if err := a.opaPolicyEvaluation(ctx, authorization, RuleMFA, input); err != nil {
+		return fmt.Errorf("%w: %s", ErrMFARequired, err)
 	}
 
-	if err := a.opaPolicyEvaluation(ctx, opaAuthorization, rule, input); err != nil {
+	if err := a.opaPolicyEvaluation(ctx, authorization, rule, input); err != nil {
 		return fmt.Errorf("rego evaluation failed : %w", err)
 	}
 
 	return nil
 }
 
+// ReloadPolicies reads the policy folder, validates and compiles the policies
+// it finds and swaps them in when they differ from the active policies. The
+// active policies are left untouched when an error is returned, so a broken
+// policy never replaces a working one. The boolean reports if a swap occurred.
+func (a *Auth) ReloadPolicies() (bool, error) {
+	a.policyMu.Lock()
+	defer a.policyMu.Unlock()
+
+	a.policyStatus.LastAttempt = time.Now()
+
+	reload := func() (bool, error) {
+		source, authentication, authorization, err := readPolicy(a.policyFolder)
+		if err != nil {
+			return false, fmt.Errorf("reading policy: %w", err)
+		}
+
+		if policyHash(authentication, authorization) == a.policy.Load().hash {
+			return false, nil
+		}
+
+		p, err := compilePolicy(source, authentication, authorization)
+		if err != nil {
+			return false, fmt.Errorf("compiling policy: %w", err)
+		}
+
+		a.policy.Store(p)
+
+		a.policyStatus.Source = p.source
+		a.policyStatus.Hash = p.hash
+		a.policyStatus.LoadedAt = p.loadedAt
+
+		return true, nil
+	}
+
+	swapped, err := reload()
+	if err != nil {
+		a.policyStatus.LastError = err.Error()
+		return false, err
+	}
+	a.policyStatus.LastError = ""
+
+	return swapped, nil
+}
+
+// PolicyStatus returns information about the active policies and the last
+// attempt to reload them.
+func (a *Auth) PolicyStatus() PolicyStatus {
+	a.policyMu.Lock()
+	defer a.policyMu.Unlock()
+
+	return a.policyStatus
+}
+
 // =============================================================================
 
+// authorizationInput constructs the input provided to the authorization
+// policy for the specified claims.
+func (a *Auth) authorizationInput(ctx context.Context, claims Claims) (map[string]any, error) {
+	permissions := []string{}
+	if a.permissionLookup != nil {
+		perms, err := a.permissionLookup.Permissions(ctx, claims.Roles)
+		if err != nil {
+			return nil, fmt.Errorf("permissions lookup: %w", err)
+		}
+		permissions = perms
+	}
+
+	input := map[string]any{
+		"Roles":       claims.Roles,
+		"Permissions": permissions,
+		"Subject":     claims.Subject,
+		"UserID":      claims.Subject,
+		"AMR":         claims.AMR,
+	}
+
+	return input, nil
+}
+
 // publicKeyLookup performs a lookup for the public pem for the specified kid.
 func (a *Auth) publicKeyLookup(kid string) (string, error) {
 	pem, err := func() (string, error) {
@@ -182,14 +472,18 @@ func (a *Auth) publicKeyLookup(kid string) (string, error) {
 	return pem, nil
 }
 
-// opaPolicyEvaluation asks opa to evaulate the token against the specified token
-// policy and public key.
-func (a *Auth) opaPolicyEvaluation(ctx context.Context, opaPolicy string, rule string, input any) error {
+// opaPolicyEvaluation asks opa to evaulate the token against the specified
+// compiled policy and public key.
+func (a *Auth) opaPolicyEvaluation(ctx context.Context, compiler *ast.Compiler, rule string, input any) error {
 	query := fmt.Sprintf("x = data.%s.%s", opaPackage, rule)
 
+	ctx, span := tracer.Start(ctx, "auth.opaPolicyEvaluation")
+	span.SetTag("rule", rule)
+	defer span.End()
+
 	q, err := rego.New(
 		rego.Query(query),
-		rego.Module("policy.rego", opaPolicy),
+		rego.Compiler(compiler),
 	).PrepareForEval(ctx)
 	if err != nil {
 		return err
@@ -197,6 +491,7 @@ func (a *Auth) opaPolicyEvaluation(ctx context.Context, opaPolicy string, rule s
 
 	results, err := q.Eval(ctx, rego.EvalInput(input))
 	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("query: %w", err)
 	}
 
@@ -205,9 +500,21 @@ func (a *Auth) opaPolicyEvaluation(ctx context.Context, opaPolicy string, rule s
 	}
 
 	result, ok := results[0].Bindings["x"].(bool)
+	span.SetTag("result", strconv.FormatBool(ok && result))
 	if !ok || !result {
 		return fmt.Errorf("bindings results[%v] ok[%v]", results, ok)
 	}
 
 	return nil
 }
+
+// hasAMR reports if the claims were authenticated with the method.
+func hasAMR(claims Claims, method string) bool {
+	for _, amr := range claims.AMR {
+		if amr == method {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/user"
+)
+
+func Test_Revalidate(t *testing.T) {
+	now := time.Now()
+	userID := uuid.New()
+	keyID := uuid.New()
+
+	session := func(issuedAt time.Time, expiresAt time.Time) Claims {
+		return Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   userID.String(),
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+			AMR: []string{AMRPassword},
+		}
+	}
+
+	key := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        keyID.String(),
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now.Add(-48 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		AMR: []string{AMRAPIKey},
+	}
+
+	tt := []struct {
+		name   string
+		claims Claims
+		usr    user.User
+		key    apikey.Key
+		valid  bool
+	}{
+		{"session", session(now.Add(-time.Minute), now.Add(time.Hour)), user.User{Enabled: true}, apikey.Key{}, true},
+		{"expired", session(now.Add(-2*time.Hour), now.Add(-time.Hour)), user.User{Enabled: true}, apikey.Key{}, false},
+		{"disabled", session(now.Add(-time.Minute), now.Add(time.Hour)), user.User{}, apikey.Key{}, false},
+		{"revoked", session(now.Add(-time.Minute), now.Add(time.Hour)), user.User{Enabled: true, DateSessionsRevoked: now}, apikey.Key{}, false},
+		{"apikey", key, user.User{Enabled: true, DateSessionsRevoked: now}, apikey.Key{}, true},
+		{"apikeyrevoked", key, user.User{Enabled: true}, apikey.Key{DateRevoked: now}, false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			a, err := New(Config{
+				Log:          zap.NewNop().Sugar(),
+				UserLookup:   userLookup(tst.usr),
+				APIKeyLookup: keyLookup(tst.key),
+			})
+			if err != nil {
+				t.Fatalf("Should be able to construct auth: %s", err)
+			}
+
+			err = a.Revalidate(context.Background(), tst.claims)
+			if (err == nil) != tst.valid {
+				t.Errorf("Should report the claims valid %t: got %v", tst.valid, err)
+			}
+		})
+	}
+}
+
+func Test_ReloadPolicies(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	a, err := New(Config{
+		Log:          zap.NewNop().Sugar(),
+		PolicyFolder: dir,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	status := a.PolicyStatus()
+	if status.Source != dir || status.Hash != policyHash(opaAuthentication, opaAuthorization) {
+		t.Errorf("Should fall back to the embedded documents for a folder without policies: got %s %s", status.Source, status.Hash)
+	}
+
+	// The auditor isn't an admin, the reloaded policy lets it through.
+	auditor := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "auditor"},
+		Roles:            []user.Role{user.RoleUser},
+	}
+
+	if err := a.Authorize(ctx, auditor, RuleAdminOnly); err == nil {
+		t.Errorf("Should deny the auditor with the embedded policy.")
+	}
+
+	write := func(authorization string) {
+		if err := os.WriteFile(filepath.Join(dir, policyFileAuthorization), []byte(authorization), 0600); err != nil {
+			t.Fatalf("Should be able to write the policy: %s", err)
+		}
+	}
+
+	policy := opaAuthorization + "\nruleAdminOnly {\n\tinput.Subject == \"auditor\"\n}\n"
+	write(policy)
+
+	swapped, err := a.ReloadPolicies()
+	if err != nil || !swapped {
+		t.Fatalf("Should swap in the valid policy: swapped %t, err %v", swapped, err)
+	}
+
+	loaded := a.PolicyStatus()
+	if loaded.Hash != policyHash(opaAuthentication, policy) || loaded.LastError != "" || loaded.LoadedAt.Before(status.LoadedAt) {
+		t.Errorf("Should report the reloaded policy: %+v", loaded)
+	}
+
+	if err := a.Authorize(ctx, auditor, RuleAdminOnly); err != nil {
+		t.Errorf("Should allow the auditor with the reloaded policy: %s", err)
+	}
+
+	if swapped, err := a.ReloadPolicies(); err != nil || swapped {
+		t.Errorf("Should not swap an unchanged policy: swapped %t, err %v", swapped, err)
+	}
+
+	// Broken policies never replace the active policy.
+	broken := []string{
+		"package qcbit.rego\n\nruleAny {",
+		"package qcbit.rego\n\nruleAny = true\n",
+	}
+
+	for _, doc := range broken {
+		write(doc)
+
+		if swapped, err := a.ReloadPolicies(); err == nil || swapped {
+			t.Errorf("Should reject the broken policy: swapped %t, err %v", swapped, err)
+		}
+
+		got := a.PolicyStatus()
+		if got.Hash != loaded.Hash || !got.LoadedAt.Equal(loaded.LoadedAt) || got.LastError == "" || got.LastAttempt.Before(loaded.LastAttempt) {
+			t.Errorf("Should keep reporting the active policy along with the failure: %+v", got)
+		}
+
+		if err := a.Authorize(ctx, auditor, RuleAdminOnly); err != nil {
+			t.Errorf("Should keep the decisions of the active policy: %s", err)
+		}
+		if err := a.Authorize(ctx, auditor, RuleUserOnly); err != nil {
+			t.Errorf("Should keep the decisions of the active policy: %s", err)
+		}
+	}
+
+	write(policy)
+
+	if _, err := a.ReloadPolicies(); err != nil {
+		t.Errorf("Should reload the repaired policy: %s", err)
+	}
+
+	if got := a.PolicyStatus(); got.LastError != "" || got.Hash != loaded.Hash {
+		t.Errorf("Should clear the failure once the policy is repaired: %+v", got)
+	}
+}
+
+// =============================================================================
+
+type userLookup user.User
+
+func (u userLookup) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	return user.User(u), nil
+}
+
+type keyLookup apikey.Key
+
+func (k keyLookup) Authenticate(ctx context.Context, key string) (apikey.Key, error) {
+	return apikey.Key{}, errors.New("not supported")
+}
+
+func (k keyLookup) QueryByID(ctx context.Context, keyID uuid.UUID) (apikey.Key, error) {
+	return apikey.Key(k), nil
+}
@@ -5,16 +5,37 @@ import (
 	"fmt"
 )
 
+// Set of codes identifying why a request failed authentication or
+// authorization. The codes are stable so clients can act on them.
+const (
+	CodeUnauthenticated    = "unauthenticated"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeMFARequired        = "mfa_required"
+	CodeAccountDisabled    = "account_disabled"
+	CodeForbidden          = "forbidden"
+)
+
 // AuthError is used to pass an error during the request through the
 // application with auth specific context.
 type AuthError struct {
-	msg string
+	msg  string
+	code string
 }
 
 // NewAuthError creates an AuthError for the provided message.
 func NewAuthError(format string, args ...any) error {
 	return &AuthError{
-		msg: fmt.Sprintf(format, args...),
+		msg:  fmt.Sprintf(format, args...),
+		code: CodeUnauthenticated,
+	}
+}
+
+// NewAuthErrorWithCode creates an AuthError with the code identifying the
+// reason of the failure.
+func NewAuthErrorWithCode(code string, format string, args ...any) error {
+	return &AuthError{
+		msg:  fmt.Sprintf(format, args...),
+		code: code,
 	}
 }
 
@@ -24,8 +45,22 @@ func (ae *AuthError) Error() string {
 	return ae.msg
 }
 
+// Code returns the code identifying the reason of the failure.
+func (ae *AuthError) Code() string {
+	return ae.code
+}
+
 // IsAuthError checks if an error of type AuthError exists.
 func IsAuthError(err error) bool {
 	var ae *AuthError
 	return errors.As(err, &ae)
 }
+
+// GetAuthError returns a copy of the AuthError pointer.
+func GetAuthError(err error) *AuthError {
+	var ae *AuthError
+	if !errors.As(err, &ae) {
+		return nil
+	}
+	return ae
+}
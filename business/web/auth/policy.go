@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// Set of file names looked for inside a policy folder. A file that is not
+// present in the folder falls back to the embedded policy.
+const (
+	policyFileAuthentication = "authentication.rego"
+	policyFileAuthorization  = "authorization.rego"
+)
+
+// Set of sources a policy can be loaded from.
+const (
+	policySourceEmbedded = "embedded"
+)
+
+// PolicyStatus describes the policies currently in use and the outcome of the
+// last attempt to reload them.
+type PolicyStatus struct {
+	Source      string
+	Hash        string
+	LoadedAt    time.Time
+	LastAttempt time.Time
+	LastError   string
+}
+
+// policy represents a validated and compiled set of rego policies that is
+// ready for evaluation. A policy value is never modified once constructed.
+type policy struct {
+	authentication *ast.Compiler
+	authorization  *ast.Compiler
+	source         string
+	hash           string
+	loadedAt       time.Time
+}
+
+// readPolicy reads the rego documents for the specified folder. If the folder
+// is empty the embedded documents are returned.
+func readPolicy(folder string) (source string, authentication string, authorization string, err error) {
+	if folder == "" {
+		return policySourceEmbedded, opaAuthentication, opaAuthorization, nil
+	}
+
+	info, err := os.Stat(folder)
+	if err != nil {
+		return "", "", "", fmt.Errorf("stat policy folder: %w", err)
+	}
+	if !info.IsDir() {
+		return "", "", "", fmt.Errorf("policy folder %q is not a directory", folder)
+	}
+
+	read := func(name string, embedded string) (string, error) {
+		data, err := os.ReadFile(filepath.Join(folder, name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return embedded, nil
+			}
+			return "", fmt.Errorf("reading %s: %w", name, err)
+		}
+		return string(data), nil
+	}
+
+	if authentication, err = read(policyFileAuthentication, opaAuthentication); err != nil {
+		return "", "", "", err
+	}
+
+	if authorization, err = read(policyFileAuthorization, opaAuthorization); err != nil {
+		return "", "", "", err
+	}
+
+	return folder, authentication, authorization, nil
+}
+
+// compilePolicy parses and compiles the rego documents and verifies that the
+// rules the service depends on are defined.
+func compilePolicy(source string, authentication string, authorization string) (*policy, error) {
+	authnCompiler, err := ast.CompileModules(map[string]string{policyFileAuthentication: authentication})
+	if err != nil {
+		return nil, fmt.Errorf("compiling %s: %w", policyFileAuthentication, err)
+	}
+
+	if err := requireRules(authnCompiler, RuleAuthenticate); err != nil {
+		return nil, fmt.Errorf("validating %s: %w", policyFileAuthentication, err)
+	}
+
+	authzCompiler, err := ast.CompileModules(map[string]string{policyFileAuthorization: authorization})
+	if err != nil {
+		return nil, fmt.Errorf("compiling %s: %w", policyFileAuthorization, err)
+	}
+
+	if err := requireRules(authzCompiler, RuleAny, RuleAdminOnly, RuleUserOnly, RuleAdminOrSubject, RulePermission, RuleMFA); err != nil {
+		return nil, fmt.Errorf("validating %s: %w", policyFileAuthorization, err)
+	}
+
+	p := policy{
+		authentication: authnCompiler,
+		authorization:  authzCompiler,
+		source:         source,
+		hash:           policyHash(authentication, authorization),
+		loadedAt:       time.Now(),
+	}
+
+	return &p, nil
+}
+
+// requireRules checks that every specified rule is defined in the opa package
+// used by the service.
+func requireRules(compiler *ast.Compiler, rules ...string) error {
+	for _, rule := range rules {
+		ref, err := ast.ParseRef(fmt.Sprintf("data.%s.%s", opaPackage, rule))
+		if err != nil {
+			return fmt.Errorf("parsing rule %q: %w", rule, err)
+		}
+
+		if len(compiler.GetRulesExact(ref)) == 0 {
+			return fmt.Errorf("rule %q is not defined", rule)
+		}
+	}
+
+	return nil
+}
+
+// policyHash returns a hash of the policy documents that is used to detect
+// changes and report which policy is active.
+func policyHash(authentication string, authorization string) string {
+	h := sha256.New()
+	h.Write([]byte(authentication))
+	h.Write([]byte{0})
+	h.Write([]byte(authorization))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -4,11 +4,17 @@ default ruleAny = false
 default ruleAdminOnly = false
 default ruleUserOnly = false
 default ruleAdminOrSubject = false
+default rulePermission = false
+default ruleMFA = false
 
 roleUser := "USER"
 roleAdmin := "ADMIN"
 roleAll := {roleAdmin, roleUser}
 
+# Roles that must authenticate with a second factor. Add roles, for example
+# roleAdmin, to require mfa for them.
+mfaRoles := set()
+
 ruleAny {
 	claim_roles := {role | role := input.Roles[_]}
 	input_roles := roleAll & claim_roles
@@ -37,3 +43,20 @@ ruleAdminOrSubject {
 	count(input_user) > 0
 	input.UserID == input.Subject
 }
+
+rulePermission {
+	input.Permission == input.Permissions[_]
+}
+
+ruleMFA {
+	claim_roles := {role | role := input.Roles[_]}
+	count(mfaRoles & claim_roles) == 0
+}
+
+ruleMFA {
+	input.AMR[_] == "otp"
+}
+
+ruleMFA {
+	input.AMR[_] == "apikey"
+}
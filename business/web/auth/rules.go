@@ -11,8 +11,17 @@ const (
 	RuleAdminOnly      = "ruleAdminOnly"
 	RuleUserOnly       = "ruleUserOnly"
 	RuleAdminOrSubject = "ruleAdminOrSubject"
+	RulePermission     = "rulePermission"
+	RuleMFA            = "ruleMFA"
 )
 
+// APIKeyHeader is the request header used by clients to present an API key.
+const APIKeyHeader = "X-API-Key"
+
+// MFAHeader is the request header used by clients to present an mfa code
+// when requesting a token.
+const MFAHeader = "X-MFA-Code"
+
 // Package name of our rego code.
 const (
 	opaPackage string = "qcbit.rego"
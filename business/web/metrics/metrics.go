@@ -22,6 +22,9 @@ type metrics struct {
 	requests   *expvar.Int
 	errors     *expvar.Int
 	panics     *expvar.Int
+	ratelimit  *expvar.Map
+	shed       *expvar.Map
+	timeouts   *expvar.Map
 }
 
 // init constructs the metrics value that will be used to capture metrics.
@@ -34,6 +37,9 @@ func init() {
 		requests:   expvar.NewInt("requests"),
 		errors:     expvar.NewInt("errors"),
 		panics:     expvar.NewInt("panics"),
+		ratelimit:  expvar.NewMap("ratelimit"),
+		shed:       expvar.NewMap("shed"),
+		timeouts:   expvar.NewMap("timeouts"),
 	}
 }
 
@@ -77,3 +83,31 @@ func AddPanics(ctx context.Context) {
 		v.panics.Add(1)
 	}
 }
+
+// AddRateLimit increments the allowed or rejected count of the rate limit
+// rule by 1.
+func AddRateLimit(ctx context.Context, rule string, allowed bool) {
+	if v, ok := ctx.Value(key).(*metrics); ok {
+		switch allowed {
+		case true:
+			v.ratelimit.Add(rule+".allowed", 1)
+		default:
+			v.ratelimit.Add(rule+".rejected", 1)
+		}
+	}
+}
+
+// AddShed increments the count of requests shed by the route group by 1.
+func AddShed(ctx context.Context, group string) {
+	if v, ok := ctx.Value(key).(*metrics); ok {
+		v.shed.Add(group, 1)
+	}
+}
+
+// AddTimeout increments the count of requests that ran past the deadline of
+// the route by 1.
+func AddTimeout(ctx context.Context, route string) {
+	if v, ok := ctx.Value(key).(*metrics); ok {
+		v.timeouts.Add(route, 1)
+	}
+}
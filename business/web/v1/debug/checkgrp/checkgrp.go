@@ -19,20 +19,34 @@ type Handlers struct {
 	Build string
 	Log   *zap.SugaredLogger
 	DB    *sqlx.DB
+
+	// Ready reports if the service is ready to receive traffic, it stops
+	// being ready once the service starts shutting down.
+	Ready func() bool
 }
 
 // Readiness checks if the database is ready and if not will return a 500 status.
-// Do not respond by just returning an error because further up in the call
-// stack it will interpret that as a non-trusted error.
+// While the service is shutting down a 503 status is returned so traffic is
+// drained before the servers stop. Do not respond by just returning an error
+// because further up in the call stack it will interpret that as a
+// non-trusted error.
 func (h Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
 	defer cancel()
 
 	status := "ok"
 	statusCode := http.StatusOK
-	if err := database.StatusCheck(ctx, h.DB); err != nil {
-		status = "db not ready"
-		statusCode = http.StatusInternalServerError
+
+	switch {
+	case h.Ready != nil && !h.Ready():
+		status = "shutting down"
+		statusCode = http.StatusServiceUnavailable
+
+	default:
+		if err := database.StatusCheck(ctx, h.DB); err != nil {
+			status = "db not ready"
+			statusCode = http.StatusInternalServerError
+		}
 	}
 
 	data := struct {
@@ -9,7 +9,9 @@ import (
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 
+	"github.com/qcbit/service/business/web/auth"
 	"github.com/qcbit/service/business/web/v1/debug/checkgrp"
+	"github.com/qcbit/service/business/web/v1/debug/policygrp"
 )
 
 // StandardLibraryMux registers all the debug routes from the standard library into a new mux
@@ -33,16 +35,34 @@ func StandardLibraryMux() *http.ServeMux {
 // debug application routes for the service. This bypassing the use of the
 // DefaultServerMux. Using the DefaultServerMux would be a security risk since
 // a dependency could inject a handler into our service without us knowing it.
-func Mux(build string, log *zap.SugaredLogger, db *sqlx.DB) http.Handler {
+// The ready function reports if the service is ready to receive traffic.
+// When protect is set, it wraps every route except the probes, which are
+// called by the kubelet without credentials.
+func Mux(build string, log *zap.SugaredLogger, db *sqlx.DB, a *auth.Auth, ready func() bool, protect func(http.Handler) http.Handler) http.Handler {
 	mux := StandardLibraryMux()
 
+	pgh := policygrp.Handlers{
+		Log:  log,
+		Auth: a,
+	}
+	mux.HandleFunc("/debug/policy", pgh.Status)
+
+	var handler http.Handler = mux
+	if protect != nil {
+		handler = protect(mux)
+	}
+
 	cgh := checkgrp.Handlers{
 		Build: build,
 		Log:   log,
 		DB:    db,
+		Ready: ready,
 	}
-	mux.HandleFunc("/debug/readiness", cgh.Readiness)
-	mux.HandleFunc("/debug/liveness", cgh.Liveness)
 
-	return mux
+	probes := http.NewServeMux()
+	probes.HandleFunc("/debug/readiness", cgh.Readiness)
+	probes.HandleFunc("/debug/liveness", cgh.Liveness)
+	probes.Handle("/", handler)
+
+	return probes
 }
@@ -0,0 +1,62 @@
+// Package policygrp maintains the group of handlers for reporting on the
+// authorization policies in use.
+package policygrp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/web/auth"
+)
+
+// Handlers manages the set of policy endpoints.
+type Handlers struct {
+	Log  *zap.SugaredLogger
+	Auth *auth.Auth
+}
+
+// Status returns the hash and load time of the active policies along with
+// the outcome of the last reload attempt.
+func (h Handlers) Status(w http.ResponseWriter, r *http.Request) {
+	status := h.Auth.PolicyStatus()
+
+	data := struct {
+		Source      string `json:"source"`
+		Hash        string `json:"hash"`
+		LoadedAt    string `json:"loadedAt"`
+		LastAttempt string `json:"lastAttempt"`
+		LastError   string `json:"lastError,omitempty"`
+	}{
+		Source:      status.Source,
+		Hash:        status.Hash,
+		LoadedAt:    status.LoadedAt.Format(time.RFC3339),
+		LastAttempt: status.LastAttempt.Format(time.RFC3339),
+		LastError:   status.LastError,
+	}
+
+	statusCode := http.StatusOK
+	if err := response(w, statusCode, data); err != nil {
+		h.Log.Errorw("policy", "ERROR", err)
+	}
+
+	h.Log.Infow("policy", "statusCode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
+
+func response(w http.ResponseWriter, statusCode int, data any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(jsonData); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+// Package errtest contains supporting code for testing that the errors of the
+// core packages have a registered response.
+package errtest
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1 "github.com/qcbit/service/business/web/v1"
+)
+
+// CoreError describes a sentinel error declared by a core package.
+type CoreError struct {
+	Name    string
+	Message string
+}
+
+// Unmapped reports the sentinel errors declared by the packages under root
+// that have no response registered with v1.RegisterError, so they would be
+// answered with a 500 status. Errors that are handled by other means are
+// listed in skip as package.Name, for example product.ErrNotFound. The
+// handler packages registering the responses must be linked into the test.
+func Unmapped(t *testing.T, root string, skip ...string) {
+	t.Helper()
+
+	coreErrs, err := Declared(root)
+	if err != nil {
+		t.Fatalf("Should be able to find the core errors: %s", err)
+	}
+
+	if len(coreErrs) == 0 {
+		t.Fatalf("Should find core errors under %s", root)
+	}
+
+	skipped := make(map[string]bool)
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	registered := make(map[string]bool)
+	for _, err := range v1.RegisteredErrors() {
+		registered[err.Error()] = true
+	}
+
+	for _, ce := range coreErrs {
+		if skipped[ce.Name] || registered[ce.Message] {
+			continue
+		}
+		t.Errorf("Should register a response for %s: %q", ce.Name, ce.Message)
+	}
+}
+
+// Declared returns the exported sentinel errors declared with errors.New by
+// the packages under root. Tests and stores are ignored.
+func Declared(root string) ([]CoreError, error) {
+	var coreErrs []CoreError
+
+	fset := token.NewFileSet()
+
+	walk := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == "stores" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if !name.IsExported() || !strings.HasPrefix(name.Name, "Err") || i >= len(vs.Values) {
+						continue
+					}
+
+					if msg, ok := errorsNew(vs.Values[i]); ok {
+						coreErrs = append(coreErrs, CoreError{
+							Name:    file.Name.Name + "." + name.Name,
+							Message: msg,
+						})
+					}
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if err := filepath.WalkDir(root, walk); err != nil {
+		return nil, err
+	}
+
+	return coreErrs, nil
+}
+
+// errorsNew returns the message of an errors.New call.
+func errorsNew(expr ast.Expr) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "New" {
+		return "", false
+	}
+
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "errors" {
+		return "", false
+	}
+
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+
+	msg, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+
+	return msg, true
+}
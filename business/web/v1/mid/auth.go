@@ -2,6 +2,8 @@ package mid
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/qcbit/service/foundation/web"
@@ -9,13 +11,25 @@ import (
 	"github.com/qcbit/service/business/web/auth"
 )
 
-// Authenticate validates a JWT from the `Authorization` header.
+// Authenticate validates a JWT from the `Authorization` header or an API key
+// from the `X-API-Key` header. Either produces the same set of claims.
 func Authenticate(a *auth.Auth) web.Middleware {
 	m := func(handler web.Handler) web.Handler {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-			claims, err := a.Authenticate(ctx, r.Header.Get("authorization"))
-			if err != nil {
-				return auth.NewAuthError("authenticate: failed: %s", err)
+			var claims auth.Claims
+			var err error
+
+			switch key := r.Header.Get(auth.APIKeyHeader); {
+			case key != "":
+				claims, err = a.AuthenticateAPIKey(ctx, key)
+				if err != nil {
+					return apiKeyFailure(err)
+				}
+			default:
+				claims, err = a.Authenticate(ctx, r.Header.Get("authorization"))
+				if err != nil {
+					return auth.NewAuthError("authenticate: failed: %s", err)
+				}
 			}
 
 			ctx = auth.SetClaims(ctx, claims)
@@ -26,7 +40,19 @@ func Authenticate(a *auth.Auth) web.Middleware {
 		return h
 	}
 
-	return m
+	// Routes requiring authentication are documented as secured.
+	return web.Documented(m, func(r *web.Route) { r.Auth() })
+}
+
+// apiKeyFailure keeps the reason an api key was rejected in the chain, so the
+// response carries the code registered for it. Failures looking up the key
+// aren't the client's fault and end up as internal errors.
+func apiKeyFailure(err error) error {
+	if errors.Is(err, auth.ErrAPIKeysUnsupported) {
+		return auth.NewAuthError("authenticate: failed: %s", err)
+	}
+
+	return fmt.Errorf("authenticate: %w", err)
 }
 
 // Authorize validates that an authenticated user has at least one role from a
@@ -36,11 +62,11 @@ func Authorize(a *auth.Auth, rule string) web.Middleware {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			claims := auth.GetClaims(ctx)
 			if claims.Subject == "" {
-				return auth.NewAuthError("authorize: you are not authorized for that action, no claims")
+				return auth.NewAuthErrorWithCode(auth.CodeForbidden, "authorize: you are not authorized for that action, no claims")
 			}
 
 			if err := a.Authorize(ctx, claims, rule); err != nil {
-				return auth.NewAuthError("authorize: you are not authorized for that action, claims[%v] rule[%v]: %s", claims.Roles, rule, err)
+				return auth.NewAuthErrorWithCode(authorizeCode(err), "authorize: you are not authorized for that action, claims[%v] rule[%v]: %s", claims.Roles, rule, err)
 			}
 
 			return handler(ctx, w, r)
@@ -49,5 +75,38 @@ func Authorize(a *auth.Auth, rule string) web.Middleware {
 		return h
 	}
 
-	return m
+	return web.Documented(m, func(r *web.Route) { r.Auth(rule) })
+}
+
+// AuthorizePermission validates that an authenticated user has been granted
+// the specified permission through one of their roles.
+func AuthorizePermission(a *auth.Auth, permission string) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			claims := auth.GetClaims(ctx)
+			if claims.Subject == "" {
+				return auth.NewAuthErrorWithCode(auth.CodeForbidden, "authorize: you are not authorized for that action, no claims")
+			}
+
+			if err := a.AuthorizePermission(ctx, claims, permission); err != nil {
+				return auth.NewAuthErrorWithCode(authorizeCode(err), "authorize: you are not authorized for that action, claims[%v] permission[%v]: %s", claims.Roles, permission, err)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return web.Documented(m, func(r *web.Route) { r.Auth(permission) })
+}
+
+// authorizeCode returns the code of a failed authorization. Callers whose
+// roles require a second factor are told so, so they can send a code.
+func authorizeCode(err error) string {
+	if errors.Is(err, auth.ErrMFARequired) {
+		return auth.CodeMFARequired
+	}
+
+	return auth.CodeForbidden
 }
@@ -0,0 +1,142 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/apikey"
+	"github.com/qcbit/service/business/core/user"
+	"github.com/qcbit/service/business/web/auth"
+	"github.com/qcbit/service/foundation/web"
+)
+
+func Test_AuthorizeMFARequired(t *testing.T) {
+	policy, err := os.ReadFile("../../auth/rego/authorization.rego")
+	if err != nil {
+		t.Fatalf("Should be able to read the policy: %s", err)
+	}
+
+	// Admins must authenticate with a second factor.
+	dir := t.TempDir()
+	doc := strings.Replace(string(policy), "mfaRoles := set()", "mfaRoles := {roleAdmin}", 1)
+	if err := os.WriteFile(filepath.Join(dir, "authorization.rego"), []byte(doc), 0600); err != nil {
+		t.Fatalf("Should be able to write the policy: %s", err)
+	}
+
+	a, err := auth.New(auth.Config{Log: zap.NewNop().Sugar(), PolicyFolder: dir})
+	if err != nil {
+		t.Fatalf("Should be able to construct auth: %s", err)
+	}
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+
+	tt := []struct {
+		name string
+		mw   web.Middleware
+		amr  []string
+		code string
+	}{
+		{"rule", Authorize(a, auth.RuleAdminOnly), []string{auth.AMRPassword}, auth.CodeMFARequired},
+		{"permission", AuthorizePermission(a, "users:write"), []string{auth.AMRPassword}, auth.CodeMFARequired},
+		{"otp", AuthorizePermission(a, "users:write"), []string{auth.AMRPassword, auth.AMROTP}, auth.CodeForbidden},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			claims := auth.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "admin"},
+				Roles:            []user.Role{user.RoleAdmin},
+				AMR:              tst.amr,
+			}
+			ctx := auth.SetClaims(context.Background(), claims)
+
+			err := tst.mw(handler)(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			authErr := auth.GetAuthError(err)
+			if authErr == nil || authErr.Code() != tst.code {
+				t.Errorf("Should fail with code %q: got %v", tst.code, err)
+			}
+		})
+	}
+}
+
+func Test_AuthenticateAPIKey(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+
+	tt := []struct {
+		name string
+		err  error
+	}{
+		{"expired", apikey.ErrExpired},
+		{"revoked", apikey.ErrRevoked},
+		{"owner", apikey.ErrInvalidOwner},
+		{"database", errDB},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			a, err := auth.New(auth.Config{Log: zap.NewNop().Sugar(), APIKeyLookup: keyLookup{err: tst.err}})
+			if err != nil {
+				t.Fatalf("Should be able to construct auth: %s", err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(auth.APIKeyHeader, "qcb_plain")
+
+			err = Authenticate(a)(handler)(context.Background(), httptest.NewRecorder(), r)
+
+			if !errors.Is(err, tst.err) {
+				t.Errorf("Should keep the reason the key was rejected in the chain: got %v", err)
+			}
+			if auth.IsAuthError(err) {
+				t.Errorf("Should leave the response to the registry: got %v", err)
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		a, err := auth.New(auth.Config{Log: zap.NewNop().Sugar()})
+		if err != nil {
+			t.Fatalf("Should be able to construct auth: %s", err)
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(auth.APIKeyHeader, "qcb_plain")
+
+		err = Authenticate(a)(handler)(context.Background(), httptest.NewRecorder(), r)
+		if !auth.IsAuthError(err) {
+			t.Errorf("Should fail authentication without api key support: got %v", err)
+		}
+	})
+}
+
+// =============================================================================
+
+type keyLookup struct {
+	err error
+}
+
+func (k keyLookup) Authenticate(ctx context.Context, key string) (apikey.Key, error) {
+	return apikey.Key{}, fmt.Errorf("authenticate: %w", k.err)
+}
+
+func (k keyLookup) QueryByID(ctx context.Context, keyID uuid.UUID) (apikey.Key, error) {
+	return apikey.Key{}, k.err
+}
@@ -0,0 +1,32 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+// BodyLimit restricts the size of request bodies to the specified number of
+// bytes. Requests declaring a larger body are rejected up front, and decoding
+// a body that turns out to be larger fails, both with a 413 status.
+func BodyLimit(maxBytes int64) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.ContentLength > maxBytes {
+				return web.NewError(fmt.Errorf("request body must not be larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+			}
+
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
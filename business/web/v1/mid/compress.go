@@ -0,0 +1,343 @@
+package mid
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+// CompressConfig describes which responses are compressed.
+type CompressConfig struct {
+
+	// MinSize is the number of bytes a response needs to reach before it is
+	// compressed. Smaller responses aren't worth the overhead.
+	MinSize int
+
+	// ContentTypes lists the media types that are compressed. A type ending
+	// in /* matches every subtype.
+	ContentTypes []string
+}
+
+// DefaultCompressTypes are the media types compressed when none are
+// configured.
+var DefaultCompressTypes = []string{"application/json", "application/problem+json", "text/*"}
+
+// Compress compresses response bodies with gzip or deflate, as negotiated
+// with the Accept-Encoding header of the request. Writes are buffered until
+// MinSize bytes are written or the handler flushes, at which point the
+// Content-Type decides if the response is compressed.
+func Compress(cfg CompressConfig) web.Middleware {
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultCompressTypes
+	}
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				return handler(ctx, w, r)
+			}
+
+			cw := compressWriter{
+				ResponseWriter: w,
+				cfg:            &cfg,
+				encoding:       encoding,
+			}
+			defer cw.close()
+
+			return handler(ctx, &cw, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// =============================================================================
+
+// Set of pools holding the compressors so they can be reused across
+// responses.
+var (
+	gzipPool = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(io.Discard)
+		},
+	}
+
+	// The deflate encoding is the zlib format (RFC 1950), not raw deflate.
+	zlibPool = sync.Pool{
+		New: func() any {
+			return zlib.NewWriter(io.Discard)
+		},
+	}
+)
+
+// compressor is the behavior shared by the gzip and zlib writers.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter buffers the start of the response until it can decide if
+// the response is compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *CompressConfig
+	encoding string
+
+	status  int
+	buf     []byte
+	decided bool
+	comp    compressor
+}
+
+// WriteHeader records the status, it is written once the response is known
+// to be compressed or not.
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+
+	// Responses without a body are never compressed.
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < http.StatusOK {
+		cw.decide(false)
+	}
+}
+
+// Write buffers the data until MinSize bytes are written.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.cfg.MinSize {
+			return len(b), nil
+		}
+
+		if err := cw.start(cw.compressible()); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if cw.comp != nil {
+		return cw.comp.Write(b)
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends the data written so far to the client. A response flushed
+// before reaching MinSize is treated as a stream and is compressed based on
+// its Content-Type alone.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.start(cw.compressible()); err != nil {
+			return
+		}
+	}
+
+	if cw.comp != nil {
+		cw.comp.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack allows the handler to take over the connection.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer doesn't support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying writer for use by http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports if the response is worth compressing based on the
+// data written so far and the headers set by the handler.
+func (cw *compressWriter) compressible() bool {
+	hdr := cw.Header()
+
+	if hdr.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := hdr.Get("Content-Type")
+	if contentType == "" {
+		if len(cw.buf) == 0 {
+			return false
+		}
+		contentType = http.DetectContentType(cw.buf)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	// Event streams are sent as they are so every event reaches the client
+	// without waiting on the compressor.
+	if mediaType == "text/event-stream" {
+		return false
+	}
+
+	for _, allowed := range cw.cfg.ContentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+
+		if mediaType == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decide records if the response is compressed and writes the headers.
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+
+	if compress {
+		hdr := cw.Header()
+		hdr.Del("Content-Length")
+		hdr.Set("Content-Encoding", cw.encoding)
+
+		// The encoded body differs from the one the tag was computed from.
+		if etag := hdr.Get("ETag"); etag != "" && !web.IsWeakETag(etag) {
+			hdr.Set("ETag", "W/"+etag)
+		}
+
+		switch cw.encoding {
+		case "gzip":
+			gw := gzipPool.Get().(*gzip.Writer)
+			gw.Reset(cw.ResponseWriter)
+			cw.comp = gw
+
+		default:
+			zw := zlibPool.Get().(*zlib.Writer)
+			zw.Reset(cw.ResponseWriter)
+			cw.comp = zw
+		}
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+// start decides if the response is compressed and writes the buffered data.
+func (cw *compressWriter) start(compress bool) error {
+	cw.decide(compress)
+
+	buf := cw.buf
+	cw.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if cw.comp != nil {
+		_, err := cw.comp.Write(buf)
+		return err
+	}
+
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// close completes the response. A response smaller than MinSize is sent
+// uncompressed with its length.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if len(cw.buf) > 0 && cw.Header().Get("Content-Encoding") == "" {
+			cw.Header().Set("Content-Length", strconv.Itoa(len(cw.buf)))
+		}
+		cw.start(false)
+		return
+	}
+
+	if cw.comp == nil {
+		return
+	}
+
+	cw.comp.Close()
+
+	switch comp := cw.comp.(type) {
+	case *gzip.Writer:
+		comp.Reset(io.Discard)
+		gzipPool.Put(comp)
+	case *zlib.Writer:
+		comp.Reset(io.Discard)
+		zlibPool.Put(comp)
+	}
+
+	cw.comp = nil
+}
+
+// negotiateEncoding selects the encoding for the response from the
+// Accept-Encoding header, preferring gzip when both are acceptable. An empty
+// string means the response isn't compressed.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	quality := make(map[string]float64)
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		quality[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		q, ok := quality[encoding]
+		if !ok {
+			q, ok = quality["*"]
+		}
+
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,140 @@
+package mid
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_NegotiateEncoding(t *testing.T) {
+	tt := []struct {
+		header string
+		exp    string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0", ""},
+		{"*", "gzip"},
+		{"br", ""},
+		{"identity", ""},
+	}
+
+	for _, tst := range tt {
+		if got := negotiateEncoding(tst.header); got != tst.exp {
+			t.Errorf("%q: Should select %q, got %q", tst.header, tst.exp, got)
+		}
+	}
+}
+
+func Test_Compress(t *testing.T) {
+	body := `{"items":"` + strings.Repeat("x", 2048) + `"}`
+
+	serve := func(handler func(w http.ResponseWriter), acceptEncoding string) *httptest.ResponseRecorder {
+		h := Compress(CompressConfig{MinSize: 1024})(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			handler(w)
+			return nil
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+
+		w := httptest.NewRecorder()
+		h(context.Background(), w, r)
+		return w
+	}
+
+	writeJSON := func(data string) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, data[:len(data)/2])
+			io.WriteString(w, data[len(data)/2:])
+		}
+	}
+
+	t.Run("large", func(t *testing.T) {
+		w := serve(writeJSON(body), "gzip")
+
+		if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" || w.Header().Get("Content-Length") != "" {
+			t.Fatalf("Should compress the response: %v", w.Header())
+		}
+
+		gr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Should be able to read the gzip body: %s", err)
+		}
+		got, _ := io.ReadAll(gr)
+		if string(got) != body {
+			t.Errorf("Should receive the original body.")
+		}
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		w := serve(writeJSON(body), "deflate")
+
+		if w.Header().Get("Content-Encoding") != "deflate" {
+			t.Fatalf("Should compress the response with deflate: %v", w.Header())
+		}
+
+		zr, err := zlib.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Should be able to read the deflate body: %s", err)
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("Should be able to decode the deflate body: %s", err)
+		}
+		if string(got) != body {
+			t.Errorf("Should receive the original body.")
+		}
+	})
+
+	t.Run("small", func(t *testing.T) {
+		w := serve(writeJSON(`{"id":1}`), "gzip")
+
+		if w.Header().Get("Content-Encoding") != "" || w.Header().Get("Content-Length") != "8" || w.Body.String() != `{"id":1}` {
+			t.Errorf("Should send small responses uncompressed: %v %q", w.Header(), w.Body.String())
+		}
+	})
+
+	t.Run("contenttype", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, body)
+		}, "gzip")
+
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+			t.Errorf("Should not compress types outside the allow-list: %v", w.Header())
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "first")
+			w.(http.Flusher).Flush()
+			io.WriteString(w, "second")
+		}, "gzip")
+
+		if !w.Flushed || w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Should compress and flush the stream: %v", w.Header())
+		}
+
+		gr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Should be able to read the gzip body: %s", err)
+		}
+		got, _ := io.ReadAll(gr)
+		if string(got) != "firstsecond" {
+			t.Errorf("Should receive every write, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+// Conditional answers GET requests for a resource the client already has
+// with a 304 status. Successful responses are buffered and receive a strong
+// ETag computed from the body, unless the handler set a tag of its own, and
+// the If-None-Match and If-Modified-Since headers of the request are then
+// evaluated against the ETag and Last-Modified headers of the response.
+func Conditional() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				return handler(ctx, w, r)
+			}
+
+			cw := conditionalWriter{ResponseWriter: w}
+
+			if err := handler(ctx, &cw, r); err != nil {
+				return err
+			}
+
+			if cw.passthrough || (cw.status == 0 && cw.buf.Len() == 0) {
+				return nil
+			}
+
+			hdr := w.Header()
+
+			etag := hdr.Get("ETag")
+			if etag == "" {
+				etag = web.StrongETag(cw.buf.Bytes())
+				hdr.Set("ETag", etag)
+			}
+
+			lastModified, _ := http.ParseTime(hdr.Get("Last-Modified"))
+
+			if web.NotModified(r, etag, lastModified) {
+				hdr.Del("Content-Type")
+				hdr.Del("Content-Length")
+
+				web.SetStatusCode(ctx, http.StatusNotModified)
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(cw.buf.Bytes())
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// =============================================================================
+
+// conditionalWriter buffers a successful response until its entity tag is
+// known. Other responses, and responses the handler flushes, are passed
+// through as they are written.
+type conditionalWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+// WriteHeader records the status of a successful response and passes any
+// other status through.
+func (cw *conditionalWriter) WriteHeader(status int) {
+	if cw.passthrough || cw.status != 0 {
+		return
+	}
+
+	if status == http.StatusOK {
+		cw.status = status
+		return
+	}
+
+	cw.passthrough = true
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+// Write buffers the data of a successful response.
+func (cw *conditionalWriter) Write(b []byte) (int, error) {
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	return cw.buf.Write(b)
+}
+
+// Flush sends the buffered data, a streamed response doesn't get an ETag.
+func (cw *conditionalWriter) Flush() {
+	if !cw.passthrough {
+		cw.passthrough = true
+
+		if cw.status != 0 {
+			cw.ResponseWriter.WriteHeader(cw.status)
+		}
+		if cw.buf.Len() > 0 {
+			cw.ResponseWriter.Write(cw.buf.Bytes())
+			cw.buf.Reset()
+		}
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for use by http.ResponseController.
+func (cw *conditionalWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
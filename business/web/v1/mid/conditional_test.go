@@ -0,0 +1,54 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+func Test_Conditional(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, map[string]string{"name": "bill"}, http.StatusOK)
+	}
+
+	h := Conditional()(handler)
+
+	w := httptest.NewRecorder()
+	if err := h(context.Background(), w, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatalf("Should handle the request: %s", err)
+	}
+
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Body.String() != `{"name":"bill"}` {
+		t.Fatalf("Should send the body with an ETag: %d %v %s", w.Code, w.Header(), w.Body)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", etag)
+
+	w = httptest.NewRecorder()
+	if err := h(context.Background(), w, r); err != nil {
+		t.Fatalf("Should handle the request: %s", err)
+	}
+
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Errorf("Should answer with a 304 status: %d %v %s", w.Code, w.Header(), w.Body)
+	}
+
+	// Responses that aren't successful are passed through.
+	notFound := Conditional()(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, map[string]string{"error": "not found"}, http.StatusNotFound)
+	})
+
+	w = httptest.NewRecorder()
+	if err := notFound(context.Background(), w, r); err != nil {
+		t.Fatalf("Should handle the request: %s", err)
+	}
+
+	if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" {
+		t.Errorf("Should pass the response through: %d %v", w.Code, w.Header())
+	}
+}
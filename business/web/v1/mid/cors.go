@@ -0,0 +1,128 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+// CORSConfig describes the cross-origin requests browsers are allowed to make.
+type CORSConfig struct {
+
+	// AllowedOrigins lists the allowed origins. An origin can contain a single
+	// wildcard for subdomains such as https://*.example.com, and * allows
+	// every origin.
+	AllowedOrigins []string
+
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+
+	// MaxAge is how long browsers can cache the result of a preflight request.
+	MaxAge time.Duration
+}
+
+// CORS adds the headers that allow browsers to make cross-origin requests
+// from the allowed origins. Preflight requests are answered by the OPTIONS
+// handling of the application, so only registered paths pass a preflight.
+func CORS(cfg CORSConfig) web.Middleware {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	var allowAll bool
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+	}
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			origin := r.Header.Get("Origin")
+
+			// The response depends on the origin, so caches must not serve
+			// it to a different origin.
+			w.Header().Add("Vary", "Origin")
+
+			if origin == "" || !allowedOrigin(cfg.AllowedOrigins, origin) {
+				return handler(ctx, w, r)
+			}
+
+			hdr := w.Header()
+
+			switch {
+			case allowAll && !cfg.AllowCredentials:
+				hdr.Set("Access-Control-Allow-Origin", "*")
+			default:
+				hdr.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if cfg.AllowCredentials {
+				hdr.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			switch preflight {
+			case true:
+				hdr.Add("Vary", "Access-Control-Request-Method")
+				hdr.Add("Vary", "Access-Control-Request-Headers")
+
+				if methods != "" {
+					hdr.Set("Access-Control-Allow-Methods", methods)
+				}
+				if headers != "" {
+					hdr.Set("Access-Control-Allow-Headers", headers)
+				}
+				if cfg.MaxAge > 0 {
+					hdr.Set("Access-Control-Max-Age", maxAge)
+				}
+
+			default:
+				if exposed != "" {
+					hdr.Set("Access-Control-Expose-Headers", exposed)
+				}
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// allowedOrigin reports if the origin matches one of the allowed origins.
+func allowedOrigin(allowed []string, origin string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard || len(origin) <= len(prefix)+len(suffix) {
+			continue
+		}
+
+		if !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+
+		// The wildcard only stands for subdomains, it can't span the scheme,
+		// port or path of the origin.
+		sub := origin[len(prefix) : len(origin)-len(suffix)]
+		if !strings.ContainsAny(sub, "/:") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+func Test_AllowedOrigin(t *testing.T) {
+	allowed := []string{"https://app.example.com", "https://*.example.org"}
+
+	tt := []struct {
+		origin string
+		exp    bool
+	}{
+		{"https://app.example.com", true},
+		{"https://other.example.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"http://a.example.org", false},
+		{"https://a.example.org.evil.com", false},
+		{"https://evil.com/.example.org", false},
+	}
+
+	for _, tst := range tt {
+		if got := allowedOrigin(allowed, tst.origin); got != tst.exp {
+			t.Errorf("%s: Should report allowed=%t", tst.origin, tst.exp)
+		}
+	}
+}
+
+func Test_CORSPreflight(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{web.TraceIDHeader},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	app := web.NewApp(nil, CORS(cfg))
+	app.Handle(http.MethodGet, "/users", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	})
+
+	r := httptest.NewRequest(http.MethodOptions, "/users", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	exp := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+		"Access-Control-Max-Age":           "600",
+		"Allow":                            "GET, HEAD, OPTIONS",
+	}
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Should answer the preflight with 204, got %d", w.Code)
+	}
+
+	for k, v := range exp {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("Should set %s to %q, got %q", k, v, got)
+		}
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set("Origin", "https://evil.com")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Should not allow other origins, got %q", got)
+	}
+}
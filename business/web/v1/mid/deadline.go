@@ -0,0 +1,58 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/qcbit/service/foundation/web"
+
+	"github.com/qcbit/service/business/sys/validate"
+	"github.com/qcbit/service/business/web/metrics"
+	v1 "github.com/qcbit/service/business/web/v1"
+)
+
+// Deadline bounds the time a request can take. The context of the handler is
+// cancelled once the timeout passes, and a handler that fails because of it
+// is answered with a 504 status. Deadlines nest, so a route can tighten the
+// deadline of its group but never extend it. No timeout means no deadline.
+func Deadline(name string, timeout time.Duration) web.Middleware {
+	if timeout <= 0 {
+		return nil
+	}
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			err := handler(ctx, w, r)
+			if err == nil || !expired(ctx, err) {
+				return err
+			}
+
+			metrics.AddTimeout(ctx, name)
+
+			return web.NewError(errors.New("request took too long to complete"), http.StatusGatewayTimeout)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// expired reports if the error was caused by the deadline of the context. An
+// error the handler already turned into a response for the client is kept.
+func expired(ctx context.Context, err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+
+	return !v1.IsRequestError(err) && !web.IsError(err) && !validate.IsFieldErrors(err) && web.GetFieldErrors(err) == nil
+}
@@ -2,7 +2,9 @@ package mid
 
 import (
 	"context"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/qcbit/service/foundation/web"
 	"go.uber.org/zap"
@@ -12,49 +14,80 @@ import (
 	v1 "github.com/qcbit/service/business/web/v1"
 )
 
+// Set of formats of error responses.
+const (
+	ErrorFormatLegacy  = "legacy"
+	ErrorFormatProblem = "problem"
+)
+
+// ErrorsConfig describes the format of error responses.
+type ErrorsConfig struct {
+
+	// Format is the format used for clients that don't ask for problem
+	// details in their Accept header, legacy when empty.
+	Format string
+
+	// ProblemTypeBase is the URI the code is appended to for the type of
+	// problem details. Without it the type is about:blank.
+	ProblemTypeBase string
+}
+
+// authDetails are the messages shown to clients for auth errors, the message
+// of the error itself can reveal the roles and rules involved.
+var authDetails = map[string]string{
+	auth.CodeUnauthenticated:    "authentication is required",
+	auth.CodeInvalidCredentials: "the credentials provided are invalid",
+	auth.CodeMFARequired:        "a multi-factor authentication code is required in the " + auth.MFAHeader + " header",
+	auth.CodeAccountDisabled:    "the account is disabled",
+	auth.CodeForbidden:          "you are not authorized for that action",
+}
+
 // Errors handles errors coming out of the call chain. It detects normal
 // application errors which are used to respond to the client in a uniform way.
-// Unexpected errors (status >= 500) are logged.
-func Errors(log *zap.SugaredLogger) web.Middleware {
+// Unexpected errors (status >= 500) are logged. Clients receive problem
+// details when the format is configured or they ask for them with the Accept
+// header, and the legacy {error, fields} document otherwise.
+func Errors(log *zap.SugaredLogger, cfg ErrorsConfig) web.Middleware {
 	m := func(handler web.Handler) web.Handler {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
 			if err := handler(ctx, w, r); err != nil {
 				log.Errorw("ERROR", "trace_id", web.GetTraceID(ctx), "message", err)
 
-				var er v1.ErrorResponse
-				var status int
+				// Fields that couldn't be decoded are reported the same
+				// way as fields that failed validation.
+				if fe := web.GetFieldErrors(err); fe != nil {
+					err = toFieldErrors(fe)
+				}
 
-				switch {
-				case validate.IsFieldErrors(err):
-					fieldErrors := validate.GetFieldErrors(err)
-					er = v1.ErrorResponse{
-						Error:  "data validation error",
-						Fields: fieldErrors.Fields(),
-					}
-					status = http.StatusBadRequest
+				f := toFailure(err)
 
-				case v1.IsRequestError(err):
-					reqErr := v1.GetRequestError(err)
-					er = v1.ErrorResponse{
-						Error: reqErr.Error(),
+				var respErr error
+				switch cfg.Format == ErrorFormatProblem || acceptsProblem(r) {
+				case true:
+					pd := v1.ProblemDetail{
+						Type:     "about:blank",
+						Title:    http.StatusText(f.problemStatus),
+						Status:   f.problemStatus,
+						Detail:   f.detail,
+						Instance: web.GetTraceID(ctx),
+						Code:     f.code,
+						Fields:   f.fields,
 					}
-					status = reqErr.Status
-
-				case auth.IsAuthError(err):
-					er = v1.ErrorResponse{
-						Error: http.StatusText(http.StatusUnauthorized),
+					if cfg.ProblemTypeBase != "" {
+						pd.Type = cfg.ProblemTypeBase + f.code
 					}
-					status = http.StatusUnauthorized
+					respErr = web.RespondAs(ctx, w, pd, f.problemStatus, v1.ProblemContentType)
 
 				default:
-					er = v1.ErrorResponse{
-						Error: http.StatusText(http.StatusInternalServerError),
+					er := v1.ErrorResponse{
+						Error:  f.message,
+						Fields: f.fields,
 					}
-					status = http.StatusInternalServerError
+					respErr = web.Respond(ctx, w, er, f.status)
 				}
 
-				if err := web.Respond(ctx, w, er, status); err != nil {
-					return err
+				if respErr != nil {
+					return respErr
 				}
 
 				// If we receive the shutdown err, return it
@@ -72,3 +105,122 @@ func Errors(log *zap.SugaredLogger) web.Middleware {
 
 	return m
 }
+
+// failure describes the response for an error. The legacy format reports
+// every auth error as unauthorized, problem details report the status and
+// detail matching the code of the error.
+type failure struct {
+	status        int
+	message       string
+	problemStatus int
+	detail        string
+	code          string
+	fields        map[string]string
+}
+
+// toFailure describes the response for the error.
+func toFailure(err error) failure {
+	switch {
+	case validate.IsFieldErrors(err):
+		fieldErrors := validate.GetFieldErrors(err)
+		return failure{
+			status:        http.StatusBadRequest,
+			message:       "data validation error",
+			problemStatus: http.StatusBadRequest,
+			detail:        "data validation error",
+			code:          v1.CodeValidation,
+			fields:        fieldErrors.Fields(),
+		}
+
+	case v1.IsRequestError(err):
+		reqErr := v1.GetRequestError(err)
+		code := reqErr.Code
+		if code == "" {
+			code = mappedCode(err, reqErr.Status)
+		}
+		return failure{
+			status:        reqErr.Status,
+			message:       reqErr.Error(),
+			problemStatus: reqErr.Status,
+			detail:        reqErr.Error(),
+			code:          code,
+		}
+
+	case web.IsError(err):
+		webErr := web.GetError(err)
+		return failure{
+			status:        webErr.Status,
+			message:       webErr.Error(),
+			problemStatus: webErr.Status,
+			detail:        webErr.Error(),
+			code:          mappedCode(err, webErr.Status),
+		}
+
+	case auth.IsAuthError(err):
+		authErr := auth.GetAuthError(err)
+		f := failure{
+			status:        http.StatusUnauthorized,
+			message:       http.StatusText(http.StatusUnauthorized),
+			problemStatus: http.StatusUnauthorized,
+			detail:        authDetails[authErr.Code()],
+			code:          authErr.Code(),
+		}
+		if authErr.Code() == auth.CodeForbidden {
+			f.problemStatus = http.StatusForbidden
+		}
+		return f
+	}
+
+	// Core errors the handler didn't map are resolved through the registry.
+	if mapping, exists := v1.LookupError(err); exists {
+		return failure{
+			status:        mapping.Status,
+			message:       mapping.Message,
+			problemStatus: mapping.Status,
+			detail:        mapping.Message,
+			code:          mapping.Code,
+		}
+	}
+
+	return failure{
+		status:        http.StatusInternalServerError,
+		message:       http.StatusText(http.StatusInternalServerError),
+		problemStatus: http.StatusInternalServerError,
+		code:          v1.CodeInternal,
+	}
+}
+
+// mappedCode returns the code of the registered error found in the chain of
+// the error, or else the code of the status.
+func mappedCode(err error, status int) string {
+	if mapping, exists := v1.LookupError(err); exists {
+		return mapping.Code
+	}
+	return v1.StatusCode(status)
+}
+
+// acceptsProblem reports if the client asked for problem details.
+func acceptsProblem(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == v1.ProblemContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toFieldErrors converts the decoding errors reported by the web package
+// into validation errors.
+func toFieldErrors(fe web.FieldErrors) validate.FieldErrors {
+	fields := make(validate.FieldErrors, len(fe))
+	for i, fld := range fe {
+		fields[i] = validate.FieldError{
+			Field: fld.Field,
+			Err:   fld.Err,
+		}
+	}
+	return fields
+}
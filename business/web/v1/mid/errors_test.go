@@ -0,0 +1,144 @@
+package mid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/sys/validate"
+	"github.com/qcbit/service/business/web/auth"
+	v1 "github.com/qcbit/service/business/web/v1"
+	"github.com/qcbit/service/foundation/web"
+)
+
+func Test_Errors(t *testing.T) {
+	errTaken := errors.New("name is taken")
+	v1.RegisterError(errTaken, http.StatusConflict, "name_taken", "the name is already in use")
+
+	tt := []struct {
+		name       string
+		err        error
+		accept     string
+		status     int
+		legacy     v1.ErrorResponse
+		problem    v1.ProblemDetail
+		useProblem bool
+	}{
+		{
+			name:   "legacy",
+			err:    v1.NewRequestError(errors.New("email is taken"), http.StatusConflict),
+			status: http.StatusConflict,
+			legacy: v1.ErrorResponse{Error: "email is taken"},
+		},
+		{
+			name:       "conflict",
+			err:        v1.NewRequestError(errors.New("email is taken"), http.StatusConflict),
+			accept:     "application/json, application/problem+json",
+			status:     http.StatusConflict,
+			problem:    v1.ProblemDetail{Type: "about:blank", Title: "Conflict", Status: http.StatusConflict, Detail: "email is taken", Code: v1.CodeConflict},
+			useProblem: true,
+		},
+		{
+			name:   "registered",
+			err:    fmt.Errorf("create: %w", errTaken),
+			status: http.StatusConflict,
+			legacy: v1.ErrorResponse{Error: "the name is already in use"},
+		},
+		{
+			name:       "registeredproblem",
+			err:        fmt.Errorf("create: %w", errTaken),
+			accept:     v1.ProblemContentType,
+			status:     http.StatusConflict,
+			problem:    v1.ProblemDetail{Type: "about:blank", Title: "Conflict", Status: http.StatusConflict, Detail: "the name is already in use", Code: "name_taken"},
+			useProblem: true,
+		},
+		{
+			name:       "validation",
+			err:        validate.NewFieldsError("name", errors.New("name is a required field")),
+			accept:     v1.ProblemContentType,
+			status:     http.StatusBadRequest,
+			problem:    v1.ProblemDetail{Type: "about:blank", Title: "Bad Request", Status: http.StatusBadRequest, Detail: "data validation error", Code: v1.CodeValidation, Fields: map[string]string{"name": "name is a required field"}},
+			useProblem: true,
+		},
+		{
+			name:   "forbiddenlegacy",
+			err:    auth.NewAuthErrorWithCode(auth.CodeForbidden, "claims[USER] rule[admin]"),
+			status: http.StatusUnauthorized,
+			legacy: v1.ErrorResponse{Error: "Unauthorized"},
+		},
+		{
+			name:       "forbidden",
+			err:        auth.NewAuthErrorWithCode(auth.CodeForbidden, "claims[USER] rule[admin]"),
+			accept:     v1.ProblemContentType,
+			status:     http.StatusForbidden,
+			problem:    v1.ProblemDetail{Type: "about:blank", Title: "Forbidden", Status: http.StatusForbidden, Detail: "you are not authorized for that action", Code: auth.CodeForbidden},
+			useProblem: true,
+		},
+		{
+			name:       "internal",
+			err:        errors.New("connection refused"),
+			accept:     v1.ProblemContentType,
+			status:     http.StatusInternalServerError,
+			problem:    v1.ProblemDetail{Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError, Code: v1.CodeInternal},
+			useProblem: true,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return tst.err
+			}
+
+			h := Errors(zap.NewNop().Sugar(), ErrorsConfig{})(handler)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tst.accept != "" {
+				r.Header.Set("Accept", tst.accept)
+			}
+
+			w := httptest.NewRecorder()
+			if err := h(context.Background(), w, r); err != nil {
+				t.Fatalf("Should handle the error: %s", err)
+			}
+
+			if w.Code != tst.status {
+				t.Errorf("Should respond with the status: got %d, exp %d", w.Code, tst.status)
+			}
+
+			switch tst.useProblem {
+			case true:
+				if ct := w.Header().Get("Content-Type"); ct != v1.ProblemContentType {
+					t.Errorf("Should respond with problem details: got %s", ct)
+				}
+
+				var got v1.ProblemDetail
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("Should decode the problem details: %s", err)
+				}
+
+				tst.problem.Instance = web.ZeroTraceID
+				if diff := cmp.Diff(got, tst.problem); diff != "" {
+					t.Errorf("Should respond with the problem details. Diff:\n%s", diff)
+				}
+
+			default:
+				var got v1.ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("Should decode the error response: %s", err)
+				}
+
+				if got.Error != tst.legacy.Error {
+					t.Errorf("Should respond with the error: got %q, exp %q", got.Error, tst.legacy.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/idempotency"
+	v1 "github.com/qcbit/service/business/web/v1"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// Set of headers used by idempotent requests.
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength is the longest key accepted.
+const maxIdempotencyKeyLength = 255
+
+// defaultMaxIdempotentBody is the largest body hashed when no limit is
+// specified.
+const defaultMaxIdempotentBody = 1 << 20
+
+// recordTimeout bounds recording the outcome of a request. The outcome is
+// recorded after the handler returned, when the deadline of the request may
+// already have passed.
+const recordTimeout = 5 * time.Second
+
+// unrecordedHeaders are response headers that describe a single request, they
+// aren't replayed. The Access-Control-* headers are excluded as well.
+var unrecordedHeaders = []string{
+	"Content-Encoding",
+	"Content-Length",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"Retry-After",
+	"Vary",
+	web.TraceIDHeader,
+}
+
+// init registers the codes of the idempotency errors, the middleware decides
+// their status.
+func init() {
+	v1.RegisterError(idempotency.ErrMismatch, http.StatusUnprocessableEntity, "idempotency_key_mismatch", "idempotency key was used with a different request")
+	v1.RegisterError(idempotency.ErrInProgress, http.StatusConflict, "idempotency_key_in_progress", "a request with this idempotency key is in progress")
+}
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to
+// retry. The response of the first request is recorded and replayed for
+// retries with the same key and body. A key reused with a different body is
+// rejected with a 422 status, and a retry arriving while the first request is
+// running receives a 409 status. Requests that fail with an error or a 5xx
+// status aren't recorded so they can be retried. Keys are scoped to the client
+// and route, so the middleware belongs after Authenticate. Responses are
+// stored as they are, so it must not be used on routes returning secrets. The
+// body is read into memory to be hashed, so bodies larger than maxBody bytes
+// are rejected with a 413 status even without BodyLimit. A maxBody of zero
+// limits bodies to 1MB.
+func Idempotency(log *zap.SugaredLogger, core *idempotency.Core, trusted []netip.Prefix, maxBody int64) web.Middleware {
+	if maxBody <= 0 {
+		maxBody = defaultMaxIdempotentBody
+	}
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				return handler(ctx, w, r)
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				return web.NewError(fmt.Errorf("%s must not be longer than %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength), http.StatusBadRequest)
+			}
+
+			var body []byte
+			if r.Body != nil {
+				var err error
+				if body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody)); err != nil {
+					var maxErr *http.MaxBytesError
+					if errors.As(err, &maxErr) {
+						return web.NewError(fmt.Errorf("request body must not be larger than %d bytes", maxErr.Limit), http.StatusRequestEntityTooLarge)
+					}
+					return fmt.Errorf("reading body: %w", err)
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			scope := clientKey(ctx, r, trusted) + " " + r.Method + " " + r.URL.Path
+			sum := sha256.Sum256(body)
+
+			rec, err := core.Begin(ctx, scope, key, hex.EncodeToString(sum[:]))
+			if err != nil {
+				switch {
+				case errors.Is(err, idempotency.ErrMismatch):
+					return web.NewError(err, http.StatusUnprocessableEntity)
+				case errors.Is(err, idempotency.ErrInProgress):
+					w.Header().Set("Retry-After", "1")
+					return web.NewError(err, http.StatusConflict)
+				default:
+					return fmt.Errorf("begin: %w", err)
+				}
+			}
+
+			if rec.Completed() {
+				return replay(ctx, w, rec)
+			}
+
+			rw := recordWriter{ResponseWriter: w}
+
+			handlerErr := handler(ctx, &rw, r)
+
+			// The key must not stay reserved because the request ran out of
+			// time, so the outcome is recorded even once it was canceled.
+			rctx, cancel := context.WithTimeout(detached{parent: ctx}, recordTimeout)
+			defer cancel()
+
+			if handlerErr != nil {
+				if err := core.Release(rctx, rec); err != nil {
+					log.Errorw("idempotency", "trace_id", web.GetTraceID(ctx), "status", "release", "ERROR", err)
+				}
+				return handlerErr
+			}
+
+			if rw.status == 0 {
+				rw.status = http.StatusOK
+				rw.header = w.Header().Clone()
+			}
+
+			if rw.status >= http.StatusInternalServerError {
+				if err := core.Release(rctx, rec); err != nil {
+					log.Errorw("idempotency", "trace_id", web.GetTraceID(ctx), "status", "release", "ERROR", err)
+				}
+				return nil
+			}
+
+			if err := core.Complete(rctx, rec, rw.status, recordedHeader(rw.header), rw.body.Bytes()); err != nil {
+				log.Errorw("idempotency", "trace_id", web.GetTraceID(ctx), "status", "complete", "ERROR", err)
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// replay writes the recorded response of an earlier request.
+func replay(ctx context.Context, w http.ResponseWriter, rec idempotency.Record) error {
+	web.SetStatusCode(ctx, rec.StatusCode)
+
+	hdr := w.Header()
+	for name, values := range rec.Header {
+		hdr[name] = values
+	}
+	hdr.Set(IdempotentReplayedHeader, "true")
+
+	w.WriteHeader(rec.StatusCode)
+
+	if len(rec.Body) > 0 {
+		if _, err := w.Write(rec.Body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordedHeader returns the headers of the response that are replayed.
+func recordedHeader(h http.Header) map[string][]string {
+	header := make(map[string][]string, len(h))
+	for name, values := range h {
+		header[name] = values
+	}
+
+	for _, name := range unrecordedHeaders {
+		delete(header, name)
+	}
+
+	for name := range header {
+		if strings.HasPrefix(name, "Access-Control-") {
+			delete(header, name)
+		}
+	}
+
+	return header
+}
+
+// =============================================================================
+
+// detached carries the values of its parent context without its deadline and
+// cancellation.
+type detached struct {
+	parent context.Context
+}
+
+// Deadline reports there is no deadline.
+func (d detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, the context is never canceled.
+func (d detached) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, the context is never canceled.
+func (d detached) Err() error {
+	return nil
+}
+
+// Value returns the value of the parent context for the key.
+func (d detached) Value(key any) any {
+	return d.parent.Value(key)
+}
+
+// =============================================================================
+
+// recordWriter captures the response written by the handler while passing it
+// on to the client.
+type recordWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status and a copy of the headers.
+func (rw *recordWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+		rw.header = rw.ResponseWriter.Header().Clone()
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the data written.
+func (rw *recordWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer for use by http.ResponseController.
+func (rw *recordWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
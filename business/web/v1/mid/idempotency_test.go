@@ -0,0 +1,196 @@
+package mid
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/qcbit/service/business/core/idempotency"
+	"github.com/qcbit/service/foundation/web"
+)
+
+// memStore keeps idempotency records in memory.
+type memStore struct {
+	mu   sync.Mutex
+	recs map[string]idempotency.Record
+}
+
+func (s *memStore) Reserve(ctx context.Context, rec idempotency.Record) (idempotency.Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.recs[rec.Scope+rec.Key]; exists {
+		return idempotency.Record{}, false, nil
+	}
+	s.recs[rec.Scope+rec.Key] = rec
+	return rec, true, nil
+}
+
+func (s *memStore) Complete(ctx context.Context, rec idempotency.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recs[rec.Scope+rec.Key] = rec
+	return nil
+}
+
+func (s *memStore) Release(ctx context.Context, rec idempotency.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.recs, rec.Scope+rec.Key)
+	return nil
+}
+
+func (s *memStore) QueryByKey(ctx context.Context, scope string, key string) (idempotency.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, exists := s.recs[scope+key]
+	if !exists {
+		return idempotency.Record{}, idempotency.ErrNotFound
+	}
+	return rec, nil
+}
+
+func (s *memStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+func Test_Idempotency(t *testing.T) {
+	var calls int
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls++
+		if r.Header.Get(IdempotencyKeyHeader) == "k2" {
+			close(started)
+			<-release
+		}
+		w.Header().Set("Location", "/v1/users/1")
+		return web.Respond(ctx, w, map[string]int{"call": calls}, http.StatusCreated)
+	}
+
+	core := idempotency.NewCore(&memStore{recs: make(map[string]idempotency.Record)}, idempotency.Config{})
+	h := Idempotency(zap.NewNop().Sugar(), core, nil, 64)(handler)
+
+	send := func(key string, body string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+		r.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		return w, h(context.Background(), w, r)
+	}
+
+	w, err := send("k1", `{"name":"bill"}`)
+	if err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("Should run the first request: %d %v", w.Code, err)
+	}
+
+	w, err = send("k1", `{"name":"bill"}`)
+	if err != nil || w.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("Should replay the response of a retry: %d %v calls %d", w.Code, err, calls)
+	}
+
+	if w.Header().Get(IdempotentReplayedHeader) != "true" || w.Header().Get("Location") != "/v1/users/1" {
+		t.Errorf("Should replay the headers: %v", w.Header())
+	}
+
+	if body, _ := io.ReadAll(w.Body); string(body) != `{"call":1}` {
+		t.Errorf("Should replay the body: %s", body)
+	}
+
+	_, err = send("k1", `{"name":"ed"}`)
+	if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusUnprocessableEntity {
+		t.Errorf("Should reject a key reused with a different body: %v", err)
+	}
+
+	_, err = send(strings.Repeat("k", 256), `{}`)
+	if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusBadRequest {
+		t.Errorf("Should reject a long key: %v", err)
+	}
+
+	_, err = send("k3", `{"name":"`+strings.Repeat("b", 64)+`"}`)
+	if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Should reject a body larger than the limit: %v", err)
+	}
+
+	// A retry arriving while the first request is running is rejected.
+	done := make(chan struct{})
+	go func() {
+		send("k2", `{}`)
+		close(done)
+	}()
+	<-started
+
+	w, err = send("k2", `{}`)
+	if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Should reject a concurrent retry: %v", err)
+	}
+
+	close(release)
+	<-done
+}
+
+func Test_IdempotencyCanceled(t *testing.T) {
+	store := memStore{recs: make(map[string]idempotency.Record)}
+	core := idempotency.NewCore(&store, idempotency.Config{})
+
+	// The deadline of the request passes while the handler runs.
+	tt := []struct {
+		name   string
+		status int
+		err    error
+		stored bool
+	}{
+		{"complete", http.StatusCreated, nil, true},
+		{"release", 0, context.DeadlineExceeded, false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				cancel()
+				if tst.err != nil {
+					return tst.err
+				}
+				return web.Respond(ctx, w, nil, tst.status)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{}`))
+			r.Header.Set(IdempotencyKeyHeader, tst.name)
+
+			Idempotency(zap.NewNop().Sugar(), core, nil, 0)(handler)(ctx, httptest.NewRecorder(), r)
+
+			var reserved, stored bool
+			for _, rec := range store.recs {
+				if rec.Key == tst.name {
+					reserved = true
+					stored = rec.Completed()
+				}
+			}
+
+			if reserved != tst.stored || stored != tst.stored {
+				t.Errorf("Should record the outcome after the request was canceled: reserved %t, completed %t", reserved, stored)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/qcbit/service/foundation/web"
+
+	"github.com/qcbit/service/business/sys/validate"
+)
+
+// Language selects the language of validation messages from the
+// Accept-Language header of the request. The language is set in the context
+// of the handler and of the request, since models are validated while the
+// request is decoded.
+func Language() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			lang := r.Header.Get("Accept-Language")
+
+			ctx = validate.WithLanguage(ctx, lang)
+			r = r.WithContext(validate.WithLanguage(r.Context(), lang))
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,166 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qcbit/service/foundation/ratelimit"
+	"github.com/qcbit/service/foundation/web"
+
+	"github.com/qcbit/service/business/web/auth"
+	"github.com/qcbit/service/business/web/metrics"
+)
+
+// RateLimitConfig describes a rate limit rule. Every client receives a bucket
+// of Burst requests that refills at Rate requests per second.
+type RateLimitConfig struct {
+
+	// Name identifies the rule in the metrics.
+	Name string
+
+	Rate  float64
+	Burst int
+
+	// TrustedProxies are the addresses of the proxies whose X-Forwarded-For
+	// header is used to find the address of the client.
+	TrustedProxies []netip.Prefix
+}
+
+// RateLimit limits the rate of requests per client. Clients are identified by
+// the subject of their claims when the middleware runs after Authenticate, or
+// else by their address. Credentials that weren't verified yet, like an API
+// key header, never pick the bucket since a client could send a different one
+// with every request. Every response carries the
+// RateLimit-* headers and rejected requests receive a 429 status with a
+// Retry-After header. A rule with no rate doesn't limit requests.
+func RateLimit(cfg RateLimitConfig) web.Middleware {
+	if cfg.Rate <= 0 {
+		return nil
+	}
+
+	limiter := ratelimit.New(cfg.Rate, cfg.Burst)
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			d := limiter.Allow(clientKey(ctx, r, cfg.TrustedProxies), web.GetTime(ctx))
+
+			metrics.AddRateLimit(ctx, cfg.Name, d.Allowed)
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			w.Header().Set("RateLimit-Reset", seconds(d.Reset))
+
+			if !d.Allowed {
+				w.Header().Set("Retry-After", seconds(d.RetryAfter))
+				return web.NewError(errors.New("rate limit exceeded"), http.StatusTooManyRequests)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// ParseTrustedProxies parses a list of addresses and networks in CIDR
+// notation.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// =============================================================================
+
+// clientKey returns the key identifying the client making the request. Only
+// authenticated claims identify the client, unauthenticated requests are
+// identified by their address.
+func clientKey(ctx context.Context, r *http.Request, trusted []netip.Prefix) string {
+	if claims := auth.GetClaims(ctx); claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+
+	return "ip:" + clientIP(r, trusted)
+}
+
+// clientIP returns the address of the client. When the request comes from a
+// trusted proxy, the X-Forwarded-For header is walked from the right and the
+// first address that isn't a trusted proxy is the client.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(remote, trusted) {
+		return host
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+
+		if !isTrusted(addr, trusted) {
+			return addr.String()
+		}
+		remote = addr
+	}
+
+	return remote.String()
+}
+
+// isTrusted reports if the address belongs to a trusted proxy.
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// seconds formats the duration as whole seconds, rounding up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,114 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/qcbit/service/foundation/web"
+
+	"github.com/qcbit/service/business/web/auth"
+)
+
+func Test_ClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("Should be able to parse the proxies: %s", err)
+	}
+
+	tt := []struct {
+		name   string
+		remote string
+		xff    []string
+		exp    string
+	}{
+		{name: "direct", remote: "203.0.113.7:4000", exp: "203.0.113.7"},
+		{name: "untrusted", remote: "203.0.113.7:4000", xff: []string{"198.51.100.1"}, exp: "203.0.113.7"},
+		{name: "proxy", remote: "10.1.2.3:4000", xff: []string{"198.51.100.1"}, exp: "198.51.100.1"},
+		{name: "chain", remote: "10.1.2.3:4000", xff: []string{"1.1.1.1, 198.51.100.1", "192.168.1.1"}, exp: "198.51.100.1"},
+		{name: "spoofed", remote: "10.1.2.3:4000", xff: []string{"bogus, 198.51.100.1"}, exp: "198.51.100.1"},
+		{name: "onlyproxies", remote: "10.1.2.3:4000", xff: []string{"10.9.9.9"}, exp: "10.9.9.9"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tst.remote
+			for _, v := range tst.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := clientIP(r, trusted); got != tst.exp {
+				t.Errorf("Should find the client address: got %s, exp %s", got, tst.exp)
+			}
+		})
+	}
+}
+
+func Test_RateLimit(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+
+	h := RateLimit(RateLimitConfig{Name: "test", Rate: 1, Burst: 1})(handler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	w := httptest.NewRecorder()
+	if err := h(context.Background(), w, r); err != nil {
+		t.Fatalf("Should allow the first request: %s", err)
+	}
+
+	if w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Should set the rate limit headers: %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	err := h(context.Background(), w, r)
+	if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusTooManyRequests {
+		t.Fatalf("Should reject the second request: %v", err)
+	}
+
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Should set the Retry-After header: %v", w.Header())
+	}
+}
+
+func Test_RateLimitUnverifiedKeys(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+
+	h := RateLimit(RateLimitConfig{Name: "test", Rate: 1, Burst: 2})(handler)
+
+	// A client rotating bogus API keys shares the bucket of its address.
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(auth.APIKeyHeader, uuid.NewString())
+
+		err := h(context.Background(), httptest.NewRecorder(), r)
+
+		switch i {
+		case 2:
+			if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusTooManyRequests {
+				t.Errorf("Should reject requests rotating API keys: %v", err)
+			}
+		default:
+			if err != nil {
+				t.Errorf("Should allow request %d: %s", i, err)
+			}
+		}
+	}
+
+	// Authenticated clients have a bucket of their own.
+	var claims auth.Claims
+	claims.Subject = uuid.NewString()
+	ctx := auth.SetClaims(context.Background(), claims)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := h(ctx, httptest.NewRecorder(), r); err != nil {
+		t.Errorf("Should allow an authenticated client from the same address: %s", err)
+	}
+}
@@ -0,0 +1,81 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/qcbit/service/foundation/web"
+
+	"github.com/qcbit/service/business/web/metrics"
+)
+
+// LoadShedConfig describes how many requests a route group handles at once.
+type LoadShedConfig struct {
+
+	// Name identifies the route group in the metrics.
+	Name string
+
+	// MaxInFlight is the number of requests handled concurrently.
+	MaxInFlight int
+
+	// QueueTimeout is how long a request waits for one of the in-flight
+	// requests to complete before it is shed.
+	QueueTimeout time.Duration
+}
+
+// LoadShed caps the number of requests handled concurrently. Requests beyond
+// the cap wait for their turn up to the queue timeout and are then rejected
+// with a 503 status and a Retry-After header, so a slow dependency can't pile
+// up requests until the service runs out of resources. A config with no cap
+// doesn't shed requests.
+func LoadShed(cfg LoadShedConfig) web.Middleware {
+	if cfg.MaxInFlight <= 0 {
+		return nil
+	}
+
+	slots := make(chan struct{}, cfg.MaxInFlight)
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			select {
+			case slots <- struct{}{}:
+
+			default:
+				if !wait(ctx, slots, cfg.QueueTimeout) {
+					metrics.AddShed(ctx, cfg.Name)
+
+					w.Header().Set("Retry-After", "1")
+					return web.NewError(errors.New("service is overloaded, try again later"), http.StatusServiceUnavailable)
+				}
+			}
+			defer func() { <-slots }()
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// wait waits for a slot to become available and reports if it was taken.
+func wait(ctx context.Context, slots chan struct{}, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,82 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+func Test_LoadShed(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+
+	h := LoadShed(LoadShedConfig{Name: "test", MaxInFlight: 1, QueueTimeout: 10 * time.Millisecond})(handler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	done := make(chan error)
+	go func() {
+		done <- h(context.Background(), httptest.NewRecorder(), r)
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	err := h(context.Background(), w, r)
+	if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("Should shed the request over the cap: %v", err)
+	}
+
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("Should set the Retry-After header: %v", w.Header())
+	}
+
+	release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatalf("Should handle the request: %v", err)
+	}
+
+	// A queued request runs once the in-flight request completes.
+	h = LoadShed(LoadShedConfig{Name: "test", MaxInFlight: 1, QueueTimeout: time.Minute})(handler)
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- h(context.Background(), httptest.NewRecorder(), r)
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		<-started
+		release <- struct{}{}
+		if err := <-done; err != nil {
+			t.Errorf("Should handle the queued request: %v", err)
+		}
+	}
+}
+
+func Test_Deadline(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	h := Deadline("test", time.Millisecond)(handler)
+
+	err := h(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if webErr := web.GetError(err); webErr == nil || webErr.Status != http.StatusGatewayTimeout {
+		t.Fatalf("Should answer an expired request with a 504 status: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package v1
+
+import "net/http"
+
+// ProblemContentType is the media type of problem details.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetail is the form used for API responses from failures in the API
+// when clients ask for RFC 7807 problem details. Code is a stable machine
+// readable code for the failure and Fields holds the fields that failed
+// validation.
+type ProblemDetail struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// Set of codes reported for failures that don't have a more specific code.
+const (
+	CodeValidation           = "validation_failed"
+	CodeBadRequest           = "bad_request"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnprocessable        = "unprocessable_entity"
+	CodeLocked               = "locked"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+	CodeUnavailable          = "service_unavailable"
+	CodeTimeout              = "timeout"
+)
+
+// statusCodes maps the statuses to their codes.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:   CodeUnprocessable,
+	http.StatusLocked:                CodeLocked,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+	http.StatusGatewayTimeout:        CodeTimeout,
+}
+
+// StatusCode returns the code reported for a failure with the status.
+func StatusCode(status int) string {
+	if code, exists := statusCodes[status]; exists {
+		return code
+	}
+
+	switch {
+	case status >= http.StatusInternalServerError:
+		return CodeInternal
+	default:
+		return CodeBadRequest
+	}
+}
@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrorMapping describes the response for an error of the core packages.
+// Message is shown to clients in place of the message of the error.
+type ErrorMapping struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// registry holds the mappings of the registered errors in the order they
+// were registered.
+var registry struct {
+	mu       sync.RWMutex
+	errs     []error
+	mappings []ErrorMapping
+}
+
+// RegisterError registers the response for a sentinel error of a core
+// package. Registering an error again replaces its mapping. Errors are
+// resolved by mid.Errors through their wrapping, so handlers can return core
+// errors without mapping them to a RequestError themselves.
+func RegisterError(err error, status int, code string, message string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	mapping := ErrorMapping{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+
+	for i, e := range registry.errs {
+		if e == err {
+			registry.mappings[i] = mapping
+			return
+		}
+	}
+
+	registry.errs = append(registry.errs, err)
+	registry.mappings = append(registry.mappings, mapping)
+}
+
+// LookupError returns the mapping of the first registered error found in the
+// chain of the error.
+func LookupError(err error) (ErrorMapping, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	for i, e := range registry.errs {
+		if errors.Is(err, e) {
+			return registry.mappings[i], true
+		}
+	}
+
+	return ErrorMapping{}, false
+}
+
+// RegisteredErrors returns the errors with a registered mapping.
+func RegisteredErrors() []error {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	errs := make([]error, len(registry.errs))
+	copy(errs, registry.errs)
+
+	return errs
+}
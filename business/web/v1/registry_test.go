@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func Test_Registry(t *testing.T) {
+	errMissing := errors.New("widget not found")
+	RegisterError(errMissing, http.StatusNotFound, "widget_not_found", "widget not found")
+
+	err := fmt.Errorf("querybyid: id[1]: %w", errMissing)
+
+	mapping, exists := LookupError(err)
+	if !exists {
+		t.Fatal("Should resolve the wrapped error")
+	}
+
+	if mapping.Status != http.StatusNotFound || mapping.Code != "widget_not_found" {
+		t.Errorf("Should return the registered mapping: %+v", mapping)
+	}
+
+	RegisterError(errMissing, http.StatusGone, "widget_gone", "widget is gone")
+	if mapping, _ := LookupError(err); mapping.Status != http.StatusGone {
+		t.Errorf("Should replace the mapping of an error registered again: %+v", mapping)
+	}
+
+	if _, exists := LookupError(errors.New("widget not found")); exists {
+		t.Error("Should only resolve the registered error itself")
+	}
+}
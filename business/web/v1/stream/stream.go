@@ -0,0 +1,202 @@
+// Package stream provides support for streaming changes to the domain to
+// clients as they happen.
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// Set of event types streamed to clients.
+const (
+	TypeUserCreated    = "user.created"
+	TypeUserUpdated    = "user.updated"
+	TypeUserDeleted    = "user.deleted"
+	TypeProductCreated = "product.created"
+	TypeProductUpdated = "product.updated"
+	TypeProductDeleted = "product.deleted"
+)
+
+// Set of default values used when the Config doesn't specify a value.
+const (
+	defaultReplaySize = 1000
+	defaultBufferSize = 64
+)
+
+// Event represents a change to the domain. Owner is the id of the user the
+// changed entity belongs to, callers that aren't admins only receive the
+// events they own.
+type Event struct {
+	ID    uint64
+	Type  string
+	Owner string
+	Data  any
+	Time  time.Time
+}
+
+// Config represents the settings of the broker.
+type Config struct {
+
+	// ReplaySize is the number of recent events kept for clients resuming
+	// the stream.
+	ReplaySize int
+
+	// BufferSize is the number of events a subscriber can fall behind before
+	// it is dropped. Dropped clients reconnect and resume from the replay
+	// buffer.
+	BufferSize int
+}
+
+// Broker delivers published events to the subscribers and keeps the most
+// recent events so clients can resume where they left off.
+type Broker struct {
+	cfg Config
+
+	mu     sync.Mutex
+	lastID uint64
+	replay []Event
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker constructs a broker for publishing events.
+func NewBroker(cfg Config) *Broker {
+	if cfg.ReplaySize <= 0 {
+		cfg.ReplaySize = defaultReplaySize
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+
+	// Ids start from the time the broker is constructed, so ids sent to
+	// clients before a restart are older than every id sent after it and
+	// those clients are told they missed events.
+	return &Broker{
+		cfg:    cfg,
+		lastID: uint64(time.Now().UnixMicro()),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the next id to the event and delivers it to the
+// subscribers. Subscribers that fell too far behind are dropped so a slow
+// client never blocks the publisher.
+func (b *Broker) Publish(typ string, owner string, data any) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	ev := Event{
+		ID:    b.lastID,
+		Type:  typ,
+		Owner: owner,
+		Data:  data,
+		Time:  time.Now(),
+	}
+
+	if len(b.replay) == b.cfg.ReplaySize {
+		copy(b.replay, b.replay[1:])
+		b.replay = b.replay[:len(b.replay)-1]
+	}
+	b.replay = append(b.replay, ev)
+
+	for sub := range b.subs {
+		select {
+		case sub.events <- ev:
+		default:
+			b.remove(sub)
+		}
+	}
+
+	return ev
+}
+
+// Subscribe starts receiving the events published from now on. When resume is
+// true, the events published after lastID that are still in the replay buffer
+// are returned, and Missed on the subscription reports that older events were
+// already discarded.
+func (b *Broker) Subscribe(lastID uint64, resume bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := Subscription{
+		broker: b,
+		events: make(chan Event, b.cfg.BufferSize),
+	}
+
+	if b.closed {
+		close(sub.events)
+		return &sub
+	}
+
+	if resume {
+		for _, ev := range b.replay {
+			if ev.ID > lastID {
+				sub.Replay = append(sub.Replay, ev)
+			}
+		}
+
+		// The client missed events when the first event it needs is gone.
+		oldest := b.lastID + 1
+		if len(b.replay) > 0 {
+			oldest = b.replay[0].ID
+		}
+		sub.Missed = lastID+1 < oldest || lastID > b.lastID
+	}
+
+	b.subs[&sub] = struct{}{}
+
+	return &sub
+}
+
+// Close drops every subscriber and rejects new ones, so the streams end
+// before the server shuts down.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// remove drops the subscriber, the caller must hold the lock.
+func (b *Broker) remove(sub *Subscription) {
+	if _, exists := b.subs[sub]; !exists {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.events)
+}
+
+// =============================================================================
+
+// Subscription receives the events published to a broker.
+type Subscription struct {
+	broker *Broker
+	events chan Event
+
+	// Replay holds the events the client missed since the event it resumed
+	// from, oldest first.
+	Replay []Event
+
+	// Missed reports the client resumed from an event that is no longer in
+	// the replay buffer, so events were lost and the client must reload.
+	Missed bool
+}
+
+// Events returns the channel delivering the events. The channel is closed
+// when the subscriber is dropped for falling behind or the broker is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops receiving events.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.remove(s)
+}
@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"testing"
+)
+
+func Test_Subscribe(t *testing.T) {
+	b := NewBroker(Config{ReplaySize: 3, BufferSize: 2})
+
+	var ids []uint64
+	for i := 0; i < 4; i++ {
+		ids = append(ids, b.Publish(TypeUserCreated, "owner", i).ID)
+	}
+
+	tt := []struct {
+		name   string
+		lastID uint64
+		resume bool
+		replay int
+		missed bool
+	}{
+		{name: "new", lastID: 0, resume: false, replay: 0, missed: false},
+		{name: "current", lastID: ids[3], resume: true, replay: 0, missed: false},
+		{name: "inbuffer", lastID: ids[1], resume: true, replay: 2, missed: false},
+		{name: "oldest", lastID: ids[0], resume: true, replay: 3, missed: false},
+		{name: "discarded", lastID: ids[0] - 1, resume: true, replay: 3, missed: true},
+		{name: "restart", lastID: ids[3] + 10, resume: true, replay: 0, missed: true},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			sub := b.Subscribe(tst.lastID, tst.resume)
+			defer sub.Close()
+
+			if len(sub.Replay) != tst.replay {
+				t.Errorf("Should replay %d events: got %d", tst.replay, len(sub.Replay))
+			}
+			if sub.Missed != tst.missed {
+				t.Errorf("Should report missed %t: got %t", tst.missed, sub.Missed)
+			}
+			for i := 1; i < len(sub.Replay); i++ {
+				if sub.Replay[i].ID <= sub.Replay[i-1].ID {
+					t.Errorf("Should replay events in order: %d after %d", sub.Replay[i].ID, sub.Replay[i-1].ID)
+				}
+			}
+		})
+	}
+}
+
+func Test_Publish(t *testing.T) {
+	b := NewBroker(Config{BufferSize: 2})
+
+	fast := b.Subscribe(0, false)
+	slow := b.Subscribe(0, false)
+
+	// The fast subscriber keeps up, the slow one never reads.
+	for i := 0; i < 3; i++ {
+		ev := b.Publish(TypeProductUpdated, "owner", i)
+
+		got := <-fast.Events()
+		if got.ID != ev.ID || got.Data != i {
+			t.Errorf("Should receive event %d: got %d", ev.ID, got.ID)
+		}
+	}
+
+	// The slow subscriber received the buffered events before being dropped.
+	var n int
+	for range slow.Events() {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Should buffer 2 events for the slow subscriber: got %d", n)
+	}
+
+	b.Close()
+
+	if _, open := <-fast.Events(); open {
+		t.Errorf("Should end the subscriptions when the broker closes.")
+	}
+
+	if _, open := <-b.Subscribe(0, false).Events(); open {
+		t.Errorf("Should end new subscriptions once the broker is closed.")
+	}
+
+	// Closing twice is harmless.
+	fast.Close()
+	slow.Close()
+}
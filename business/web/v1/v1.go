@@ -12,16 +12,18 @@ type ErrorResponse struct {
 }
 
 // RequestError is used to pass an error during the request through the
-// application with web specific context.
+// application with web specific context. Code is the stable code reported to
+// clients, the code of the status is used when it's empty.
 type RequestError struct {
 	Err    error
 	Status int
+	Code   string
 }
 
 // NewRequestError wraps a provided error with an HTTP status code. This
 // function should be used when handlers encounter expected errors.
 func NewRequestError(err error, status int) error {
-	return &RequestError{err, status}
+	return &RequestError{Err: err, Status: status}
 }
 
 // Error implements the error interface. It uses the default message of the
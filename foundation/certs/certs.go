@@ -0,0 +1,180 @@
+// Package certs provides support for serving TLS with certificates that are
+// reloaded from disk without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader holds a certificate and its key loaded from disk. The files are
+// loaded again once they change, so rotated certificates are served to new
+// connections without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	certMod time.Time
+	keyMod  time.Time
+	cert    atomic.Pointer[tls.Certificate]
+}
+
+// NewReloader constructs a reloader for the certificate and key files, both
+// PEM encoded. The files must hold a valid key pair.
+func NewReloader(certFile string, keyFile string) (*Reloader, error) {
+	r := Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Reload loads the key pair again if either file changed since it was last
+// loaded, and reports if the certificate was swapped. When the files don't
+// hold a valid key pair, for example while only one of them is rewritten, the
+// current certificate is kept and the reload is tried again on the next call.
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certMod, err := modTime(r.certFile)
+	if err != nil {
+		return false, err
+	}
+
+	keyMod, err := modTime(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	if r.cert.Load() != nil && certMod.Equal(r.certMod) && keyMod.Equal(r.keyMod) {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("loading key pair: %w", err)
+	}
+
+	r.cert.Store(&cert)
+	r.certMod = certMod
+	r.keyMod = keyMod
+
+	return true, nil
+}
+
+// Watch checks the files for changes at every interval until the context is
+// done. Errors are reported to the onError function and the current
+// certificate is kept.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if _, err := r.Reload(); err != nil {
+			onError(err)
+		}
+	}
+}
+
+// GetCertificate returns the current certificate, for use as the
+// GetCertificate function of a tls.Config.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := r.cert.Load()
+	if cert == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return cert, nil
+}
+
+// ServerConfig returns a TLS configuration serving the certificates of the
+// reloader with modern defaults. HTTP/2 is negotiated over ALPN.
+func ServerConfig(r *Reloader) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+		},
+
+		// Only forward secret AEAD suites are offered for TLS 1.2, the
+		// suites of TLS 1.3 aren't configurable.
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// VerifyClientCerts changes the configuration to verify the certificates
+// clients present against the authorities in the PEM encoded file. Clients
+// without a certificate can still connect, RequireClientCert rejects them on
+// the routes that need a certificate.
+func VerifyClientCerts(cfg *tls.Config, caFile string) error {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("reading client ca: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+
+	return nil
+}
+
+// RequireClientCert wraps the handler so only clients that presented a
+// verified certificate reach it, others receive a 403 status.
+func RequireClientCert(handler http.Handler) http.Handler {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "client certificate required", http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(h)
+}
+
+// =============================================================================
+
+// modTime returns the time the file was last modified.
+func modTime(file string) (time.Time, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("stat: %w", err)
+	}
+	return info.ModTime(), nil
+}
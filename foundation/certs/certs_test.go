@@ -0,0 +1,275 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_ServeReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	ca.issue(t, 1, certFile, keyFile, time.Now())
+
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Should be able to load the key pair: %s", err)
+	}
+
+	addr := serve(t, ServerConfig(r), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	client := ca.client(nil)
+
+	resp := get(t, client, addr)
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Should negotiate HTTP/2: got %s", resp.Proto)
+	}
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 1 {
+		t.Errorf("Should serve the first certificate: got serial %d", serial)
+	}
+
+	if swapped, err := r.Reload(); err != nil || swapped {
+		t.Errorf("Should not reload unchanged files: swapped %t, err %v", swapped, err)
+	}
+
+	// A half written pair keeps the current certificate.
+	if err := os.WriteFile(keyFile, []byte("partial"), 0600); err != nil {
+		t.Fatalf("Should be able to write the key: %s", err)
+	}
+	touch(t, time.Now().Add(time.Minute), keyFile)
+
+	if _, err := r.Reload(); err == nil {
+		t.Errorf("Should fail to reload an invalid key pair.")
+	}
+
+	ca.issue(t, 2, certFile, keyFile, time.Now().Add(2*time.Minute))
+
+	if swapped, err := r.Reload(); err != nil || !swapped {
+		t.Fatalf("Should reload the rotated files: swapped %t, err %v", swapped, err)
+	}
+
+	client.CloseIdleConnections()
+
+	resp = get(t, client, addr)
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 2 {
+		t.Errorf("Should serve the rotated certificate: got serial %d", serial)
+	}
+}
+
+func Test_VerifyClientCerts(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	ca.issue(t, 1, certFile, keyFile, time.Now())
+
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, ca.pem, 0600); err != nil {
+		t.Fatalf("Should be able to write the ca: %s", err)
+	}
+
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Should be able to load the key pair: %s", err)
+	}
+
+	cfg := ServerConfig(r)
+	if err := VerifyClientCerts(cfg, caFile); err != nil {
+		t.Fatalf("Should be able to load the client ca: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/probe", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/", RequireClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	srv := serve(t, cfg, mux)
+
+	anonymous := ca.client(nil)
+
+	if resp := get(t, anonymous, srv+"/probe"); resp.StatusCode != http.StatusOK {
+		t.Errorf("Should let a client without a certificate reach an open route: got %d", resp.StatusCode)
+	}
+
+	if resp := get(t, anonymous, srv+"/debug/vars"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Should reject a client without a certificate on a protected route: got %d", resp.StatusCode)
+	}
+
+	clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Should be able to load the client certificate: %s", err)
+	}
+
+	if resp := get(t, ca.client(&clientCert), srv+"/debug/vars"); resp.StatusCode != http.StatusOK {
+		t.Errorf("Should let a client with a certificate reach a protected route: got %d", resp.StatusCode)
+	}
+
+	// A certificate from another authority fails the handshake.
+	other := newCA(t)
+	other.issue(t, 3, certFile, keyFile, time.Now())
+
+	otherCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Should be able to load the other certificate: %s", err)
+	}
+
+	otherClient := ca.client(&otherCert)
+	if resp, err := otherClient.Get(srv + "/probe"); err == nil {
+		resp.Body.Close()
+		t.Errorf("Should reject a certificate from an unknown authority.")
+	}
+}
+
+// =============================================================================
+
+// authority is a self-signed certificate authority issuing certificates for
+// the tests.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T) authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Should be able to create the ca: %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Should be able to parse the ca: %s", err)
+	}
+
+	return authority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue writes a certificate for localhost with the serial number, usable by
+// servers and clients, and sets the modification time of the files.
+func (a authority) issue(t *testing.T, serial int64, certFile string, keyFile string, mod time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatalf("Should be able to create the certificate: %s", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the key: %s", err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Should be able to write the certificate: %s", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("Should be able to write the key: %s", err)
+	}
+
+	touch(t, mod, certFile, keyFile)
+}
+
+// client returns a client trusting the authority, presenting the certificate
+// when one is provided.
+func (a authority) client(cert *tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.cert)
+
+	cfg := tls.Config{RootCAs: pool}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &cfg,
+			ForceAttemptHTTP2: true,
+		},
+	}
+}
+
+// serve starts a TLS server with the configuration and handler and returns
+// its URL.
+func serve(t *testing.T, cfg *tls.Config, handler http.Handler) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Should be able to listen: %s", err)
+	}
+
+	srv := http.Server{
+		Handler:   handler,
+		TLSConfig: cfg,
+		ErrorLog:  log.New(io.Discard, "", 0),
+	}
+	go srv.ServeTLS(ln, "", "")
+	t.Cleanup(func() { srv.Close() })
+
+	return "https://" + ln.Addr().String()
+}
+
+func get(t *testing.T, client *http.Client, url string) *http.Response {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Should be able to get: %s", err)
+	}
+	resp.Body.Close()
+
+	return resp
+}
+
+func touch(t *testing.T, mod time.Time, files ...string) {
+	for _, file := range files {
+		if err := os.Chtimes(file, mod, mod); err != nil {
+			t.Fatalf("Should be able to set the modification time: %s", err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package lifecycle provides support for the orderly shutdown of a service.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config provides the settings for shutting down the service.
+type Config struct {
+
+	// DrainPeriod is how long the service keeps serving requests after it
+	// reports it isn't ready, so load balancers stop sending it traffic
+	// before the servers are stopped.
+	DrainPeriod time.Duration
+
+	// HookTimeout is how long a shutdown hook is given when it's registered
+	// without a timeout.
+	HookTimeout time.Duration
+
+	// Timeout bounds the whole shutdown, drain period included. The servers
+	// and hooks are given what is left of it when that's shorter than their
+	// own timeouts. Zero means the shutdown isn't bounded.
+	Timeout time.Duration
+
+	// OnStep is called as every step of the shutdown starts.
+	OnStep func(step string)
+}
+
+// Hook is a function run during the shutdown. It should return once the
+// context is done.
+type Hook func(ctx context.Context) error
+
+// server is a registered http server.
+type server struct {
+	name    string
+	srv     *http.Server
+	timeout time.Duration
+}
+
+// hook is a registered shutdown hook.
+type hook struct {
+	name    string
+	fn      Hook
+	timeout time.Duration
+}
+
+// Manager reports the readiness of the service and shuts it down in order:
+// readiness is flipped to not ready, the drain period passes, the servers are
+// stopped in the order they were added, and the hooks are run in the reverse
+// order they were added.
+type Manager struct {
+	cfg   Config
+	ready atomic.Bool
+
+	mu      sync.Mutex
+	servers []server
+	hooks   []hook
+}
+
+// New constructs a manager. The service isn't ready until SetReady is called.
+func New(cfg Config) *Manager {
+	if cfg.HookTimeout <= 0 {
+		cfg.HookTimeout = 5 * time.Second
+	}
+	if cfg.OnStep == nil {
+		cfg.OnStep = func(string) {}
+	}
+
+	return &Manager{
+		cfg: cfg,
+	}
+}
+
+// Ready reports if the service is ready to receive traffic.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// SetReady sets if the service is ready to receive traffic.
+func (m *Manager) SetReady(ready bool) {
+	m.ready.Store(ready)
+}
+
+// AddServer registers a server to be shut down. Requests in flight are given
+// the timeout to complete before the server is closed.
+func (m *Manager) AddServer(name string, srv *http.Server, timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.servers = append(m.servers, server{name: name, srv: srv, timeout: timeout})
+}
+
+// AddHook registers a hook to run once the servers are stopped. Hooks run
+// like deferred calls, the last hook added runs first, so resources are
+// released after the workers using them are stopped. A hook without a
+// timeout is given the configured HookTimeout.
+func (m *Manager) AddHook(name string, timeout time.Duration, fn Hook) {
+	if timeout <= 0 {
+		timeout = m.cfg.HookTimeout
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook{name: name, fn: fn, timeout: timeout})
+}
+
+// Shutdown shuts the service down. The drain period is skipped when the
+// service wasn't ready or the context is done before it passes, so a second
+// signal can cut it short. The servers and hooks are stopped with their own
+// timeouts, within the Timeout of the whole shutdown. Every step is attempted
+// and their errors are returned together.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	bound := context.Background()
+	if m.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		bound, cancel = context.WithTimeout(bound, m.cfg.Timeout)
+		defer cancel()
+	}
+
+	if m.ready.Swap(false) && m.cfg.DrainPeriod > 0 {
+		m.cfg.OnStep("draining")
+
+		timer := time.NewTimer(m.cfg.DrainPeriod)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		case <-bound.Done():
+			timer.Stop()
+		}
+	}
+
+	m.mu.Lock()
+	servers := m.servers
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	var errs []error
+
+	for _, s := range servers {
+		m.cfg.OnStep("stopping " + s.name)
+
+		if err := stopServer(bound, s); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", s.name, err))
+		}
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+
+		m.cfg.OnStep("running " + h.name)
+
+		if err := runHook(bound, h); err != nil {
+			errs = append(errs, fmt.Errorf("running %s: %w", h.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// =============================================================================
+
+// stopServer gracefully shuts the server down, closing it when the requests
+// in flight don't complete in time or the context is done.
+func stopServer(ctx context.Context, s server) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.srv.Close()
+		return err
+	}
+
+	return nil
+}
+
+// runHook runs the hook, giving up on it once its timeout passes or the
+// context is done so a hook ignoring its context can't hold up the shutdown.
+func runHook(ctx context.Context, h hook) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,146 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Shutdown(t *testing.T) {
+	var mu sync.Mutex
+	var steps []string
+
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, step)
+	}
+
+	m := New(Config{DrainPeriod: 50 * time.Millisecond, OnStep: record})
+
+	// A request in flight when the shutdown starts must still be served.
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		record("served")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Should be able to listen: %s", err)
+	}
+
+	api := http.Server{Handler: handler}
+	go api.Serve(ln)
+
+	m.AddServer("api", &api, time.Second)
+	m.AddHook("database", 0, func(ctx context.Context) error {
+		record("closed database")
+		return nil
+	})
+	m.AddHook("workers", 0, func(ctx context.Context) error {
+		record("stopped workers")
+		return errors.New("worker failed")
+	})
+	m.SetReady(true)
+
+	go http.Get("http://" + ln.Addr().String())
+	<-started
+
+	err = m.Shutdown(context.Background())
+	if err == nil || err.Error() != "running workers: worker failed" {
+		t.Errorf("Should return the errors of the hooks: %v", err)
+	}
+
+	if m.Ready() {
+		t.Errorf("Should not be ready after the shutdown.")
+	}
+
+	exp := []string{"draining", "stopping api", "served", "running workers", "stopped workers", "running database", "closed database"}
+	if diff := cmp.Diff(steps, exp); diff != "" {
+		t.Errorf("Should shut down in order:\n%s", diff)
+	}
+}
+
+func Test_Readiness(t *testing.T) {
+	m := New(Config{DrainPeriod: time.Hour})
+
+	var readyDuringHook bool
+	m.AddHook("check", 0, func(ctx context.Context) error {
+		readyDuringHook = m.Ready()
+		return nil
+	})
+
+	m.SetReady(true)
+
+	// The drain is cut short by the context so the test doesn't wait.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for m.Ready() {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("Should be able to shutdown: %s", err)
+	}
+
+	if readyDuringHook {
+		t.Errorf("Should report not ready before the hooks run.")
+	}
+}
+
+func Test_HookTimeout(t *testing.T) {
+	m := New(Config{})
+
+	var ran bool
+	m.AddHook("after", 0, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	m.AddHook("stuck", 10*time.Millisecond, func(ctx context.Context) error {
+		select {}
+	})
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Should time out the stuck hook: %v", err)
+	}
+
+	if !ran {
+		t.Errorf("Should run the hooks after a stuck hook.")
+	}
+}
+
+func Test_ShutdownTimeout(t *testing.T) {
+	m := New(Config{
+		DrainPeriod: time.Hour,
+		Timeout:     50 * time.Millisecond,
+	})
+
+	for _, name := range []string{"first", "second"} {
+		m.AddHook(name, time.Hour, func(ctx context.Context) error {
+			select {}
+		})
+	}
+	m.SetReady(true)
+
+	start := time.Now()
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Should time out the stuck hooks: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Should stop within the timeout of the whole shutdown: took %v", elapsed)
+	}
+}
@@ -0,0 +1,227 @@
+// Package openapi generates an OpenAPI 3.1 document from the routes
+// registered with a web.App.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qcbit/service/foundation/web"
+)
+
+// Version is the version of the OpenAPI specification documents follow.
+const Version = "3.1.0"
+
+// Document represents an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info provides metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem map[string]*Operation
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	AuthRules   []string              `json:"x-auth-rules,omitempty"`
+}
+
+// Parameter describes a single operation parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes a request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response from an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType provides the schema for a media type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable objects of the document.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme defines a security scheme that can be used by operations.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+// Config provides the information needed to generate a document. Operations
+// that require authentication reference every configured security scheme.
+type Config struct {
+	Info            Info
+	SecuritySchemes map[string]SecurityScheme
+}
+
+// Generate constructs the document for the specified routes.
+func Generate(cfg Config, routes []*web.Route) Document {
+	g := generator{
+		schemas: make(map[string]*Schema),
+	}
+
+	var security []map[string][]string
+	for name := range cfg.SecuritySchemes {
+		security = append(security, map[string][]string{name: {}})
+	}
+	sortSecurity(security)
+
+	doc := Document{
+		OpenAPI: Version,
+		Info:    cfg.Info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas:         g.schemas,
+			SecuritySchemes: cfg.SecuritySchemes,
+		},
+	}
+
+	for _, route := range routes {
+		path := Path(route.Path)
+
+		item, exists := doc.Paths[path]
+		if !exists {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+
+		op := Operation{
+			Summary:     route.Summary,
+			Tags:        route.Tags,
+			OperationID: operationID(route),
+			Responses:   make(map[string]Response),
+			AuthRules:   route.AuthRules,
+		}
+
+		for _, param := range route.Params {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     param,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+
+		for _, param := range route.QueryParams {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+
+		if route.RequestType != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{
+					"application/json": {Schema: g.schema(route.RequestType)},
+				},
+			}
+		}
+
+		for _, status := range route.Statuses() {
+			resp := Response{
+				Description: http.StatusText(status),
+			}
+			if typ := route.Responses[status]; typ != nil {
+				resp.Content = map[string]MediaType{
+					"application/json": {Schema: g.schema(typ)},
+				}
+			}
+			op.Responses[strconv.Itoa(status)] = resp
+		}
+
+		if len(op.Responses) == 0 {
+			op.Responses["200"] = Response{Description: http.StatusText(http.StatusOK)}
+		}
+
+		if route.Secured {
+			op.Security = security
+		}
+
+		(*item)[strings.ToLower(route.Method)] = &op
+	}
+
+	return doc
+}
+
+// Path converts a route path into an OpenAPI path template.
+func Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// operationID constructs a unique identifier for the route.
+func operationID(route *web.Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+
+	for _, segment := range strings.Split(route.Path, "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+
+	return b.String()
+}
+
+// sortSecurity orders the security requirements by scheme name so the
+// document is stable.
+func sortSecurity(security []map[string][]string) {
+	name := func(m map[string][]string) string {
+		for k := range m {
+			return k
+		}
+		return ""
+	}
+
+	sort.Slice(security, func(i, j int) bool {
+		return name(security[i]) < name(security[j])
+	})
+}
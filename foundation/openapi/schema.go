@@ -0,0 +1,252 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema represents a JSON Schema as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// Set of types with a dedicated representation.
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte{})
+)
+
+// nameRegEx matches the characters that are not allowed in component names.
+var nameRegEx = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// generator converts Go types into schemas, registering named struct types
+// as reusable components.
+type generator struct {
+	schemas map[string]*Schema
+}
+
+// schema returns the schema for the specified type.
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		name := componentName(t)
+		if _, exists := g.schemas[name]; !exists {
+
+			// Register a placeholder first so recursive types terminate.
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.object(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+// object returns the schema for a struct, using the json tags for property
+// names and the validate tags for constraints. Embedded structs without a
+// json name are flattened into the object.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := g.object(ft)
+				for k, v := range embedded.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := g.schema(field.Type)
+
+		s.Properties[name] = prop
+		if applyValidate(prop, field.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return &s
+}
+
+// jsonName returns the name of the field in JSON documents.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// applyValidate translates the validate tag into schema constraints and
+// reports if the field is required. Constraints on properties that refer to
+// a component are not applied since the component is shared.
+func applyValidate(s *Schema, tag string) bool {
+	var required bool
+
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+
+		if key == "required" {
+			required = true
+			continue
+		}
+
+		if s.Ref != "" {
+			continue
+		}
+
+		switch key {
+		case "email":
+			s.Format = "email"
+
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+
+		case "url", "uri":
+			s.Format = "uri"
+
+		case "oneof":
+			s.Enum = strings.Fields(value)
+
+		case "min", "gte":
+			setBound(s, value, true)
+
+		case "max", "lte":
+			setBound(s, value, false)
+
+		case "len":
+			setBound(s, value, true)
+			setBound(s, value, false)
+		}
+	}
+
+	return required
+}
+
+// setBound sets the lower or upper bound of the schema based on its type.
+func setBound(s *Schema, value string, lower bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+
+	switch s.Type {
+	case "string":
+		if lower {
+			s.MinLength = &i
+		} else {
+			s.MaxLength = &i
+		}
+
+	case "array":
+		if lower {
+			s.MinItems = &i
+		} else {
+			s.MaxItems = &i
+		}
+
+	case "integer", "number":
+		if lower {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	}
+}
+
+// componentName returns the name the type is registered under. Generic type
+// arguments are reduced to their unqualified names.
+func componentName(t reflect.Type) string {
+	name := t.Name()
+
+	if open := strings.Index(name, "["); open != -1 {
+		args := strings.Split(strings.TrimSuffix(name[open+1:], "]"), ",")
+		for i, arg := range args {
+			if dot := strings.LastIndex(arg, "."); dot != -1 {
+				arg = arg[dot+1:]
+			}
+			args[i] = arg
+		}
+		name = name[:open] + "_" + strings.Join(args, "_")
+	}
+
+	return nameRegEx.ReplaceAllString(name, "")
+}
@@ -0,0 +1,137 @@
+// Package ratelimit provides token bucket rate limiting for a set of keys.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled are removed so the
+// limiter doesn't grow with every key it has ever seen.
+const sweepInterval = time.Minute
+
+// Decision describes the outcome of a request against a bucket.
+type Decision struct {
+
+	// Allowed reports if the request can proceed.
+	Allowed bool
+
+	// Limit is the size of the bucket and Remaining the number of whole
+	// tokens left after the request.
+	Limit     int
+	Remaining int
+
+	// RetryAfter is how long a rejected request needs to wait for a token.
+	RetryAfter time.Duration
+
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// bucket holds the tokens available to a key.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter maintains a token bucket per key. Buckets hold up to burst tokens
+// and refill at rate tokens per second. Every request consumes a token.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New constructs a limiter with the specified refill rate in tokens per
+// second and bucket size.
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow consumes a token from the bucket for the key if one is available at
+// the specified time.
+func (l *Limiter) Allow(key string, now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	l.refill(b, now)
+
+	d := Decision{
+		Limit: int(l.burst),
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.duration(1 - b.tokens)
+	}
+
+	d.Remaining = int(math.Floor(b.tokens))
+	d.Reset = l.duration(l.burst - b.tokens)
+
+	return d
+}
+
+// Len returns the number of buckets tracked by the limiter.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+// =============================================================================
+
+// refill adds the tokens earned since the bucket was last used.
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+}
+
+// duration returns how long it takes to earn the specified number of tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	if tokens <= 0 || l.rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(tokens / l.rate * float64(time.Second)))
+}
+
+// sweep removes the buckets that are full again, since a new bucket for the
+// key would be identical.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Limiter(t *testing.T) {
+	l := New(1, 2)
+	now := time.Now()
+
+	for i, exp := range []int{1, 0} {
+		d := l.Allow("client", now)
+		if !d.Allowed || d.Remaining != exp || d.Limit != 2 {
+			t.Fatalf("request %d: Should be allowed with %d remaining: %+v", i, exp, d)
+		}
+	}
+
+	d := l.Allow("client", now)
+	if d.Allowed || d.RetryAfter != time.Second || d.Reset != 2*time.Second {
+		t.Fatalf("Should reject the request until a token is earned: %+v", d)
+	}
+
+	if d := l.Allow("other", now); !d.Allowed {
+		t.Fatalf("Should track every key separately: %+v", d)
+	}
+
+	if d := l.Allow("client", now.Add(time.Second)); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("Should allow the request after a token is earned: %+v", d)
+	}
+
+	// Once the buckets are full again they are swept.
+	l.Allow("client", now.Add(sweepInterval+time.Hour))
+	if l.Len() != 1 {
+		t.Errorf("Should remove the refilled buckets: got %d buckets", l.Len())
+	}
+}
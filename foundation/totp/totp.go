@@ -0,0 +1,124 @@
+// Package totp implements time-based one-time passwords as described in
+// RFC 6238 using HMAC-SHA1, the algorithm supported by authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Set of values used to generate codes. These are the values authenticator
+// apps assume when the provisioning URI doesn't specify them.
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	SecretSize = 20
+)
+
+// encoding is the base32 encoding used for secrets in provisioning URIs.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret generates a new random secret.
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, SecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("reading random: %w", err)
+	}
+
+	return secret, nil
+}
+
+// EncodeSecret returns the base32 representation of the secret used by
+// authenticator apps.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// DecodeSecret parses the base32 representation of a secret.
+func DecodeSecret(value string) ([]byte, error) {
+	secret, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(value, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("decoding secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// ProvisioningURI returns the otpauth URI that authenticator apps use to
+// enroll the secret, usually rendered as a QR code.
+func ProvisioningURI(issuer string, account string, secret []byte) string {
+	v := url.Values{}
+	v.Set("secret", EncodeSecret(secret))
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+
+	return u.String()
+}
+
+// Counter returns the time step the specified time falls in.
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the code for the secret at the specified time.
+func Code(secret []byte, t time.Time) string {
+	return generate(secret, Counter(t), Digits)
+}
+
+// Validate checks the code against the secret at the specified time. Codes
+// from up to skew time steps before or after are accepted to allow for clock
+// drift. On success the time step the code belongs to is returned so callers
+// can reject a code that has already been used.
+func Validate(secret []byte, code string, t time.Time, skew int) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	counter := Counter(t)
+	for i := -skew; i <= skew; i++ {
+		c := counter + int64(i)
+		if subtle.ConstantTimeCompare([]byte(generate(secret, c, Digits)), []byte(code)) == 1 {
+			return c, true
+		}
+	}
+
+	return 0, false
+}
+
+// generate implements the HOTP algorithm from RFC 4226 for the specified
+// counter and number of digits.
+func generate(secret []byte, counter int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
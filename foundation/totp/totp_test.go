@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 Appendix B for the SHA1 algorithm.
+func Test_RFC6238(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	tt := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tst := range tt {
+		got := generate(secret, Counter(time.Unix(tst.unix, 0)), 8)
+		if got != tst.code {
+			t.Errorf("time %d: got %s, exp %s", tst.unix, got, tst.code)
+		}
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatalf("Should be able to generate a secret: %s", err)
+	}
+
+	now := time.Now()
+	code := Code(secret, now.Add(-Period))
+
+	if _, ok := Validate(secret, code, now, 0); ok {
+		t.Errorf("Should reject a code from the previous time step without skew.")
+	}
+
+	counter, ok := Validate(secret, code, now, 1)
+	if !ok {
+		t.Fatalf("Should accept a code from the previous time step with skew.")
+	}
+
+	if exp := Counter(now) - 1; counter != exp {
+		t.Errorf("Should return the matched time step: got %d, exp %d", counter, exp)
+	}
+}
+
+func Test_Secret(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatalf("Should be able to generate a secret: %s", err)
+	}
+
+	got, err := DecodeSecret(EncodeSecret(secret))
+	if err != nil {
+		t.Fatalf("Should be able to decode the secret: %s", err)
+	}
+
+	if string(got) != string(secret) {
+		t.Errorf("Should round trip the secret.")
+	}
+}
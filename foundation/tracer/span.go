@@ -0,0 +1,133 @@
+package tracer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Set of span kinds as defined by zipkin. Spans without a kind describe
+// local work.
+const (
+	KindServer = "SERVER"
+	KindClient = "CLIENT"
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID  string
+	ID       string
+	ParentID string
+	Sampled  bool
+}
+
+// Span represents a single timed operation within a trace. Spans that aren't
+// sampled are still propagated so their children share the same trace, but
+// they are never exported. The methods of a nil Span do nothing so code can
+// trace unconditionally.
+type Span struct {
+	SpanContext
+	Name     string
+	Kind     string
+	Start    time.Time
+	Duration time.Duration
+
+	tracer *Tracer
+
+	mu    sync.Mutex
+	tags  map[string]string
+	ended bool
+}
+
+// SetTag records a key/value pair describing the span.
+func (s *Span) SetTag(key string, value string) {
+	if s == nil || !s.Sampled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tags == nil {
+		s.tags = make(map[string]string)
+	}
+	s.tags[key] = value
+}
+
+// SetError records the error on the span.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetTag("error", err.Error())
+}
+
+// End records the duration of the span and queues it for export. Calling End
+// more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.Duration = time.Since(s.Start)
+	s.mu.Unlock()
+
+	if s.Sampled && s.tracer != nil {
+		s.tracer.enqueue(s)
+	}
+}
+
+// Tags returns a copy of the tags recorded on the span.
+func (s *Span) Tags() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// =============================================================================
+
+type ctxKey int
+
+const key ctxKey = 1
+
+// FromContext returns the span stored in the context, or nil if the context
+// has no span.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(key).(*Span)
+	return s
+}
+
+// Start starts a span as a child of the span stored in the context and
+// returns a context holding the new span. When the context has no span there
+// is no trace to contribute to and a nil span is returned.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	s := Span{
+		SpanContext: SpanContext{
+			TraceID:  parent.TraceID,
+			ID:       newID(),
+			ParentID: parent.ID,
+			Sampled:  parent.Sampled,
+		},
+		Name:   name,
+		Start:  time.Now(),
+		tracer: parent.tracer,
+	}
+
+	return context.WithValue(ctx, key, &s), &s
+}
@@ -0,0 +1,210 @@
+// Package tracer provides support for recording the spans of distributed
+// traces and exporting them to a zipkin collector.
+package tracer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config provides the settings for recording and exporting spans.
+type Config struct {
+
+	// ServiceName identifies the service in the exported spans.
+	ServiceName string
+
+	// ReporterURL is the zipkin v2 endpoint spans are posted to, for example
+	// http://zipkin:9411/api/v2/spans.
+	ReporterURL string
+
+	// Probability is the fraction of new traces that are sampled. Traces
+	// started by a caller follow the caller's sampling decision.
+	Probability float64
+
+	// BatchSize is the number of spans exported in a single request, a
+	// partial batch is exported after FlushInterval.
+	BatchSize     int
+	FlushInterval time.Duration
+
+	// QueueSize is the number of ended spans waiting for export. Spans are
+	// dropped when the queue is full so tracing never blocks requests.
+	QueueSize int
+
+	// Client is the client used to post the spans.
+	Client *http.Client
+
+	// OnError is called when a batch can't be exported.
+	OnError func(err error)
+}
+
+// Tracer records spans and exports them in batches in the background.
+type Tracer struct {
+	cfg      Config
+	queue    chan *Span
+	shutdown chan struct{}
+	done     chan struct{}
+	once     sync.Once
+	dropped  atomic.Int64
+	exported atomic.Int64
+}
+
+// New constructs a tracer and starts the goroutine exporting the spans. The
+// tracer must be shut down to export the spans still queued.
+func New(cfg Config) *Tracer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10 * cfg.BatchSize
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(error) {}
+	}
+
+	t := Tracer{
+		cfg:      cfg,
+		queue:    make(chan *Span, cfg.QueueSize),
+		shutdown: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go t.run()
+
+	return &t
+}
+
+// Sample makes the sampling decision for a new trace.
+func (t *Tracer) Sample() bool {
+	return mrand.Float64() < t.cfg.Probability
+}
+
+// StartServer starts the span for a request received by the service using
+// the identifiers of the request's trace context, and returns a context
+// holding the span.
+func (t *Tracer) StartServer(ctx context.Context, name string, sc SpanContext) (context.Context, *Span) {
+	s := Span{
+		SpanContext: sc,
+		Name:        name,
+		Kind:        KindServer,
+		Start:       time.Now(),
+		tracer:      t,
+	}
+
+	return context.WithValue(ctx, key, &s), &s
+}
+
+// Dropped returns the number of spans dropped because the queue was full.
+func (t *Tracer) Dropped() int64 {
+	return t.dropped.Load()
+}
+
+// Exported returns the number of spans successfully exported.
+func (t *Tracer) Exported() int64 {
+	return t.exported.Load()
+}
+
+// Shutdown stops the tracer after exporting the queued spans, or returns
+// when the context is done.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.once.Do(func() {
+		close(t.shutdown)
+	})
+
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("tracer shutdown: %w", ctx.Err())
+	}
+}
+
+// =============================================================================
+
+// enqueue queues the span for export, dropping it if the queue is full.
+func (t *Tracer) enqueue(s *Span) {
+	select {
+	case t.queue <- s:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// run collects the queued spans into batches and exports them when a batch
+// is full or the flush interval passes.
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, t.cfg.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := t.export(batch); err != nil {
+			t.cfg.OnError(err)
+		} else {
+			t.exported.Add(int64(len(batch)))
+		}
+
+		batch = batch[:0]
+	}
+
+	add := func(s *Span) {
+		batch = append(batch, s)
+		if len(batch) >= t.cfg.BatchSize {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case s := <-t.queue:
+			add(s)
+
+		case <-ticker.C:
+			flush()
+
+		case <-t.shutdown:
+			for {
+				select {
+				case s := <-t.queue:
+					add(s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// newID generates a random 64 bit span id encoded as lowercase hex.
+func newID() string {
+	var b [8]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(fmt.Sprintf("reading random: %s", err))
+		}
+
+		if b != [8]byte{} {
+			return hex.EncodeToString(b[:])
+		}
+	}
+}
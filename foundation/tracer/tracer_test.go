@@ -0,0 +1,99 @@
+package tracer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Export(t *testing.T) {
+	var mu sync.Mutex
+	var spans []zipkinSpan
+	var batches int
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []zipkinSpan
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("Should receive zipkin spans: %s", err)
+		}
+
+		mu.Lock()
+		spans = append(spans, batch...)
+		batches++
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer collector.Close()
+
+	tr := New(Config{
+		ServiceName:   "sales-api",
+		ReporterURL:   collector.URL,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		OnError:       func(err error) { t.Errorf("Should export the spans: %s", err) },
+	})
+
+	sc := SpanContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		ID:      "00f067aa0ba902b7",
+		Sampled: true,
+	}
+
+	ctx, server := tr.StartServer(context.Background(), "GET /v1/users", sc)
+	server.SetTag("http.status_code", "200")
+
+	_, child := Start(ctx, "database.query")
+	child.End()
+
+	_, other := Start(ctx, "auth.opa")
+	other.End()
+	server.End()
+
+	// Unsampled traces are propagated but never exported.
+	ctx, skipped := tr.StartServer(context.Background(), "GET /v1/test", SpanContext{TraceID: sc.TraceID, ID: "1111111111111111"})
+	_, skippedChild := Start(ctx, "database.query")
+	skippedChild.End()
+	skipped.End()
+
+	// Without a span in the context there is nothing to record.
+	if _, s := Start(context.Background(), "orphan"); s != nil {
+		t.Errorf("Should not start a span without a trace.")
+	}
+
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Should be able to shutdown: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(spans) != 3 || batches != 2 {
+		t.Fatalf("Should export 3 spans in 2 batches: got %d spans in %d batches", len(spans), batches)
+	}
+
+	byName := make(map[string]zipkinSpan)
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+
+	srv := byName["GET /v1/users"]
+	if srv.Kind != KindServer || srv.ID != sc.ID || srv.Tags["http.status_code"] != "200" || srv.LocalEndpoint.ServiceName != "sales-api" {
+		t.Errorf("Should export the server span: %+v", srv)
+	}
+
+	for _, name := range []string{"database.query", "auth.opa"} {
+		s := byName[name]
+		if s.TraceID != sc.TraceID || s.ParentID != sc.ID || s.ID == "" || s.Duration < 1 {
+			t.Errorf("Should export %s as a child of the server span: %+v", name, s)
+		}
+	}
+
+	if tr.Exported() != 3 || tr.Dropped() != 0 {
+		t.Errorf("Should count the exported spans: exported %d dropped %d", tr.Exported(), tr.Dropped())
+	}
+}
@@ -0,0 +1,77 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// zipkinSpan is the representation of a span in the zipkin v2 JSON format.
+type zipkinSpan struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind,omitempty"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint zipkinEndpoint    `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+// zipkinEndpoint identifies the service that recorded a span.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+func toZipkinSpan(serviceName string, s *Span) zipkinSpan {
+
+	// Zipkin requires a duration of at least one microsecond.
+	duration := s.Duration.Microseconds()
+	if duration < 1 {
+		duration = 1
+	}
+
+	return zipkinSpan{
+		TraceID:   s.TraceID,
+		ID:        s.ID,
+		ParentID:  s.ParentID,
+		Name:      s.Name,
+		Kind:      s.Kind,
+		Timestamp: s.Start.UnixNano() / int64(time.Microsecond),
+		Duration:  duration,
+		LocalEndpoint: zipkinEndpoint{
+			ServiceName: serviceName,
+		},
+		Tags: s.Tags(),
+	}
+}
+
+// export posts the batch of spans to the zipkin collector.
+func (t *Tracer) export(batch []*Span) error {
+	spans := make([]zipkinSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = toZipkinSpan(t.cfg.ServiceName, s)
+	}
+
+	data, err := json.Marshal(spans)
+	if err != nil {
+		return fmt.Errorf("marshal spans: %w", err)
+	}
+
+	resp, err := t.cfg.Client.Post(t.cfg.ReporterURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("post spans: %w", err)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post spans: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StrongETag returns a strong entity tag computed from the response body. A
+// strong tag changes whenever a single byte of the body changes.
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns a weak entity tag for the version of a resource, such as
+// the date it was last updated. A weak tag promises the content is
+// equivalent, not byte for byte identical.
+func WeakETag(version string) string {
+	return `W/"` + strings.ReplaceAll(version, `"`, "") + `"`
+}
+
+// IsWeakETag reports if the entity tag is a weak tag.
+func IsWeakETag(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// SetValidators sets the ETag and Last-Modified headers of the response. An
+// empty tag or zero time leaves the header unset.
+func SetValidators(w http.ResponseWriter, etag string, lastModified time.Time) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// NotModified reports if the client's copy of the resource is current given
+// the conditional headers of the request. If-None-Match is evaluated with the
+// weak comparison and takes precedence over If-Modified-Since. Only GET and
+// HEAD requests can be answered as not modified.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && matchETag(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	// The header only has a precision of seconds.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// CheckNotModified sets the validators of the response and, when the client's
+// copy of the resource is current, answers the request with a 304 status.
+// Handlers call it with a cheap version of the resource before loading it and
+// return when it reports true.
+func CheckNotModified(ctx context.Context, w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	SetValidators(w, etag, lastModified)
+
+	if !NotModified(r, etag, lastModified) {
+		return false
+	}
+
+	SetStatusCode(ctx, http.StatusNotModified)
+	w.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+// matchETag reports if the etag matches one of the tags in the If-None-Match
+// header using the weak comparison.
+func matchETag(header string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_NotModified(t *testing.T) {
+	modified := time.Date(2023, 6, 1, 12, 0, 0, 500, time.UTC)
+	etag := StrongETag([]byte(`{"name":"bill"}`))
+
+	tt := []struct {
+		name   string
+		method string
+		header map[string]string
+		exp    bool
+	}{
+		{name: "none", method: http.MethodGet, exp: false},
+		{name: "match", method: http.MethodGet, header: map[string]string{"If-None-Match": etag}, exp: true},
+		{name: "list", method: http.MethodGet, header: map[string]string{"If-None-Match": `"a", ` + etag}, exp: true},
+		{name: "weak", method: http.MethodGet, header: map[string]string{"If-None-Match": "W/" + etag}, exp: true},
+		{name: "star", method: http.MethodGet, header: map[string]string{"If-None-Match": "*"}, exp: true},
+		{name: "mismatch", method: http.MethodGet, header: map[string]string{"If-None-Match": `"a"`}, exp: false},
+		{name: "post", method: http.MethodPost, header: map[string]string{"If-None-Match": etag}, exp: false},
+		{name: "since", method: http.MethodGet, header: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, exp: true},
+		{name: "older", method: http.MethodGet, header: map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}, exp: false},
+		{name: "precedence", method: http.MethodGet, header: map[string]string{"If-None-Match": `"a"`, "If-Modified-Since": modified.Format(http.TimeFormat)}, exp: false},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			r := httptest.NewRequest(tst.method, "/", nil)
+			for k, v := range tst.header {
+				r.Header.Set(k, v)
+			}
+
+			if got := NotModified(r, etag, modified); got != tst.exp {
+				t.Errorf("Should evaluate the conditions: got %t, exp %t", got, tst.exp)
+			}
+		})
+	}
+}
+
+func Test_CheckNotModified(t *testing.T) {
+	etag := WeakETag("42")
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", etag)
+
+	w := httptest.NewRecorder()
+	if !CheckNotModified(context.Background(), w, r, etag, time.Time{}) {
+		t.Fatal("Should report the client's copy is current")
+	}
+
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `W/"42"` {
+		t.Errorf("Should answer with a 304 status and the tag: %d %v", w.Code, w.Header())
+	}
+}
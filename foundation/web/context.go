@@ -9,11 +9,20 @@ type ctxKey int
 
 const key ctxKey = 1
 
-// Values represent state for each request.
+// Values represent state for each request. The trace fields follow the W3C
+// Trace Context recommendation, ParentSpanID and TraceState are only set
+// when the caller propagated a trace.
 type Values struct {
-	TraceID    string
-	Now        time.Time
-	StatusCode int
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	TraceFlags   byte
+	TraceState   string
+	Now          time.Time
+	StatusCode   int
+
+	// cleanups run once the request has been handled.
+	cleanups []func()
 }
 
 // GetValues returns the values from the context.
@@ -21,7 +30,7 @@ func GetValues(ctx context.Context) *Values {
 	v, ok := ctx.Value(key).(*Values)
 	if !ok {
 		return &Values{
-			TraceID: "00000000-0000-0000-0000-000000000000",
+			TraceID: ZeroTraceID,
 			Now:     time.Now(),
 		}
 	}
@@ -33,7 +42,7 @@ func GetValues(ctx context.Context) *Values {
 func GetTraceID(ctx context.Context) string {
 	v, ok := ctx.Value(key).(*Values)
 	if !ok {
-		return "00000000-0000-0000-0000-000000000000"
+		return ZeroTraceID
 	}
 	return v.TraceID
 }
@@ -56,3 +65,14 @@ func SetStatusCode(ctx context.Context, statusCode int) {
 
 	v.StatusCode = statusCode
 }
+
+// onDone registers the function to run once the request has been handled. It
+// is ignored when the context doesn't carry the values of a request.
+func onDone(ctx context.Context, fn func()) {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return
+	}
+
+	v.cleanups = append(v.cleanups, fn)
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Error is used to pass an error detected by the framework through the
+// application with the HTTP status that describes it.
+type Error struct {
+	Err    error
+	Status int
+}
+
+// NewError wraps a provided error with an HTTP status code.
+func NewError(err error, status int) error {
+	return &Error{err, status}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsError checks if an error of type Error exists.
+func IsError(err error) bool {
+	var e *Error
+	return errors.As(err, &e)
+}
+
+// GetError returns a copy of the Error pointer.
+func GetError(err error) *Error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	return e
+}
+
+// =============================================================================
+
+// FieldError is used to indicate a request field that couldn't be decoded.
+type FieldError struct {
+	Field string `json:"field"`
+	Err   string `json:"error"`
+}
+
+// FieldErrors represents a collection of field decoding errors.
+type FieldErrors []FieldError
+
+// Error implements the error interface.
+func (fe FieldErrors) Error() string {
+	d, err := json.Marshal(fe)
+	if err != nil {
+		return err.Error()
+	}
+	return string(d)
+}
+
+// IsFieldErrors checks if an error of type FieldErrors exists.
+func IsFieldErrors(err error) bool {
+	var fe FieldErrors
+	return errors.As(err, &fe)
+}
+
+// GetFieldErrors returns a copy of the FieldErrors.
+func GetFieldErrors(err error) FieldErrors {
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		return nil
+	}
+	return fe
+}
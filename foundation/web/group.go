@@ -0,0 +1,67 @@
+package web
+
+import (
+	"strings"
+)
+
+// Group is a set of routes that share a path prefix and a set of middleware.
+// Requests are handled by the app middleware first, then the middleware of
+// each enclosing group from the outermost inwards, then the route middleware.
+type Group struct {
+	app    *App
+	prefix string
+	mw     []Middleware
+}
+
+// Group creates a group of routes mounted under the specified prefix. The
+// middleware is applied to every route registered through the group.
+func (a *App) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		app:    a,
+		prefix: joinPath("", prefix),
+		mw:     mw,
+	}
+}
+
+// Group creates a nested group mounted under the prefix of this group. The
+// middleware of this group runs before the middleware of the nested group.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		app:    g.app,
+		prefix: joinPath(g.prefix, prefix),
+		mw:     combine(g.mw, mw),
+	}
+}
+
+// Handle sets a handler function for a given HTTP method and path relative
+// to the prefix of the group. The returned Route can be used to document the
+// route.
+func (g *Group) Handle(method string, path string, handler Handler, mw ...Middleware) *Route {
+	return g.app.Handle(method, joinPath(g.prefix, path), handler, combine(g.mw, mw)...)
+}
+
+// combine returns a new slice with the middleware of a followed by the
+// middleware of b so groups never share a backing array.
+func combine(a []Middleware, b []Middleware) []Middleware {
+	mw := make([]Middleware, 0, len(a)+len(b))
+	mw = append(mw, a...)
+	mw = append(mw, b...)
+
+	return mw
+}
+
+// joinPath joins a prefix and a path making sure there is exactly one slash
+// between them.
+func joinPath(prefix string, path string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	path = strings.TrimLeft(path, "/")
+
+	if path == "" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+
+	return prefix + "/" + path
+}
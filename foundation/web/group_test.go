@@ -0,0 +1,91 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_Group(t *testing.T) {
+	var calls []string
+
+	trace := func(name string) Middleware {
+		return func(handler Handler) Handler {
+			return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				calls = append(calls, name)
+				return handler(ctx, w, r)
+			}
+		}
+	}
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls = append(calls, "handler")
+		return nil
+	}
+
+	app := NewApp(nil, trace("app"))
+
+	v1 := app.Group("/v1", trace("v1"))
+	v1.Handle(http.MethodGet, "/users", handler, trace("route"))
+
+	admin := v1.Group("admin/", trace("admin"))
+	admin.Handle(http.MethodGet, "/", handler)
+	admin.Handle(http.MethodGet, "/keys", handler, trace("route"))
+
+	root := app.Group("", trace("root"))
+	root.Handle(http.MethodGet, "/test", handler)
+
+	tt := []struct {
+		path string
+		exp  []string
+	}{
+		{"/v1/users", []string{"app", "v1", "route", "handler"}},
+		{"/v1/admin", []string{"app", "v1", "admin", "handler"}},
+		{"/v1/admin/keys", []string{"app", "v1", "admin", "route", "handler"}},
+		{"/test", []string{"app", "root", "handler"}},
+	}
+
+	for _, tst := range tt {
+		calls = nil
+
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tst.path, nil))
+
+		if !reflect.DeepEqual(calls, tst.exp) {
+			t.Errorf("%s: got %v, exp %v", tst.path, calls, tst.exp)
+		}
+	}
+
+	if got := len(root.Routes()); got != 4 {
+		t.Errorf("Should list every route of the app for a group at the root: got %d", got)
+	}
+	if got := len(admin.Routes()); got != 2 {
+		t.Errorf("Should list the routes under the prefix of the group: got %d", got)
+	}
+}
+
+func Test_Documented(t *testing.T) {
+	secured := Documented(func(handler Handler) Handler { return handler }, func(r *Route) {
+		r.Auth("admin")
+	})
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+
+	app := NewApp(nil)
+
+	admin := app.Group("/admin", secured)
+	keys := admin.Handle(http.MethodGet, "/keys", handler)
+	public := app.Handle(http.MethodGet, "/public", handler)
+
+	if !keys.Secured || !reflect.DeepEqual(keys.AuthRules, []string{"admin"}) {
+		t.Errorf("Should document the routes of the group from the middleware: got %t %v", keys.Secured, keys.AuthRules)
+	}
+
+	if public.Secured || public.AuthRules != nil {
+		t.Errorf("Should not document routes without the middleware: got %t %v", public.Secured, public.AuthRules)
+	}
+}
@@ -1,38 +1,403 @@
 package web
 
 import (
+	"context"
+	"encoding"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/dimfeld/httptreemux/v5"
 )
 
 type validator interface {
-	Validate() error
+	Validate(ctx context.Context) error
 }
 
+// bodyField is the field reported for errors that concern the request body
+// as a whole instead of a specific field.
+const bodyField = "body"
+
+// multipartMemory is the amount of a multipart body held in memory, the
+// remainder of the files are stored in temporary files.
+var multipartMemory int64 = 32 << 20
+
 // Param returns the web call parameters from the request.
 func Param(r *http.Request, key string) string {
 	m := httptreemux.ContextParams(r.Context())
 	return m[key]
 }
 
-// Decode reads the body of an HTTP request looking for a JSON document. The
-// body is decoded into the provided value.
-// If the provided value is a struct then it is checked for validation tags.
-// If the value implements a validate function, it is executed.
+// Decode reads the body of an HTTP request into the provided value. The
+// Content-Type of the request selects between a JSON document, an url encoded
+// form and a multipart form. Form fields are matched by the json tag names
+// of the struct fields.
+//
+// Unsupported content types are reported with a 415 status and bodies larger
+// than the limit set on the request with a 413 status. Documents that can't
+// be decoded into the value are reported as FieldErrors.
+//
+// If the value implements a validate function, it is executed with the
+// context of the request.
 func Decode(r *http.Request, val any) error {
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(val); err != nil {
+	contentType := r.Header.Get("Content-Type")
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return NewError(fmt.Errorf("content type %q is not supported", contentType), http.StatusUnsupportedMediaType)
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		err = decodeJSON(r.Body, val)
+
+	case mediaType == "application/x-www-form-urlencoded":
+		err = decodeForm(r, val, false)
+
+	case mediaType == "multipart/form-data":
+		err = decodeForm(r, val, true)
+
+	default:
+		return NewError(fmt.Errorf("content type %q is not supported", mediaType), http.StatusUnsupportedMediaType)
+	}
+
+	if err != nil {
 		return err
 	}
 
 	if v, ok := val.(validator); ok {
-		if err := v.Validate(); err != nil {
+		if err := v.Validate(r.Context()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// decodeJSON decodes a single JSON document into the value.
+func decodeJSON(body io.Reader, val any) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(val); err != nil {
+		return jsonError(err)
+	}
+
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if tooLarge := sizeError(err); tooLarge != nil {
+			return tooLarge
+		}
+		return FieldErrors{{Field: bodyField, Err: "must contain a single JSON document"}}
+	}
+
+	return nil
+}
+
+// jsonError converts the errors returned by the json decoder into errors
+// that can be reported to the client.
+func jsonError(err error) error {
+	if tooLarge := sizeError(err); tooLarge != nil {
+		return tooLarge
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return FieldErrors{{Field: bodyField, Err: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)}}
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return FieldErrors{{Field: bodyField, Err: "malformed JSON"}}
+
+	case errors.Is(err, io.EOF):
+		return FieldErrors{{Field: bodyField, Err: "must not be empty"}}
+
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = bodyField
+		}
+		return FieldErrors{{Field: field, Err: "must be " + typeName(typeErr.Type)}}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return FieldErrors{{Field: field, Err: "unknown field"}}
+	}
+
+	return err
+}
+
+// sizeError returns the error reported when the body exceeds the limit set
+// with http.MaxBytesReader, or nil if the error is not caused by the limit.
+func sizeError(err error) error {
+	var maxErr *http.MaxBytesError
+	if !errors.As(err, &maxErr) {
+		return nil
+	}
+
+	return NewError(fmt.Errorf("request body must not be larger than %d bytes", maxErr.Limit), http.StatusRequestEntityTooLarge)
+}
+
+// =============================================================================
+
+// decodeForm decodes the url encoded or multipart form in the body into the
+// value. Files of a multipart form can be decoded into fields of type
+// *multipart.FileHeader or []*multipart.FileHeader. The temporary files of a
+// multipart form are removed once the App handled the request, outside of an
+// App the caller must remove them with r.MultipartForm.RemoveAll.
+func decodeForm(r *http.Request, val any, multi bool) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decoding form into %T: value must be a pointer to a struct", val)
+	}
+
+	var values map[string][]string
+	var files map[string][]*multipart.FileHeader
+
+	if multi {
+		if err := r.ParseMultipartForm(multipartMemory); err != nil {
+			if tooLarge := sizeError(err); tooLarge != nil {
+				return tooLarge
+			}
+			return FieldErrors{{Field: bodyField, Err: "malformed multipart form"}}
+		}
+
+		// The handler receives a copy of the request net/http doesn't know
+		// about, so the files are removed here once the handler returns.
+		form := r.MultipartForm
+		onDone(r.Context(), func() { form.RemoveAll() })
+		values = r.MultipartForm.Value
+		files = r.MultipartForm.File
+	} else {
+		if err := r.ParseForm(); err != nil {
+			if tooLarge := sizeError(err); tooLarge != nil {
+				return tooLarge
+			}
+			return FieldErrors{{Field: bodyField, Err: "malformed form"}}
+		}
+		values = r.PostForm
+	}
+
+	fields := make(map[string]reflect.Value)
+	formFields(rv.Elem(), fields)
+
+	var fe FieldErrors
+
+	for name, vals := range values {
+		fld, exists := fields[name]
+		if !exists {
+			fe = append(fe, FieldError{Field: name, Err: "unknown field"})
+			continue
+		}
+
+		if err := setField(fld, vals); err != nil {
+			fe = append(fe, FieldError{Field: name, Err: err.Error()})
+		}
+	}
+
+	for name, fhs := range files {
+		fld, exists := fields[name]
+		if !exists {
+			fe = append(fe, FieldError{Field: name, Err: "unknown field"})
+			continue
+		}
+
+		if err := setFiles(fld, fhs); err != nil {
+			fe = append(fe, FieldError{Field: name, Err: err.Error()})
+		}
+	}
+
+	if len(fe) > 0 {
+		sort.Slice(fe, func(i, j int) bool { return fe[i].Field < fe[j].Field })
+		return fe
+	}
+
+	return nil
+}
+
+// formFields collects the settable fields of the struct by their json name.
+// Embedded structs without a json name are flattened like encoding/json does.
+func formFields(rv reflect.Value, fields map[string]reflect.Value) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			formFields(rv.Field(i), fields)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = rv.Field(i)
+	}
+}
+
+// Set of types with a dedicated representation in forms.
+var (
+	fileHeaderType      = reflect.TypeOf(&multipart.FileHeader{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setFiles sets the files of a multipart form into the field.
+func setFiles(fld reflect.Value, fhs []*multipart.FileHeader) error {
+	switch {
+	case fld.Type() == fileHeaderType:
+		if len(fhs) != 1 {
+			return errors.New("must be a single file")
+		}
+		fld.Set(reflect.ValueOf(fhs[0]))
+
+	case fld.Kind() == reflect.Slice && fld.Type().Elem() == fileHeaderType:
+		fld.Set(reflect.ValueOf(fhs))
+
+	default:
+		return fmt.Errorf("must be %s", typeName(fld.Type()))
+	}
+
+	return nil
+}
+
+// setField sets the form values into the field. Slices receive every value,
+// any other type accepts a single value.
+func setField(fld reflect.Value, vals []string) error {
+	if fld.Kind() == reflect.Slice && fld.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fld.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setValue(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		fld.Set(slice)
+		return nil
+	}
+
+	if len(vals) != 1 {
+		return errors.New("must be a single value")
+	}
+
+	return setValue(fld, vals[0])
+}
+
+// setValue parses the string into the value based on its type.
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), s); err != nil {
 			return err
 		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := tu.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("must be %s", typeName(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be %s", typeName(v.Type()))
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be %s", typeName(v.Type()))
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be %s", typeName(v.Type()))
+		}
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be %s", typeName(v.Type()))
+		}
+		v.SetFloat(n)
+
+	default:
+		return fmt.Errorf("must be %s", typeName(v.Type()))
 	}
 
 	return nil
 }
+
+// typeName returns the name of the JSON type the Go type is represented by,
+// preceded by its article.
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "a string"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem() == fileHeaderType {
+			return "a list of files"
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "a string"
+		}
+		return "an array"
+	case reflect.Struct:
+		if reflect.PointerTo(t) == fileHeaderType {
+			return "a file"
+		}
+		return "an object"
+	case reflect.Map:
+		return "an object"
+	}
+
+	return "a valid value"
+}
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type decodeAddress struct {
+	City string `json:"city"`
+}
+
+type decodeModel struct {
+	Name    string                `json:"name"`
+	Age     int                   `json:"age"`
+	Tags    []string              `json:"tags"`
+	Active  *bool                 `json:"active"`
+	Address decodeAddress         `json:"address"`
+	Avatar  *multipart.FileHeader `json:"avatar"`
+}
+
+func Test_DecodeJSON(t *testing.T) {
+	tt := []struct {
+		name        string
+		contentType string
+		body        string
+		limit       int64
+		status      int
+		fields      FieldErrors
+	}{
+		{name: "valid", contentType: "application/json; charset=utf-8", body: `{"name":"bill","age":40}`},
+		{name: "suffix", contentType: "application/merge-patch+json", body: `{"name":"bill"}`},
+		{name: "contenttype", contentType: "text/plain", body: `{}`, status: http.StatusUnsupportedMediaType},
+		{name: "nocontenttype", body: `{}`, status: http.StatusUnsupportedMediaType},
+		{name: "limit", contentType: "application/json", body: `{"name":"` + strings.Repeat("x", 64) + `"}`, limit: 32, status: http.StatusRequestEntityTooLarge},
+		{name: "syntax", contentType: "application/json", body: `{"name":}`, fields: FieldErrors{{Field: "body", Err: "malformed JSON at offset 9"}}},
+		{name: "truncated", contentType: "application/json", body: `{"name":"bill"`, fields: FieldErrors{{Field: "body", Err: "malformed JSON"}}},
+		{name: "empty", contentType: "application/json", fields: FieldErrors{{Field: "body", Err: "must not be empty"}}},
+		{name: "type", contentType: "application/json", body: `{"address":{"city":10}}`, fields: FieldErrors{{Field: "address.city", Err: "must be a string"}}},
+		{name: "unknown", contentType: "application/json", body: `{"nickname":"bill"}`, fields: FieldErrors{{Field: "nickname", Err: "unknown field"}}},
+		{name: "trailing", contentType: "application/json", body: `{"name":"bill"}{}`, fields: FieldErrors{{Field: "body", Err: "must contain a single JSON document"}}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tst.body))
+			if tst.contentType != "" {
+				r.Header.Set("Content-Type", tst.contentType)
+			}
+			if tst.limit > 0 {
+				r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, tst.limit)
+			}
+
+			var m decodeModel
+			err := Decode(r, &m)
+
+			switch {
+			case tst.status != 0:
+				if webErr := GetError(err); webErr == nil || webErr.Status != tst.status {
+					t.Fatalf("Should receive status %d: %v", tst.status, err)
+				}
+
+			case tst.fields != nil:
+				if fe := GetFieldErrors(err); !reflect.DeepEqual(fe, tst.fields) {
+					t.Fatalf("Should receive field errors: got %v, exp %v", err, tst.fields)
+				}
+
+			case err != nil:
+				t.Fatalf("Should be able to decode the body: %s", err)
+			}
+		})
+	}
+}
+
+func Test_DecodeForm(t *testing.T) {
+	form := "name=bill&age=40&tags=a&tags=b&active=true"
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var m decodeModel
+	if err := Decode(r, &m); err != nil {
+		t.Fatalf("Should be able to decode the form: %s", err)
+	}
+
+	if m.Name != "bill" || m.Age != 40 || !reflect.DeepEqual(m.Tags, []string{"a", "b"}) || m.Active == nil || !*m.Active {
+		t.Errorf("Should decode every field: %+v", m)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=a&name=b&age=old&nickname=bill"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	exp := FieldErrors{
+		{Field: "age", Err: "must be an integer"},
+		{Field: "name", Err: "must be a single value"},
+		{Field: "nickname", Err: "unknown field"},
+	}
+
+	if fe := GetFieldErrors(Decode(r, &decodeModel{})); !reflect.DeepEqual(fe, exp) {
+		t.Errorf("Should receive field errors: got %v, exp %v", fe, exp)
+	}
+}
+
+func Test_DecodeMultipart(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "bill")
+
+	fw, err := mw.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatalf("Should be able to create the file part: %s", err)
+	}
+	fw.Write([]byte("image"))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var m decodeModel
+	if err := Decode(r, &m); err != nil {
+		t.Fatalf("Should be able to decode the form: %s", err)
+	}
+
+	if m.Name != "bill" || m.Avatar == nil || m.Avatar.Filename != "avatar.png" {
+		t.Errorf("Should decode the fields and file: %+v", m)
+	}
+}
+
+func Test_DecodeMultipartRemovesFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	// Every file is spilled to a temporary file.
+	defer func(memory int64) { multipartMemory = memory }(multipartMemory)
+	multipartMemory = 1
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var m decodeModel
+		if err := Decode(r, &m); err != nil {
+			t.Errorf("Should be able to decode the form: %s", err)
+			return nil
+		}
+
+		f, err := m.Avatar.Open()
+		if err != nil {
+			t.Errorf("Should be able to open the file in the handler: %s", err)
+			return nil
+		}
+		defer f.Close()
+
+		if data, _ := io.ReadAll(f); string(data) != "image" {
+			t.Errorf("Should read the file: got %q", data)
+		}
+
+		if entries, _ := os.ReadDir(dir); len(entries) == 0 {
+			t.Errorf("Should spill the file to a temporary file.")
+		}
+
+		return nil
+	}
+
+	app := NewApp(nil)
+	app.Handle(http.MethodPost, "/upload", handler)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatalf("Should be able to create the file part: %s", err)
+	}
+	fw.Write([]byte("image"))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Should be able to read the temporary folder: %s", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Should remove the temporary files once the request is handled: %d left", len(entries))
+	}
+}
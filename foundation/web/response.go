@@ -8,6 +8,13 @@ import (
 
 // Respond converts a Go value to JSON and sends it to the client.
 func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode int) error {
+	return RespondAs(ctx, w, data, statusCode, "application/json")
+}
+
+// RespondAs converts a Go value to JSON and sends it to the client with the
+// specified content type, for media types based on JSON such as
+// application/problem+json.
+func RespondAs(ctx context.Context, w http.ResponseWriter, data any, statusCode int, contentType string) error {
 	SetStatusCode(ctx, statusCode)
 
 	if statusCode == http.StatusNoContent {
@@ -20,7 +27,7 @@ func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode in
 		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(statusCode)
 
 	if _, err := w.Write(jsonData); err != nil {
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// QueryParam describes a query string parameter accepted by a route.
+type QueryParam struct {
+	Name        string
+	Description string
+}
+
+// Route describes a route registered with the App. Besides the method and
+// path, it records metadata that is used to generate API documentation. The
+// metadata is provided by chaining calls on the value returned by Handle.
+type Route struct {
+	Method      string
+	Path        string
+	Params      []string
+	Summary     string
+	Tags        []string
+	RequestType reflect.Type
+	Responses   map[int]reflect.Type
+	QueryParams []QueryParam
+	Secured     bool
+	AuthRules   []string
+}
+
+// newRoute constructs a route for the specified method and path, extracting
+// the path parameters.
+func newRoute(method string, path string) *Route {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			params = append(params, segment[1:])
+		}
+	}
+
+	return &Route{
+		Method:    method,
+		Path:      path,
+		Params:    params,
+		Responses: make(map[int]reflect.Type),
+	}
+}
+
+// Doc sets a short summary of what the route does.
+func (r *Route) Doc(summary string, tags ...string) *Route {
+	r.Summary = summary
+	r.Tags = tags
+	return r
+}
+
+// Request records the model decoded from the request body.
+func (r *Route) Request(model any) *Route {
+	r.RequestType = reflect.TypeOf(model)
+	return r
+}
+
+// Response records the model returned for the specified status. A nil model
+// documents a response without a body.
+func (r *Route) Response(status int, model any) *Route {
+	r.Responses[status] = reflect.TypeOf(model)
+	return r
+}
+
+// Query records a query string parameter accepted by the route.
+func (r *Route) Query(name string, description string) *Route {
+	r.QueryParams = append(r.QueryParams, QueryParam{Name: name, Description: description})
+	return r
+}
+
+// Auth records that the route requires an authenticated caller along with
+// the authorization rules or permissions the caller must satisfy.
+func (r *Route) Auth(rules ...string) *Route {
+	r.Secured = true
+	r.AuthRules = append(r.AuthRules, rules...)
+	return r
+}
+
+// Statuses returns the documented response statuses in ascending order.
+func (r *Route) Statuses() []int {
+	statuses := make([]int, 0, len(r.Responses))
+	for status := range r.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	return statuses
+}
+
+// =============================================================================
+
+// registering holds the route being registered while its middleware is
+// applied, so documented middleware can describe it.
+var (
+	registerMu  sync.Mutex
+	registering *Route
+)
+
+// Documented returns the middleware documenting every route it's applied
+// to with the describe function. The documentation is derived from the
+// middleware protecting the route, so the two can't drift apart.
+func Documented(mw Middleware, describe func(r *Route)) Middleware {
+	m := func(handler Handler) Handler {
+		if registering != nil {
+			describe(registering)
+		}
+
+		return mw(handler)
+	}
+
+	return m
+}
+
+// register applies the middleware to the handler on behalf of the route.
+func register(route *Route, handler Handler, mw ...[]Middleware) Handler {
+	registerMu.Lock()
+	defer registerMu.Unlock()
+
+	registering = route
+	defer func() { registering = nil }()
+
+	for _, m := range mw {
+		handler = wrapMiddleware(m, handler)
+	}
+
+	return handler
+}
+
+// Routes returns the routes registered with the App in registration order.
+func (a *App) Routes() []*Route {
+	routes := make([]*Route, len(a.routes))
+	copy(routes, a.routes)
+
+	return routes
+}
+
+// Routes returns the routes registered under the prefix of the group. A
+// group mounted at the root returns every route of the app.
+func (g *Group) Routes() []*Route {
+	prefix := strings.TrimRight(g.prefix, "/")
+
+	var routes []*Route
+	for _, route := range g.app.routes {
+		if route.Path == prefix || strings.HasPrefix(route.Path, prefix+"/") {
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
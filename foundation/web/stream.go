@@ -0,0 +1,119 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Event is a Server-Sent Event. The ID is sent back by clients in the
+// Last-Event-ID header when they reconnect.
+type Event struct {
+	ID   string
+	Name string
+	Data []byte
+}
+
+// Stream writes Server-Sent Events to a long-lived response. The server's
+// WriteTimeout would end the response once it passes, so the write deadline
+// is instead extended before every write and only a client that stops
+// reading is cut off.
+type Stream struct {
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	writeTimeout time.Duration
+}
+
+// NewStream starts a stream of events on the response. Every write must
+// complete within the write timeout, zero leaves the deadline of the server
+// in place. The retry tells clients how long to wait before reconnecting,
+// zero leaves it to the client.
+func NewStream(ctx context.Context, w http.ResponseWriter, writeTimeout time.Duration, retry time.Duration) (*Stream, error) {
+	s := Stream{
+		w:            w,
+		rc:           http.NewResponseController(w),
+		writeTimeout: writeTimeout,
+	}
+
+	if err := s.extend(); err != nil {
+		return nil, err
+	}
+
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("X-Accel-Buffering", "no")
+
+	SetStatusCode(ctx, http.StatusOK)
+	w.WriteHeader(http.StatusOK)
+
+	if retry > 0 {
+		if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("flushing stream: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Send writes the event and flushes it to the client.
+func (s *Stream) Send(ev Event) error {
+	var b strings.Builder
+
+	if ev.ID != "" {
+		b.WriteString("id: " + ev.ID + "\n")
+	}
+	if ev.Name != "" {
+		b.WriteString("event: " + ev.Name + "\n")
+	}
+	for _, line := range strings.Split(string(ev.Data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Comment writes a comment, which clients ignore. Comments are sent as
+// heartbeats to keep idle connections from being closed by proxies.
+func (s *Stream) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+// =============================================================================
+
+// write writes the data within the write timeout and flushes it.
+func (s *Stream) write(data string) error {
+	if err := s.extend(); err != nil {
+		return err
+	}
+
+	if _, err := s.w.Write([]byte(data)); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+// extend moves the write deadline of the connection past the next write.
+// Writers that don't support deadlines, such as test recorders, are left
+// alone.
+func (s *Stream) extend() error {
+	if s.writeTimeout <= 0 {
+		return nil
+	}
+
+	err := s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("setting write deadline: %w", err)
+	}
+
+	return nil
+}
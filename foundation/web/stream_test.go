@@ -0,0 +1,69 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Stream(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		s, err := NewStream(context.Background(), w, time.Second, 3*time.Second)
+		if err != nil {
+			t.Errorf("Should be able to start the stream: %s", err)
+			return
+		}
+
+		// The events are sent after the server's WriteTimeout passed.
+		for i, ev := range []Event{{ID: "1", Name: "user.created", Data: []byte("{\"a\":1}")}, {ID: "2", Data: []byte("one\ntwo")}} {
+			time.Sleep(60 * time.Millisecond)
+
+			if i == 1 {
+				if err := s.Comment("heartbeat"); err != nil {
+					t.Errorf("Should be able to send a comment: %s", err)
+				}
+			}
+
+			if err := s.Send(ev); err != nil {
+				t.Errorf("Should be able to send event %s: %s", ev.ID, err)
+			}
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Should be able to listen: %s", err)
+	}
+
+	srv := http.Server{
+		Handler:      http.HandlerFunc(handler),
+		WriteTimeout: 50 * time.Millisecond,
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Should be able to connect: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Should receive an event stream: got %s", ct)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	exp := "retry: 3000||id: 1|event: user.created|data: {\"a\":1}||: heartbeat||id: 2|data: one|data: two|"
+	if got := strings.Join(lines, "|"); got != exp {
+		t.Errorf("Should receive the events:\ngot %s\nexp %s", got, exp)
+	}
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Set of headers used to propagate the trace context between services as
+// defined by the W3C Trace Context recommendation. The trace id of every
+// request is returned to the client in the TraceIDHeader.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+	TraceIDHeader     = "X-Trace-ID"
+)
+
+// TraceFlagSampled is the trace flag indicating the caller may have recorded
+// the trace.
+const TraceFlagSampled byte = 0x01
+
+// ZeroTraceID is the trace id reported when there is no trace.
+const ZeroTraceID = "00000000000000000000000000000000"
+
+// Set of limits defined by the recommendation.
+const (
+	traceParentSize = 55
+	maxTraceState   = 512
+)
+
+// startTrace initializes the trace context of the values from the headers
+// of the request. A caller that doesn't provide a valid traceparent header
+// starts a new trace, which is flagged as sampled when sample reports so.
+// Every request receives a new span id.
+func (v *Values) startTrace(h http.Header, sample func() bool) {
+	traceID, parentID, flags, ok := parseTraceParent(h.Get(TraceParentHeader))
+
+	if ok {
+		v.TraceID = traceID
+		v.ParentSpanID = parentID
+		v.TraceFlags = flags
+
+		if state := strings.Join(h.Values(TraceStateHeader), ","); len(state) <= maxTraceState {
+			v.TraceState = state
+		}
+	} else {
+		v.TraceID = newID(16)
+		if sample() {
+			v.TraceFlags = TraceFlagSampled
+		}
+	}
+
+	v.SpanID = newID(8)
+}
+
+// TraceParent returns the value of the traceparent header that propagates
+// the trace to calls made while handling the request.
+func (v *Values) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", v.TraceID, v.SpanID, v.TraceFlags)
+}
+
+// Sampled reports if the trace is flagged as being recorded.
+func (v *Values) Sampled() bool {
+	return v.TraceFlags&TraceFlagSampled != 0
+}
+
+// parseTraceParent parses the value of a traceparent header. Versions after
+// 00 are parsed as version 00 as required by the recommendation.
+func parseTraceParent(s string) (traceID string, parentID string, flags byte, ok bool) {
+	if len(s) < traceParentSize || (len(s) > traceParentSize && s[traceParentSize] != '-') {
+		return "", "", 0, false
+	}
+
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return "", "", 0, false
+	}
+
+	version := s[0:2]
+	if !isHex(version) || version == "ff" || (version == "00" && len(s) != traceParentSize) {
+		return "", "", 0, false
+	}
+
+	traceID = s[3:35]
+	parentID = s[36:52]
+	if !isHex(traceID) || !isHex(parentID) || isZero(traceID) || isZero(parentID) {
+		return "", "", 0, false
+	}
+
+	f, err := hex.DecodeString(s[53:55])
+	if err != nil || !isHex(s[53:55]) {
+		return "", "", 0, false
+	}
+
+	return traceID, parentID, f[0], true
+}
+
+// isHex reports if the string only contains lowercase hex digits.
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZero reports if the id only contains zeros, which is an invalid id.
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// newID generates a random id of the specified number of bytes encoded as
+// lowercase hex.
+func newID(size int) string {
+	b := make([]byte, size)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(fmt.Sprintf("reading random: %s", err))
+		}
+
+		if id := hex.EncodeToString(b); !isZero(id) {
+			return id
+		}
+	}
+}
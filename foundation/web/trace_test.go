@@ -0,0 +1,79 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_TraceParent(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+
+	tt := []struct {
+		name   string
+		header string
+		ok     bool
+		flags  byte
+	}{
+		{name: "sampled", header: "00-" + traceID + "-" + parentID + "-01", ok: true, flags: 0x01},
+		{name: "notsampled", header: "00-" + traceID + "-" + parentID + "-00", ok: true},
+		{name: "future", header: "01-" + traceID + "-" + parentID + "-01-extra", ok: true, flags: 0x01},
+		{name: "empty", header: ""},
+		{name: "invalidversion", header: "ff-" + traceID + "-" + parentID + "-01"},
+		{name: "trailing", header: "00-" + traceID + "-" + parentID + "-01-extra"},
+		{name: "uppercase", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + parentID + "-01"},
+		{name: "zerotrace", header: "00-" + ZeroTraceID + "-" + parentID + "-01"},
+		{name: "zeroparent", header: "00-" + traceID + "-0000000000000000-01"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			gotTrace, gotParent, gotFlags, ok := parseTraceParent(tst.header)
+			if ok != tst.ok {
+				t.Fatalf("Should parse %q as valid=%t", tst.header, tst.ok)
+			}
+
+			if ok && (gotTrace != traceID || gotParent != parentID || gotFlags != tst.flags) {
+				t.Errorf("Should parse the fields: got %s %s %02x", gotTrace, gotParent, gotFlags)
+			}
+		})
+	}
+}
+
+func Test_TracePropagation(t *testing.T) {
+	var values Values
+
+	app := NewApp(nil)
+	app.Handle(http.MethodGet, "/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		values = *GetValues(ctx)
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set(TraceStateHeader, "congo=t61rcWkgMzE")
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if values.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || values.ParentSpanID != "00f067aa0ba902b7" || values.TraceState != "congo=t61rcWkgMzE" {
+		t.Errorf("Should continue the caller's trace: %+v", values)
+	}
+
+	if len(values.SpanID) != 16 || values.SpanID == values.ParentSpanID {
+		t.Errorf("Should generate a new span id: %s", values.SpanID)
+	}
+
+	if got := w.Header().Get(TraceIDHeader); got != values.TraceID {
+		t.Errorf("Should echo the trace id: got %s, exp %s", got, values.TraceID)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	if _, _, _, ok := parseTraceParent(values.TraceParent()); !ok || values.ParentSpanID != "" {
+		t.Errorf("Should start a new valid trace: %+v", values)
+	}
+}
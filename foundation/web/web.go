@@ -4,13 +4,17 @@ package web
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/dimfeld/httptreemux/v5"
-	"github.com/google/uuid"
+	"github.com/qcbit/service/foundation/tracer"
 )
 
 // A Handler is a type that handles an http request within our own little mini framework.
@@ -23,15 +27,30 @@ type App struct {
 	*httptreemux.ContextMux
 	shutdown chan os.Signal
 	mw       []Middleware
+	routes   []*Route
+	tracer   *tracer.Tracer
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
+// Requests for unknown paths and unsupported methods are answered through the
+// middleware like any other request.
 func NewApp(shutdown chan os.Signal, mw ...Middleware) *App {
-	return &App{
+	app := App{
 		ContextMux: httptreemux.NewContextMux(),
 		shutdown:   shutdown,
 		mw:         mw,
 	}
+
+	app.ContextMux.MethodNotAllowedHandler = app.methodNotAllowed
+	app.ContextMux.NotFoundHandler = app.notFound
+
+	return &app
+}
+
+// SetTracer enables recording a server span for every request. The tracer
+// makes the sampling decision for requests that don't continue a trace.
+func (a *App) SetTracer(t *tracer.Tracer) {
+	a.tracer = t
 }
 
 // SignalShutdown is used to gracefully shutdown the app when an
@@ -41,19 +60,64 @@ func (a *App) SignalShutdown() {
 }
 
 // Handle sets a handler function for a given HTTP method and path pair to the application server mux.
-func (a *App) Handle(method string, path string, handler Handler, mw ...Middleware) {
-	handler = wrapMiddleware(mw, handler)
-	handler = wrapMiddleware(a.mw, handler)
+// The returned Route can be used to document the route.
+func (a *App) Handle(method string, path string, handler Handler, mw ...Middleware) *Route {
+	route := newRoute(method, path)
+
+	handler = register(route, handler, mw, a.mw)
+
+	a.ContextMux.Handle(method, path, a.serve(method+" "+path, handler))
+	a.routes = append(a.routes, route)
 
+	return route
+}
+
+// serve constructs the http handler that executes the handler with the
+// values for the request. The name identifies the request's span.
+func (a *App) serve(name string, handler Handler) http.HandlerFunc {
 	h := func(w http.ResponseWriter, r *http.Request) {
 
 		v := Values{
-			TraceID: uuid.New().String(),
-			Now:     time.Now(),
+			Now: time.Now(),
 		}
+		v.startTrace(r.Header, a.sample)
+		w.Header().Set(TraceIDHeader, v.TraceID)
+
 		ctx := context.WithValue(r.Context(), key, &v)
 
-		if err := handler(ctx, w, r); err != nil {
+		var span *tracer.Span
+		if a.tracer != nil {
+			ctx, span = a.tracer.StartServer(ctx, name, tracer.SpanContext{
+				TraceID:  v.TraceID,
+				ID:       v.SpanID,
+				ParentID: v.ParentSpanID,
+				Sampled:  v.Sampled(),
+			})
+			span.SetTag("http.method", r.Method)
+			span.SetTag("http.path", r.URL.Path)
+		}
+
+		// The request carries the values as well, so code only holding the
+		// request can register work to run once it is handled.
+		err := handler(ctx, w, r.WithContext(ctx))
+
+		for _, fn := range v.cleanups {
+			fn()
+		}
+
+		span.SetTag("http.status_code", strconv.Itoa(v.StatusCode))
+		span.SetError(err)
+		span.End()
+
+		if err != nil {
+
+			// Errors detected by the framework are never a reason to shut
+			// down, they are answered when no middleware handled them.
+			if webErr := GetError(err); webErr != nil {
+				http.Error(w, webErr.Error(), webErr.Status)
+				return
+			}
+
 			if validateShutdown(err) {
 				a.SignalShutdown()
 				return
@@ -63,8 +127,66 @@ func (a *App) Handle(method string, path string, handler Handler, mw ...Middlewa
 		// ADD CODE HERE: LOG
 	}
 
-	a.ContextMux.Handle(method, path, h)
+	return h
+}
+
+// methodNotAllowed handles requests for a registered path with a method that
+// has no handler. OPTIONS requests are answered with the methods the path
+// supports, anything else is reported as a 405 error. Both run through the
+// application middleware so they are logged and receive CORS headers.
+func (a *App) methodNotAllowed(w http.ResponseWriter, r *http.Request, methods map[string]httptreemux.HandlerFunc) {
+	allow := allowedMethods(methods, a.ContextMux.HeadCanUseGet)
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Allow", allow)
+
+		if r.Method == http.MethodOptions {
+			return Respond(ctx, w, nil, http.StatusNoContent)
+		}
+
+		return NewError(fmt.Errorf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	}
+
+	a.serve(r.Method+" "+r.URL.Path, wrapMiddleware(a.mw, handler))(w, r)
+}
+
+// notFound handles requests for paths that aren't registered.
+func (a *App) notFound(w http.ResponseWriter, r *http.Request) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return NewError(fmt.Errorf("path %s not found", r.URL.Path), http.StatusNotFound)
+	}
+
+	a.serve("not found", wrapMiddleware(a.mw, handler))(w, r)
+}
 
+// allowedMethods returns the value of the Allow header for a path that has
+// handlers for the specified methods.
+func allowedMethods(methods map[string]httptreemux.HandlerFunc, headCanUseGet bool) string {
+	allow := []string{http.MethodOptions}
+	for method := range methods {
+		if method != http.MethodOptions {
+			allow = append(allow, method)
+		}
+	}
+
+	if _, exists := methods[http.MethodHead]; !exists && headCanUseGet {
+		if _, exists := methods[http.MethodGet]; exists {
+			allow = append(allow, http.MethodHead)
+		}
+	}
+
+	sort.Strings(allow)
+
+	return strings.Join(allow, ", ")
+}
+
+// sample makes the sampling decision for a request starting a new trace.
+// Without a tracer every trace is flagged as sampled.
+func (a *App) sample() bool {
+	if a.tracer == nil {
+		return true
+	}
+	return a.tracer.Sample()
 }
 
 // validateShutdown validates the error for special conditions that do not
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Fallbacks(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	var statuses []int
+	record := func(handler Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := handler(ctx, w, r)
+			if webErr := GetError(err); webErr != nil {
+				statuses = append(statuses, webErr.Status)
+			}
+			return err
+		}
+	}
+
+	app := NewApp(nil, record)
+	app.Handle(http.MethodGet, "/users", handler)
+	app.Handle(http.MethodPost, "/users", handler)
+
+	tt := []struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		{method: http.MethodOptions, path: "/users", status: http.StatusNoContent, allow: "GET, HEAD, OPTIONS, POST"},
+		{method: http.MethodDelete, path: "/users", status: http.StatusMethodNotAllowed, allow: "GET, HEAD, OPTIONS, POST"},
+		{method: http.MethodGet, path: "/products", status: http.StatusNotFound},
+	}
+
+	for _, tst := range tt {
+		r := httptest.NewRequest(tst.method, tst.path, nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+
+		if w.Code != tst.status {
+			t.Errorf("%s %s: Should receive status %d, got %d", tst.method, tst.path, tst.status, w.Code)
+		}
+
+		if got := w.Header().Get("Allow"); got != tst.allow {
+			t.Errorf("%s %s: Should receive Allow %q, got %q", tst.method, tst.path, tst.allow, got)
+		}
+	}
+
+	// The errors pass through the application middleware.
+	if len(statuses) != 2 || statuses[0] != http.StatusMethodNotAllowed || statuses[1] != http.StatusNotFound {
+		t.Errorf("Should run the middleware for fallbacks: %v", statuses)
+	}
+}